@@ -0,0 +1,124 @@
+package credentials
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/omecodes/common/errors"
+	"github.com/omecodes/common/httpx"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type ctxAdmin struct{}
+
+// ContextWithAdmin returns a new context that holds the authenticated admin user name
+func ContextWithAdmin(parent context.Context, user string) context.Context {
+	return context.WithValue(parent, ctxAdmin{}, user)
+}
+
+// AdminFromContext returns the authenticated admin user name stored in ctx
+func AdminFromContext(ctx context.Context) string {
+	user, _ := ctx.Value(ctxAdmin{}).(string)
+	return user
+}
+
+// GRPCAdminAuthentication is a grpcx authentication func that checks Basic
+// credentials against the admins config of the app stored in ctx
+func GRPCAdminAuthentication(ctx context.Context) (context.Context, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx, status.Error(codes.Unauthenticated, "missing credentials")
+	}
+
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return ctx, status.Error(codes.Unauthenticated, "missing credentials")
+	}
+
+	user, password, ok := parseBasic(values[0])
+	if !ok {
+		return ctx, status.Error(codes.Unauthenticated, "malformed credentials")
+	}
+
+	err := VerifyAdmin(ctx, user, password)
+	if err != nil {
+		return ctx, status.Error(codes.Unauthenticated, errors.Unauthorized.Error())
+	}
+	return ContextWithAdmin(ctx, user), nil
+}
+
+// BasicAdmin returns a middleware that requires Basic credentials matching an admin of the app stored in the request context
+func BasicAdmin(realm string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, password, ok := r.BasicAuth()
+			if !ok || VerifyAdmin(r.Context(), user, password) != nil {
+				httpx.WriteResponse(w, http.StatusUnauthorized, &httpx.RequireAuth{Type: "Basic", Realm: realm})
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ContextWithAdmin(r.Context(), user)))
+		})
+	}
+}
+
+// GRPCAccessAuthentication is a grpcx authentication func that checks "Access key:secret" credentials against
+// the access config of the app stored in ctx. The verified key is stored in the context, see httpx.AccessKeyFromContext
+func GRPCAccessAuthentication(ctx context.Context) (context.Context, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx, status.Error(codes.Unauthenticated, "missing credentials")
+	}
+
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return ctx, status.Error(codes.Unauthenticated, "missing credentials")
+	}
+
+	key, secret, ok := httpx.ParseAccessAuthorization(values[0])
+	if !ok {
+		return ctx, status.Error(codes.Unauthenticated, "malformed credentials")
+	}
+
+	err := VerifyAccess(ctx, key, secret)
+	if err != nil {
+		return ctx, status.Error(codes.Unauthenticated, errors.Unauthorized.Error())
+	}
+	return httpx.ContextWithAccessKey(ctx, key), nil
+}
+
+// Access returns a middleware that requires "Access key:secret" credentials matching the access config of the app
+// stored in the request context. The verified key is stored in the request context, see httpx.AccessKeyFromContext
+func Access() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, secret, ok := httpx.ParseAccessAuthorization(r.Header.Get("Authorization"))
+			if !ok || VerifyAccess(r.Context(), key, secret) != nil {
+				httpx.WriteError(w, errors.Unauthorized)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(httpx.ContextWithAccessKey(r.Context(), key)))
+		})
+	}
+}
+
+func parseBasic(authorization string) (string, string, bool) {
+	const prefix = "basic "
+	if len(authorization) < len(prefix) || !strings.EqualFold(authorization[:len(prefix)], prefix) {
+		return "", "", false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(authorization[len(prefix):])
+	if err != nil {
+		return "", "", false
+	}
+
+	parts := strings.SplitN(string(decoded), ":", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
@@ -0,0 +1,168 @@
+package credentials
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"sync"
+
+	"github.com/omecodes/common/credentials/kdf"
+	"github.com/omecodes/common/env/app"
+	"github.com/omecodes/common/errors"
+	"github.com/omecodes/common/utils/jcon"
+	"github.com/omecodes/common/utils/log"
+)
+
+var (
+	Hash        = kdf.Hash
+	HashWith    = kdf.HashWith
+	NeedsRehash = kdf.NeedsRehash
+)
+
+var (
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
+// verifyDummy spends the same time as a real verification when the subject does not exist
+// so that response time does not reveal it
+func verifyDummy(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = kdf.Hash("dummy password")
+	})
+	_, _ = kdf.Verify(password, dummyHash)
+}
+
+// LegacyCheck compares password with a value stored before hashing was introduced
+type LegacyCheck func(password string, stored string) bool
+
+// legacySHA256 matches the unsalted base64 encoded SHA-256 digests of the old admins config
+func legacySHA256(password string, stored string) bool {
+	data := sha256.Sum256([]byte(password))
+	encoded := base64.StdEncoding.EncodeToString(data[:])
+	return subtle.ConstantTimeCompare([]byte(encoded), []byte(stored)) == 1
+}
+
+// legacyPlain matches secrets stored in plain text
+func legacyPlain(password string, stored string) bool {
+	return subtle.ConstantTimeCompare([]byte(password), []byte(stored)) == 1
+}
+
+// Verify checks password against stored. It tells whether stored must be replaced by a new hash
+func Verify(password string, stored string, legacy LegacyCheck) (ok bool, rehash bool, err error) {
+	if !kdf.IsHash(stored) {
+		if legacy == nil {
+			return false, false, kdf.ErrMalformedHash
+		}
+		return legacy(password, stored), true, nil
+	}
+
+	ok, err = kdf.Verify(password, stored)
+	if err != nil || !ok {
+		return false, false, err
+	}
+	return true, kdf.NeedsRehash(stored), nil
+}
+
+// VerifyAdmin checks user password against the admins credentials config of the app stored in ctx.
+// Legacy SHA-256 entries are rehashed and saved on success
+func VerifyAdmin(ctx context.Context, user string, password string) error {
+	a := app.FromContext(ctx)
+	if a == nil {
+		return errors.Internal
+	}
+
+	admins := a.GetConfig(app.ConfigAdminsCredentials)
+	stored, found := "", false
+	if admins != nil {
+		stored, found = admins.GetString(user)
+	}
+
+	if !found {
+		verifyDummy(password)
+		return errors.Unauthorized
+	}
+
+	ok, rehash, err := Verify(password, stored, legacySHA256)
+	if err != nil {
+		log.Error("[credentials] could not verify admin password", log.Field("user", user), log.Err(err))
+		return errors.Unauthorized
+	}
+	if !ok {
+		return errors.Unauthorized
+	}
+
+	if rehash {
+		updated := jcon.Map{}
+		for name, value := range admins {
+			updated[name] = value
+		}
+		updateHash(a, app.ConfigAdminsCredentials, updated, user, password)
+	}
+	return nil
+}
+
+// VerifyAccess checks an API access key and secret against the access config of the app stored in ctx.
+// The hash of the secret is used, the plain secret being only kept for the clients of the app
+func VerifyAccess(ctx context.Context, key string, secret string) error {
+	return verifyPair(ctx, app.ConfigAccess, "key", "secret", "secret_hash", key, secret)
+}
+
+// VerifyCredentials checks subject password against the credentials config of the app stored in ctx
+func VerifyCredentials(ctx context.Context, subject string, password string) error {
+	return verifyPair(ctx, app.ConfigCredentialsTable, "subject", "password", "password", subject, password)
+}
+
+// verifyPair checks name and secret against the item config. The hash is read from hashKey, or from secretKey for
+// configs saved before hashing was introduced, in which case it is saved under hashKey on success
+func verifyPair(ctx context.Context, item app.ConfigType, nameKey, secretKey, hashKey, name, secret string) error {
+	a := app.FromContext(ctx)
+	if a == nil {
+		return errors.Internal
+	}
+
+	cfg := a.GetConfig(item)
+	if cfg == nil {
+		verifyDummy(secret)
+		return errors.Unauthorized
+	}
+
+	expectedName, _ := cfg.GetString(nameKey)
+	stored, _ := cfg.GetString(hashKey)
+	if stored == "" {
+		stored, _ = cfg.GetString(secretKey)
+	}
+	if subtle.ConstantTimeCompare([]byte(expectedName), []byte(name)) != 1 || stored == "" {
+		verifyDummy(secret)
+		return errors.Unauthorized
+	}
+
+	ok, rehash, err := Verify(secret, stored, legacyPlain)
+	if err != nil || !ok {
+		return errors.Unauthorized
+	}
+
+	if rehash {
+		updated := jcon.Map{}
+		for k, v := range cfg {
+			updated[k] = v
+		}
+		updateHash(a, item, updated, hashKey, secret)
+	}
+	return nil
+}
+
+func updateHash(a *app.App, item app.ConfigType, values jcon.Map, key string, password string) {
+	hash, err := kdf.Hash(password)
+	if err != nil {
+		log.Error("[credentials] could not hash password", log.Err(err))
+		return
+	}
+
+	values[key] = hash
+	err = a.UpdateConfig(item, values)
+	if err != nil {
+		log.Error("[credentials] could not save rehashed password", log.Field("config", item.String()), log.Err(err))
+	}
+}
@@ -0,0 +1,199 @@
+package credentials
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/omecodes/common/credentials/kdf"
+	"github.com/omecodes/common/env/app"
+	"github.com/omecodes/common/errors"
+	"github.com/omecodes/common/httpx"
+	"github.com/omecodes/common/utils/jcon"
+)
+
+func testApp(t *testing.T) (context.Context, *app.App, string) {
+	dir, err := ioutil.TempDir("", "credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := app.New("omecodes", "credentials-test", app.WithCustomAppData(dir))
+	if err = a.InitDirs(); err != nil {
+		t.Fatal(err)
+	}
+	return app.ContextWithApp(context.Background(), a), a, dir
+}
+
+func TestVerifyAdmin(t *testing.T) {
+	ctx, a, dir := testApp(t)
+	defer os.RemoveAll(dir)
+
+	digest := sha256.Sum256([]byte("legacy"))
+	hash, err := kdf.Hash("current")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = a.UpdateConfig(app.ConfigAdminsCredentials, jcon.Map{
+		"alice": base64.StdEncoding.EncodeToString(digest[:]),
+		"bob":   hash,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct{ user, password string }{{"alice", "wrong"}, {"bob", "legacy"}, {"carol", "current"}} {
+		if err = VerifyAdmin(ctx, c.user, c.password); errors.Code(err) != errors.Unauthorized {
+			t.Fatal("invalid credentials must be rejected", c.user, err)
+		}
+	}
+	if err = VerifyAdmin(ctx, "bob", "current"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the legacy digest is replaced with a hash on login
+	if err = VerifyAdmin(ctx, "alice", "legacy"); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := a.GetConfig(app.ConfigAdminsCredentials).GetString("alice")
+	if ok, err := kdf.Verify("legacy", stored); err != nil || !ok {
+		t.Fatal("legacy password must be rehashed", stored, err)
+	}
+	if err = VerifyAdmin(ctx, "alice", "legacy"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyAdminConcurrent(t *testing.T) {
+	ctx, a, dir := testApp(t)
+	defer os.RemoveAll(dir)
+
+	admins := jcon.Map{}
+	for _, user := range []string{"alice", "bob", "carol", "dave"} {
+		digest := sha256.Sum256([]byte(user))
+		admins[user] = base64.StdEncoding.EncodeToString(digest[:])
+	}
+	if err := a.UpdateConfig(app.ConfigAdminsCredentials, admins); err != nil {
+		t.Fatal(err)
+	}
+
+	// legacy entries are rehashed while the other logins read the config
+	var wg sync.WaitGroup
+	errs := make(chan error, 4*len(admins))
+	for user := range admins {
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(user string) {
+				defer wg.Done()
+				errs <- VerifyAdmin(ctx, user, user)
+			}(user)
+		}
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestVerifyAccess(t *testing.T) {
+	ctx, a, dir := testApp(t)
+	defer os.RemoveAll(dir)
+
+	err := a.UpdateConfig(app.ConfigAccess, jcon.Map{"key": "key", "secret": "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct{ key, secret string }{{"key", "wrong"}, {"other", "secret"}, {"", ""}} {
+		if err = VerifyAccess(ctx, c.key, c.secret); errors.Code(err) != errors.Unauthorized {
+			t.Fatal("invalid access must be rejected", c.key, err)
+		}
+	}
+
+	// a plain secret is hashed on success and kept for the clients
+	if err = VerifyAccess(ctx, "key", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	cfg := a.GetConfig(app.ConfigAccess)
+	plain, _ := cfg.GetString("secret")
+	stored, _ := cfg.GetString("secret_hash")
+	if plain != "secret" || !kdf.IsHash(stored) {
+		t.Fatal("unexpected access config", cfg)
+	}
+	if err = VerifyAccess(ctx, "key", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err = VerifyAccess(ctx, "key", "wrong"); errors.Code(err) != errors.Unauthorized {
+		t.Fatal("invalid secret must be rejected", err)
+	}
+}
+
+func TestVerifyCredentials(t *testing.T) {
+	ctx, a, dir := testApp(t)
+	defer os.RemoveAll(dir)
+
+	hash, err := kdf.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = a.UpdateConfig(app.ConfigCredentialsTable, jcon.Map{"subject": "alice", "password": hash})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = VerifyCredentials(ctx, "alice", "password"); err != nil {
+		t.Fatal(err)
+	}
+	if err = VerifyCredentials(ctx, "alice", "wrong"); errors.Code(err) != errors.Unauthorized {
+		t.Fatal("invalid password must be rejected", err)
+	}
+	if err = VerifyCredentials(ctx, "bob", "password"); errors.Code(err) != errors.Unauthorized {
+		t.Fatal("unknown subject must be rejected", err)
+	}
+}
+
+func TestAccessMiddleware(t *testing.T) {
+	ctx, a, dir := testApp(t)
+	defer os.RemoveAll(dir)
+
+	hash, err := kdf.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = a.UpdateConfig(app.ConfigAccess, jcon.Map{"key": "key", "secret": "secret", "secret_hash": hash})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var key string
+	handler := Access()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key = httpx.AccessKeyFromContext(r.Context())
+	}))
+
+	for _, authorization := range []string{"", "Access key:wrong", "Access key:secret"} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if authorization == "Access key:secret" {
+			if w.Code != http.StatusOK || key != "key" {
+				t.Fatal("verified key must be stored in the context", w.Code, key)
+			}
+		} else if w.Code != http.StatusUnauthorized || key != "" {
+			t.Fatal("invalid credentials must be rejected", authorization, w.Code)
+		}
+	}
+}
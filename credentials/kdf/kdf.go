@@ -0,0 +1,156 @@
+package kdf
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/omecodes/common/errors"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+)
+
+// ErrMalformedHash is returned when an encoded hash cannot be parsed
+var ErrMalformedHash = errors.New("kdf: malformed hash")
+
+// Argon2Params holds argon2id cost parameters
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params follows the OWASP recommendations
+var DefaultArgon2Params = Argon2Params{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// maxArgon2Memory is the memory cost in KiB above which a hash is rejected instead of verified
+const maxArgon2Memory = 1024 * 1024
+
+// DefaultBcryptCost is the cost used to hash with bcrypt
+var DefaultBcryptCost = 12
+
+var b64 = base64.RawStdEncoding
+
+// Hash hashes password with argon2id and default params
+func Hash(password string) (string, error) {
+	return HashWith(Argon2id, password)
+}
+
+// HashWith hashes password with the algorithm alg. The result is a PHC string for argon2id,
+// and the standard modular crypt format for bcrypt
+func HashWith(alg string, password string) (string, error) {
+	switch alg {
+	case Argon2id:
+		p := DefaultArgon2Params
+		salt := make([]byte, p.SaltLength)
+		_, err := rand.Read(salt)
+		if err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version, p.Memory, p.Iterations, p.Parallelism, b64.EncodeToString(salt), b64.EncodeToString(key)), nil
+
+	case Bcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), DefaultBcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+
+	default:
+		return "", errors.NotSupported
+	}
+}
+
+// IsHash tells whether encoded is a hash produced by this package
+func IsHash(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$") || isBcrypt(encoded)
+}
+
+func isBcrypt(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// Verify checks password against encoded in constant time
+func Verify(password string, encoded string) (bool, error) {
+	if isBcrypt(encoded) {
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	p, salt, key, err := decodeArgon2(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	computed := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(computed, key) == 1, nil
+}
+
+// NeedsRehash tells whether encoded was not produced with the current default algorithm and params
+func NeedsRehash(encoded string) bool {
+	if !strings.HasPrefix(encoded, "$argon2id$") {
+		return true
+	}
+
+	p, salt, key, err := decodeArgon2(encoded)
+	if err != nil {
+		return true
+	}
+
+	d := DefaultArgon2Params
+	return p.Memory != d.Memory || p.Iterations != d.Iterations || p.Parallelism != d.Parallelism ||
+		uint32(len(salt)) != d.SaltLength || uint32(len(key)) != d.KeyLength
+}
+
+func decodeArgon2(encoded string) (*Argon2Params, []byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != Argon2id {
+		return nil, nil, nil, ErrMalformedHash
+	}
+
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return nil, nil, nil, ErrMalformedHash
+	}
+
+	p := new(Argon2Params)
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism)
+	if err != nil || p.Iterations == 0 || p.Parallelism == 0 || p.Memory > maxArgon2Memory {
+		return nil, nil, nil, ErrMalformedHash
+	}
+
+	salt, err := b64.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, ErrMalformedHash
+	}
+
+	key, err := b64.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return nil, nil, nil, ErrMalformedHash
+	}
+
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+	return p, salt, key, nil
+}
@@ -0,0 +1,56 @@
+package kdf
+
+import "testing"
+
+func TestHashAndVerify(t *testing.T) {
+	for _, alg := range []string{Argon2id, Bcrypt} {
+		encoded, err := HashWith(alg, "password")
+		if err != nil {
+			t.Fatal(alg, err)
+		}
+
+		if !IsHash(encoded) {
+			t.Fatal(alg, "hash not recognized:", encoded)
+		}
+
+		ok, err := Verify("password", encoded)
+		if err != nil || !ok {
+			t.Fatal(alg, "verification failed:", err)
+		}
+
+		ok, err = Verify("wrong", encoded)
+		if err != nil || ok {
+			t.Fatal(alg, "wrong password accepted:", err)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	encoded, err := Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if NeedsRehash(encoded) {
+		t.Fatal("fresh argon2id hash must not need rehash")
+	}
+
+	bcryptHash, err := HashWith(Bcrypt, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !NeedsRehash(bcryptHash) {
+		t.Fatal("bcrypt hash must be upgraded to argon2id")
+	}
+}
+
+func TestVerifyMalformedParams(t *testing.T) {
+	for _, params := range []string{"m=65536,t=0,p=2", "m=65536,t=3,p=0", "m=4294967295,t=3,p=2"} {
+		encoded := "$argon2id$v=19$" + params + "$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+		if ok, err := Verify("password", encoded); ok || err != ErrMalformedHash {
+			t.Fatal("hash params must be rejected", params, err)
+		}
+		if !NeedsRehash(encoded) {
+			t.Fatal("malformed hash must need rehash", params)
+		}
+	}
+}
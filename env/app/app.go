@@ -6,6 +6,7 @@ import (
 	"github.com/omecodes/common/env/web/app"
 	templates2 "github.com/omecodes/common/env/web/templates"
 	"github.com/omecodes/common/futils"
+	"github.com/omecodes/common/netx"
 	"github.com/omecodes/common/utils/jcon"
 	"github.com/omecodes/common/utils/lang"
 	log2 "github.com/omecodes/common/utils/log"
@@ -18,7 +19,7 @@ import (
 )
 
 type App struct {
-	sync.Mutex
+	sync.RWMutex
 	vendor string
 	name   string
 
@@ -178,6 +179,11 @@ func (a *App) initDirs() error {
 	cacheFolder := dirs.QueryFolders(configdir.Cache)[0]
 
 	a.dataDir = globalFolder.Path
+	a.cacheDir = cacheFolder.Path
+	if a.options.customAppDataDirPath != "" {
+		a.dataDir = a.options.customAppDataDirPath
+		a.cacheDir = filepath.Join(a.dataDir, "cache")
+	}
 
 	if a.options.version != "" {
 		a.dataDir = filepath.Join(a.dataDir, fmt.Sprintf("v%s", a.options.version))
@@ -188,7 +194,6 @@ func (a *App) initDirs() error {
 		return err
 	}
 
-	a.cacheDir = cacheFolder.Path
 	err = os.MkdirAll(a.cacheDir, os.ModePerm)
 	if err != nil {
 		return err
@@ -263,10 +268,23 @@ func (a *App) configure(outputFilename string, mode os.FileMode, items ...config
 	return a.configs.Save(outputFilename, mode)
 }
 
+// GetConfig returns the values of item. They must not be modified, UpdateConfig is used to change them
 func (a *App) GetConfig(item ConfigType) jcon.Map {
+	a.RLock()
+	defer a.RUnlock()
 	return a.configs.GetConf(item.String())
 }
 
+// UpdateConfig replaces the values of item and saves the configs file
+func (a *App) UpdateConfig(item ConfigType, values jcon.Map) error {
+	a.Lock()
+	defer a.Unlock()
+
+	a.configs.Set(item.String(), values)
+	cfgFilename := filepath.Join(a.dataDir, "configs.json")
+	return a.configs.Save(cfgFilename, os.ModePerm)
+}
+
 func (a *App) GetCommand() *cobra.Command {
 	return a.cmd
 }
@@ -284,6 +302,9 @@ func (a *App) InitDirs() error {
 }
 
 func (a *App) LoadConfigs() error {
+	a.Lock()
+	defer a.Unlock()
+
 	cfgFilename := filepath.Join(a.dataDir, "configs.json")
 	return jcon.Load(cfgFilename, &a.configs)
 }
@@ -300,6 +321,11 @@ func (a *App) CacheDir() string {
 	return a.cacheDir
 }
 
+// LocalCA loads or creates the local certificate authority persisted in the app data dir
+func (a *App) LocalCA(opts ...netx.LocalCAOption) (*netx.LocalCA, error) {
+	return netx.NewLocalCA(filepath.Join(a.dataDir, "ca"), opts...)
+}
+
 func (a *App) Label() string {
 	return strcase.ToDelimited(a.name, ' ')
 }
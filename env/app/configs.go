@@ -1,9 +1,8 @@
 package app
 
 import (
-	"crypto/sha256"
-	"encoding/base64"
 	"fmt"
+	"github.com/omecodes/common/credentials/kdf"
 	"github.com/omecodes/common/errors"
 	"github.com/omecodes/common/utils/jcon"
 	"github.com/omecodes/common/utils/prompt"
@@ -137,8 +136,16 @@ func configureAccess(description string, defaults jcon.Map) (jcon.Map, error) {
 		return nil, err
 	}
 
+	hashedSecret, err := kdf.Hash(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	// the plain secret is kept for the clients of the app, which send it to the other services.
+	// Incoming credentials are verified against the hash
 	cfg["key"] = name
 	cfg["secret"] = secret
+	cfg["secret_hash"] = hashedSecret
 	return cfg, nil
 }
 
@@ -200,7 +207,12 @@ func configureCredentialsTable(description string, defaults jcon.Map) (jcon.Map,
 	if err != nil {
 		return nil, err
 	}
-	return jcon.Map{"subject": key, "password": secret}, nil
+
+	hashedSecret, err := kdf.Hash(secret)
+	if err != nil {
+		return nil, err
+	}
+	return jcon.Map{"subject": key, "password": hashedSecret}, nil
 }
 
 func configureMailer(description string, defaults jcon.Map) (jcon.Map, error) {
@@ -343,8 +355,11 @@ func configureAdminsCredentials(description string, defaults jcon.Map) (jcon.Map
 			return nil, err
 		}
 
-		data := sha256.Sum256([]byte(password))
-		cfg[user] = base64.StdEncoding.EncodeToString(data[:])
+		hashedPassword, err := kdf.Hash(password)
+		if err != nil {
+			return nil, err
+		}
+		cfg[user] = hashedPassword
 		fmt.Println()
 	}
 	return cfg, err
@@ -585,11 +600,38 @@ func configureOauth2Providers(description string, defaults jcon.Map) (jcon.Map,
 			return nil, err
 		}
 
+		authorizeEndpoint, _ := defaultsValues.GetString("config/authorize_endpoint")
+		if authorizeEndpoint == "" {
+			authorizeEndpoint = "/authorize"
+		}
+		authorizeEndpoint, err = prompt.TextWithDefault("authorize endpoint", authorizeEndpoint, false)
+		if err != nil {
+			return nil, err
+		}
+
+		tokenEndpoint, _ := defaultsValues.GetString("config/token_endpoint")
+		if tokenEndpoint == "" {
+			tokenEndpoint = "/token"
+		}
+		tokenEndpoint, err = prompt.TextWithDefault("token endpoint", tokenEndpoint, false)
+		if err != nil {
+			return nil, err
+		}
+
+		oldScope, _ := defaultsValues.GetString("config/scope")
+		scope, err := prompt.TextWithDefault("scope", oldScope, true)
+		if err != nil {
+			return nil, err
+		}
+
 		cfg[name] = jcon.Map{
 			"config": jcon.Map{
-				"server_url": serverURL,
-				"client_id":  clientID,
-				"secret":     clientSecret,
+				"server_url":         serverURL,
+				"client_id":          clientID,
+				"secret":             clientSecret,
+				"authorize_endpoint": authorizeEndpoint,
+				"token_endpoint":     tokenEndpoint,
+				"scope":              scope,
 			},
 			"info": jcon.Map{
 				"label":    label,
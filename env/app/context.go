@@ -31,8 +31,10 @@ func FromContext(ctx context.Context) *App {
 
 func ConfigFromContext(ctx context.Context, item ConfigType) jcon.Map {
 	app := FromContext(ctx)
-	cfg := app.configs.GetConf(item.String())
-	return cfg
+	if app == nil {
+		return nil
+	}
+	return app.GetConfig(item)
 }
 
 func Oauth2ProviderConfig(ctx context.Context, providerName string) jcon.Map {
@@ -41,7 +43,7 @@ func Oauth2ProviderConfig(ctx context.Context, providerName string) jcon.Map {
 		return nil
 	}
 
-	cfg := app.configs.GetConf(ConfigOauth2Providers.String())
+	cfg := app.GetConfig(ConfigOauth2Providers)
 	if cfg == nil {
 		return nil
 	}
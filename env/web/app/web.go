@@ -30,7 +30,8 @@ type Webapp struct {
 	i18n *lang.I18n
 }
 
-func (s *Webapp) Serve(locale string, appName string, filename string) (string, io.ReadCloser, int64, error) {
+// resolve returns the path of filename in the appName folder of locale, falling back to en-US
+func (s *Webapp) resolve(locale string, appName string, filename string) (string, error) {
 	if locale == "" {
 		locale = "en-US"
 	}
@@ -42,40 +43,42 @@ func (s *Webapp) Serve(locale string, appName string, filename string) (string,
 
 	localeFolder = filepath.Join(s.dir, locale, appName)
 	if !futils.FileExists(localeFolder) {
-		return "", nil, 0, errors.NotFound
+		return "", errors.NotFound
 	}
 
 	resPath := filepath.Join(localeFolder, filename)
-
-	size := int64(0)
-	contentType := ""
-	extension := filepath.Ext(resPath)
-	if extension == "" {
+	if filepath.Ext(resPath) == "" {
 		resPath = filepath.Join(resPath, "index.html")
-		extension = "html"
 	}
 
 	if !futils.FileExists(resPath) {
-		return "", nil, 0, errors.NotFound
+		return "", errors.NotFound
+	}
+	return resPath, nil
+}
+
+func (s *Webapp) Serve(locale string, appName string, filename string) (string, io.ReadCloser, int64, error) {
+	resPath, err := s.resolve(locale, appName, filename)
+	if err != nil {
+		return "", nil, 0, err
 	}
 
 	f, err := os.Open(resPath)
 	if err != nil {
-		return "", f, size, err
+		return "", f, 0, err
 	}
 
 	stat, err := f.Stat()
 	if err != nil {
-		return "", f, size, err
+		return "", f, 0, err
 	}
 
-	size = stat.Size()
-	contentType, ok := mimes[extension]
+	contentType, ok := mimes[filepath.Ext(resPath)]
 	if !ok {
-		extension = futils.Mime(filename)
+		contentType = futils.Mime(resPath)
 	}
 
-	return contentType, f, size, err
+	return contentType, f, stat.Size(), err
 }
 
 func (s *Webapp) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -97,12 +100,15 @@ func (s *Webapp) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		filename = path.Join(parts[1:]...)
 	}
 
-	contentType, content, size, err := s.Serve(locale, appName, filename)
+	resPath, err := s.resolve(locale, appName, filename)
 	if err != nil {
 		httpx.WriteError(w, err)
 		return
 	}
 
-	defer content.Close()
-	httpx.WriteContent(w, contentType, size, content)
+	// serves precompressed bundles and honours conditional and range requests
+	if contentType, ok := mimes[filepath.Ext(resPath)]; ok {
+		w.Header().Set("Content-Type", contentType)
+	}
+	httpx.ServeFile(w, r, resPath)
 }
@@ -1,9 +1,11 @@
 package errors
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 )
 
 var New = errors.New
@@ -22,6 +24,15 @@ const (
 	NotSupported        = Error(8)
 	NotImplemented      = Error(9)
 	ServiceNotAvailable = Error(10)
+	TooManyRequests     = Error(11)
+	Timeout             = Error(12)
+	Canceled            = Error(13)
+
+	// UnsupportedMediaType is returned for request bodies whose content type cannot be decoded
+	UnsupportedMediaType = Error(14)
+
+	// TooLarge is returned for request bodies over the size limit
+	TooLarge = Error(15)
 )
 
 func (e Error) Error() string {
@@ -53,45 +64,111 @@ func (e Error) Error() string {
 	case ServiceNotAvailable:
 		return "service not available"
 
+	case TooManyRequests:
+		return "too many requests"
+
+	case Timeout:
+		return "timeout"
+
+	case Canceled:
+		return "canceled"
+
+	case UnsupportedMediaType:
+		return "unsupported media type"
+
+	case TooLarge:
+		return "too large"
+
 	default:
 		return "internal"
 	}
 }
 
+// MessageKey returns the stable i18n key of the code message, like "error.not_found"
+func (e Error) MessageKey() string {
+	return "error." + strings.ReplaceAll(known(e).Error(), " ", "_")
+}
+
 func (e Error) HttpCode() int {
 	return HttpStatus(e)
 }
 
+// HttpStatus returns the HTTP status matching the code of e
 func HttpStatus(e error) int {
-	str := e.Error()
-	switch str {
-	case "not found":
-		return http.StatusNotFound
-	case "duplicate":
-		return http.StatusConflict
-	case "forbidden":
-		return http.StatusForbidden
-	case "unauthorized":
-		return http.StatusUnauthorized
-	case "unavailable":
-		return http.StatusServiceUnavailable
-	case "bad input":
-		return http.StatusBadRequest
-	case "not supported":
-		return http.StatusHTTPVersionNotSupported
-	case "not implemented":
-		return http.StatusNotImplemented
-	case "service not available":
-		return http.StatusServiceUnavailable
-	default:
-		return http.StatusInternalServerError
+	if e == nil {
+		return http.StatusOK
+	}
+	return httpStatuses[Code(e)]
+}
+
+// statusClientClosedRequest is the non standard status used by proxies when clients give up
+const statusClientClosedRequest = 499
+
+var httpStatuses = map[Error]int{
+	Internal:             http.StatusInternalServerError,
+	NotFound:             http.StatusNotFound,
+	Duplicate:            http.StatusConflict,
+	Forbidden:            http.StatusForbidden,
+	Unauthorized:         http.StatusUnauthorized,
+	Unavailable:          http.StatusServiceUnavailable,
+	BadInput:             http.StatusBadRequest,
+	NotSupported:         http.StatusNotImplemented,
+	NotImplemented:       http.StatusNotImplemented,
+	ServiceNotAvailable:  http.StatusServiceUnavailable,
+	TooManyRequests:      http.StatusTooManyRequests,
+	Timeout:              http.StatusGatewayTimeout,
+	Canceled:             statusClientClosedRequest,
+	UnsupportedMediaType: http.StatusUnsupportedMediaType,
+	TooLarge:             http.StatusRequestEntityTooLarge,
+}
+
+// Code returns the code of e. It is found in the chain of wrapped errors with errors.As
+// and, for errors rebuilt from plain messages, by matching the code text. Unknown errors are Internal
+func Code(e error) Error {
+	var failure *Failure
+	if errors.As(e, &failure) {
+		return known(failure.Code)
+	}
+
+	var code Error
+	if errors.As(e, &code) {
+		return known(code)
+	}
+
+	if errors.Is(e, context.Canceled) {
+		return Canceled
+	}
+	if errors.Is(e, context.DeadlineExceeded) {
+		return Timeout
+	}
+
+	if e != nil {
+		if code, found := codesByText[e.Error()]; found {
+			return code
+		}
+	}
+	return Internal
+}
+
+func known(code Error) Error {
+	if _, found := httpStatuses[code]; found {
+		return code
+	}
+	return Internal
+}
+
+var codesByText = map[string]Error{}
+
+func init() {
+	for code := range httpStatuses {
+		codesByText[code.Error()] = code
 	}
 }
 
 func IsNotFound(e error) bool {
-	return errors.Is(e, NotFound) || e.Error() == "not found"
+	return e != nil && Code(e) == NotFound
 }
 
 func IsForbidden(e error) bool {
-	return errors.Is(e, Forbidden) || e.Error() == "forbidden"
+	return e != nil && Code(e) == Forbidden
 }
@@ -0,0 +1,152 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Failure is an error with a code, a message safe to show to clients, the wrapped cause,
+// details and the list of invalid fields. The With methods return copies, so failures declared
+// as package variables can be enriched per call
+type Failure struct {
+	Code       Error
+	Message    string
+	Cause      error
+	Details    map[string]interface{}
+	Violations []FieldViolation
+
+	// Key and Args are the i18n key and arguments of the message. The code key is used when Key is empty
+	Key  string
+	Args []interface{}
+}
+
+// FieldViolation describes why a field of a request is invalid
+type FieldViolation struct {
+	Field       string `json:"name"`
+	Description string `json:"reason"`
+}
+
+// Create creates a failure with code and message. The message is formatted with args if any
+func Create(code Error, message string, args ...interface{}) *Failure {
+	if len(args) > 0 {
+		message = fmt.Sprintf(message, args...)
+	}
+	return &Failure{Code: code, Message: message}
+}
+
+// Wrap creates a failure with code and message that wraps cause
+func Wrap(code Error, cause error, message string) *Failure {
+	return &Failure{Code: code, Message: message, Cause: cause}
+}
+
+// Invalid creates a BadInput failure for a single invalid field
+func Invalid(field, description string) *Failure {
+	return Create(BadInput, "").WithViolation(field, description)
+}
+
+// FailureOf returns the failure in the chain of err. Errors without failure are converted using their code
+func FailureOf(err error) *Failure {
+	if err == nil {
+		return nil
+	}
+
+	var failure *Failure
+	if errors.As(err, &failure) {
+		return failure
+	}
+	return &Failure{Code: Code(err), Cause: err}
+}
+
+func (f *Failure) Error() string {
+	message := f.Message
+	if message == "" {
+		message = f.Code.Error()
+	}
+
+	if len(f.Violations) > 0 {
+		var fields []string
+		for _, v := range f.Violations {
+			fields = append(fields, v.Field+": "+v.Description)
+		}
+		message += " (" + strings.Join(fields, ", ") + ")"
+	}
+
+	if f.Cause != nil {
+		message += ": " + f.Cause.Error()
+	}
+	return message
+}
+
+// Unwrap returns the cause
+func (f *Failure) Unwrap() error {
+	return f.Cause
+}
+
+// Is reports whether target is the failure code, so that errors.Is(err, NotFound) matches failures
+func (f *Failure) Is(target error) bool {
+	code, ok := target.(Error)
+	return ok && code == f.Code
+}
+
+// clone returns a copy of f that can be changed without changing f, which may be a shared variable
+func (f *Failure) clone() *Failure {
+	c := *f
+	if f.Details != nil {
+		c.Details = make(map[string]interface{}, len(f.Details))
+		for key, value := range f.Details {
+			c.Details[key] = value
+		}
+	}
+	c.Violations = append([]FieldViolation(nil), f.Violations...)
+	c.Args = append([]interface{}(nil), f.Args...)
+	return &c
+}
+
+// WithCause returns a copy of f that wraps cause
+func (f *Failure) WithCause(cause error) *Failure {
+	c := f.clone()
+	c.Cause = cause
+	return c
+}
+
+// WithDetail returns a copy of f with the detail added
+func (f *Failure) WithDetail(key string, value interface{}) *Failure {
+	c := f.clone()
+	if c.Details == nil {
+		c.Details = map[string]interface{}{}
+	}
+	c.Details[key] = value
+	return c
+}
+
+// WithViolation returns a copy of f with the invalid field added
+func (f *Failure) WithViolation(field, description string) *Failure {
+	c := f.clone()
+	c.Violations = append(c.Violations, FieldViolation{Field: field, Description: description})
+	return c
+}
+
+// Localized returns a copy of f with the i18n key of the message and its arguments
+func (f *Failure) Localized(key string, args ...interface{}) *Failure {
+	c := f.clone()
+	c.Key = key
+	c.Args = args
+	return c
+}
+
+// MessageKey returns the i18n key of the message and its arguments
+func (f *Failure) MessageKey() (string, []interface{}) {
+	if f.Key != "" {
+		return f.Key, f.Args
+	}
+	return f.Code.MessageKey(), nil
+}
+
+// Public returns the message that can be sent to clients. The cause is never exposed
+func (f *Failure) Public() string {
+	if f.Message != "" {
+		return f.Message
+	}
+	return f.Code.Error()
+}
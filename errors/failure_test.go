@@ -0,0 +1,102 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+)
+
+func TestCodeOfWrappedErrors(t *testing.T) {
+	err := fmt.Errorf("loading user: %w", Wrap(NotFound, errors.New("no rows"), "user not found"))
+
+	if Code(err) != NotFound || HttpStatus(err) != http.StatusNotFound {
+		t.Fatal("wrapped failures must keep their code")
+	}
+
+	if !errors.Is(err, NotFound) || !IsNotFound(err) {
+		t.Fatal("failures must match their code")
+	}
+
+	if HttpStatus(fmt.Errorf("saving: %w", Duplicate)) != http.StatusConflict {
+		t.Fatal("wrapped codes must keep their status")
+	}
+
+	if HttpStatus(errors.New("boom")) != http.StatusInternalServerError {
+		t.Fatal("unknown errors must be internal")
+	}
+}
+
+func TestGRPCRoundTrip(t *testing.T) {
+	f := Create(BadInput, "invalid user").
+		WithDetail("id", "u1").
+		WithViolation("email", "must be a valid address").
+		WithCause(errors.New("secret cause"))
+
+	st := GRPCStatus(f)
+	if st.Code() != codes.InvalidArgument || st.Message() != "invalid user" {
+		t.Fatal("unexpected status", st.Code(), st.Message())
+	}
+
+	back := FailureOf(FromGRPC(st.Err()))
+	if back.Code != BadInput || back.Message != "invalid user" || back.Details["id"] != "u1" {
+		t.Fatal("unexpected failure", back)
+	}
+
+	if len(back.Violations) != 1 || back.Violations[0].Field != "email" {
+		t.Fatal("violations must be restored", back.Violations)
+	}
+
+	if back.Cause != nil {
+		t.Fatal("causes must not be sent")
+	}
+
+	if FailureOf(FromGRPC(ServiceNotAvailable.GRPCStatus().Err())).Code != ServiceNotAvailable {
+		t.Fatal("codes sharing a gRPC code must be restored")
+	}
+}
+
+func TestFromGRPCInternal(t *testing.T) {
+	back := FailureOf(FromGRPC(GRPCStatus(errors.New("dial tcp 10.0.0.3:5432: connection refused")).Err()))
+	if back.Code != Internal || back.Public() != Internal.Error() {
+		t.Fatal("internal error text must not be public", back.Public())
+	}
+	if back.Cause == nil {
+		t.Fatal("internal error text must be kept as cause")
+	}
+}
+
+func TestFailureBuildersCopy(t *testing.T) {
+	sentinel := Create(Unauthorized, "token is expired")
+
+	enriched := sentinel.WithDetail("kid", "k1").WithViolation("token", "expired").Localized("token_expired")
+	if enriched == sentinel || enriched.Details["kid"] != "k1" || enriched.Key != "token_expired" {
+		t.Fatal("unexpected enriched failure", enriched)
+	}
+	if sentinel.Details != nil || sentinel.Violations != nil || sentinel.Key != "" {
+		t.Fatal("shared failures must not be changed", sentinel)
+	}
+}
+
+func TestProblem(t *testing.T) {
+	w := httptest.NewRecorder()
+	ProblemOf(Invalid("name", "required")).Write(w)
+
+	if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != ProblemContentType {
+		t.Fatal("unexpected response", w.Code, w.Header())
+	}
+
+	var p Problem
+	err := json.Unmarshal(w.Body.Bytes(), &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Code != "bad input" || len(p.InvalidParams) != 1 || p.InvalidParams[0].Field != "name" {
+		t.Fatal("unexpected problem", p)
+	}
+}
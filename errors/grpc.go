@@ -0,0 +1,219 @@
+package errors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorInfoDomain identifies the ErrorInfo details written by this package
+const errorInfoDomain = "omecodes"
+
+// metadata entries holding the i18n key and arguments of failures messages
+const (
+	metadataKey  = "@key"
+	metadataArgs = "@args"
+)
+
+var grpcCodes = map[Error]codes.Code{
+	Internal:             codes.Internal,
+	NotFound:             codes.NotFound,
+	Unavailable:          codes.Unavailable,
+	Forbidden:            codes.PermissionDenied,
+	Unauthorized:         codes.Unauthenticated,
+	Duplicate:            codes.AlreadyExists,
+	BadInput:             codes.InvalidArgument,
+	NotSupported:         codes.Unimplemented,
+	NotImplemented:       codes.Unimplemented,
+	ServiceNotAvailable:  codes.Unavailable,
+	TooManyRequests:      codes.ResourceExhausted,
+	Timeout:              codes.DeadlineExceeded,
+	Canceled:             codes.Canceled,
+	UnsupportedMediaType: codes.InvalidArgument,
+	TooLarge:             codes.ResourceExhausted,
+}
+
+var errorCodes = map[codes.Code]Error{
+	codes.Canceled:           Canceled,
+	codes.Unknown:            Internal,
+	codes.InvalidArgument:    BadInput,
+	codes.DeadlineExceeded:   Timeout,
+	codes.NotFound:           NotFound,
+	codes.AlreadyExists:      Duplicate,
+	codes.PermissionDenied:   Forbidden,
+	codes.ResourceExhausted:  TooManyRequests,
+	codes.FailedPrecondition: BadInput,
+	codes.Aborted:            Duplicate,
+	codes.OutOfRange:         BadInput,
+	codes.Unimplemented:      NotImplemented,
+	codes.Internal:           Internal,
+	codes.Unavailable:        Unavailable,
+	codes.DataLoss:           Internal,
+	codes.Unauthenticated:    Unauthorized,
+}
+
+// GRPCCode returns the gRPC code matching the code of err
+func GRPCCode(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+	return grpcCodes[Code(err)]
+}
+
+// GRPCStatus converts err to a gRPC status. Failure details and violations are sent as
+// ErrorInfo and BadRequest details. gRPC statuses found in the chain of err are returned as is
+// and errors without code get the Unknown code, like gRPC does
+func GRPCStatus(err error) *status.Status {
+	if err == nil {
+		return nil
+	}
+
+	var failure *Failure
+	if errors.As(err, &failure) {
+		return failure.GRPCStatus()
+	}
+
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &grpcErr) {
+		return grpcErr.GRPCStatus()
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return Canceled.GRPCStatus()
+	case errors.Is(err, context.DeadlineExceeded):
+		return Timeout.GRPCStatus()
+	default:
+		return status.New(codes.Unknown, err.Error())
+	}
+}
+
+// GRPCStatus makes gRPC servers return the matching status when code is returned by a handler
+func (e Error) GRPCStatus() *status.Status {
+	return (&Failure{Code: e}).GRPCStatus()
+}
+
+// GRPCStatus makes gRPC servers return the matching status when f is returned by a handler
+func (f *Failure) GRPCStatus() *status.Status {
+	code := Code(f)
+	st := status.New(grpcCodes[code], f.Public())
+
+	info := &errdetails.ErrorInfo{
+		Reason: code.Error(),
+		Domain: errorInfoDomain,
+	}
+	for key, value := range f.Details {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		if info.Metadata == nil {
+			info.Metadata = map[string]string{}
+		}
+		info.Metadata[key] = string(encoded)
+	}
+
+	if f.Key != "" {
+		if info.Metadata == nil {
+			info.Metadata = map[string]string{}
+		}
+		info.Metadata[metadataKey] = f.Key
+		if encoded, err := json.Marshal(f.Args); err == nil && len(f.Args) > 0 {
+			info.Metadata[metadataArgs] = string(encoded)
+		}
+	}
+
+	withDetails, err := st.WithDetails(info)
+	if err != nil {
+		return st
+	}
+	st = withDetails
+
+	if len(f.Violations) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, v := range f.Violations {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       v.Field,
+				Description: v.Description,
+			})
+		}
+
+		withDetails, err = st.WithDetails(badRequest)
+		if err == nil {
+			st = withDetails
+		}
+	}
+	return st
+}
+
+// FromGRPC converts an error returned by a gRPC client to a failure, restoring the code,
+// details and violations sent by GRPCStatus. Errors that are not gRPC statuses are returned as they are
+func FromGRPC(err error) error {
+	st, ok := status.FromError(err)
+	if !ok || st == nil {
+		return err
+	}
+	if st.Code() == codes.OK {
+		return nil
+	}
+	return FromStatus(st)
+}
+
+// FromStatus converts a gRPC status to a failure. The message of Internal and Unknown statuses becomes the cause
+func FromStatus(st *status.Status) *Failure {
+	f := &Failure{Code: Internal, Message: st.Message()}
+	if code, found := errorCodes[st.Code()]; found {
+		f.Code = code
+	}
+
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			if d.Domain != errorInfoDomain {
+				continue
+			}
+
+			if code, found := codesByText[d.Reason]; found {
+				f.Code = code
+			}
+
+			for key, encoded := range d.Metadata {
+				if key == metadataKey {
+					f.Key = encoded
+					continue
+				}
+				if key == metadataArgs {
+					_ = json.Unmarshal([]byte(encoded), &f.Args)
+					continue
+				}
+
+				var value interface{}
+				if json.Unmarshal([]byte(encoded), &value) != nil {
+					value = encoded
+				}
+				f = f.WithDetail(key, value)
+			}
+
+		case *errdetails.BadRequest:
+			for _, v := range d.FieldViolations {
+				f = f.WithViolation(v.Field, v.Description)
+			}
+		}
+	}
+
+	if f.Message == f.Code.Error() {
+		f.Message = ""
+	}
+
+	// servers send the text of unexpected errors with Internal and Unknown statuses. It is kept as cause,
+	// which is never exposed, so that the generic message is shown to clients
+	if f.Code == Internal && f.Message != "" {
+		f.Cause = errors.New(f.Message)
+		f.Message = ""
+	}
+	return f
+}
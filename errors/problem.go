@@ -0,0 +1,70 @@
+package errors
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ProblemContentType is the media type of problem documents
+const ProblemContentType = "application/problem+json"
+
+// Problem is the RFC 7807 document written for errors in HTTP responses
+type Problem struct {
+	Type          string                 `json:"type,omitempty"`
+	Title         string                 `json:"title"`
+	Status        int                    `json:"status"`
+	Detail        string                 `json:"detail,omitempty"`
+	Instance      string                 `json:"instance,omitempty"`
+	Code          string                 `json:"code"`
+	Details       map[string]interface{} `json:"details,omitempty"`
+	InvalidParams []FieldViolation       `json:"invalid-params,omitempty"`
+}
+
+// ProblemOf builds the problem document of err. Causes are not exposed
+func ProblemOf(err error) *Problem {
+	f := FailureOf(err)
+	code := Code(f)
+	status := httpStatuses[code]
+
+	p := &Problem{
+		Type:          "about:blank",
+		Title:         http.StatusText(status),
+		Status:        status,
+		Code:          code.Error(),
+		Details:       f.Details,
+		InvalidParams: f.Violations,
+	}
+	if p.Title == "" {
+		p.Title = code.Error()
+	}
+	if f.Message != "" {
+		p.Detail = f.Message
+	}
+	return p
+}
+
+// Translator returns the translation of key formatted with args, or false when key is not translated
+type Translator func(key string, args ...interface{}) (string, bool)
+
+// LocalizedProblemOf builds the problem document of err with the message translated by translate.
+// The untranslated message is kept when translate has no translation for the key
+func LocalizedProblemOf(err error, translate Translator) *Problem {
+	p := ProblemOf(err)
+	if translate == nil {
+		return p
+	}
+
+	key, args := FailureOf(err).MessageKey()
+	if text, ok := translate(key, args...); ok {
+		p.Detail = text
+	}
+	return p
+}
+
+// Write writes the problem document with its status
+func (p *Problem) Write(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
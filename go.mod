@@ -3,25 +3,33 @@ module github.com/omecodes/common
 go 1.13
 
 require (
+	github.com/andybalholm/brotli v1.0.1
 	github.com/boltdb/bolt v1.3.1
+	github.com/go-redis/redis/v8 v8.4.0
 	github.com/golang/protobuf v1.4.3
 	github.com/gorilla/mux v1.8.0
 	github.com/gorilla/securecookie v1.1.1
 	github.com/gorilla/sessions v1.2.1
+	github.com/gorilla/websocket v1.4.2
 	github.com/grpc-ecosystem/go-grpc-middleware v1.2.2
 	github.com/grpc-ecosystem/go-grpc-prometheus v1.2.0
 	github.com/grpc-ecosystem/grpc-gateway v1.16.0
 	github.com/iancoleman/strcase v0.1.2
 	github.com/jinzhu/gorm v1.9.16
 	github.com/manifoldco/promptui v0.8.0
+	github.com/mattn/go-sqlite3 v1.14.0
 	github.com/omecodes/libome v0.0.0-20201128214815-2b3f03af9fa6
+	github.com/prometheus/client_golang v0.9.3
 	github.com/sendgrid/rest v2.6.2+incompatible // indirect
 	github.com/sendgrid/sendgrid-go v3.7.2+incompatible
 	github.com/shibukawa/configdir v0.0.0-20170330084843-e180dbdc8da0
 	github.com/spf13/cobra v1.1.1
+	github.com/vmihailenco/msgpack/v4 v4.3.12
 	go.uber.org/multierr v1.6.0 // indirect
 	go.uber.org/zap v1.16.0
+	golang.org/x/crypto v0.0.0-20201124201722-c8d3bf9c5392
 	golang.org/x/text v0.3.4
+	google.golang.org/genproto v0.0.0-20201119123407-9b1e624d6bc4
 	google.golang.org/grpc v1.33.2
 	google.golang.org/grpc/examples v0.0.0-20201130222003-4a0125ac5808 // indirect
 	gopkg.in/AlecAivazis/survey.v1 v1.8.8
@@ -4,6 +4,10 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	grpc_auth "github.com/grpc-ecosystem/go-grpc-middleware/auth"
+	"github.com/omecodes/common/jwt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type gRPCClientApiAccess struct {
@@ -74,12 +78,16 @@ func NewGRPCProxy(key, secret string) *gRPCProxyBasic {
 }
 
 type gRPCClientJwt struct {
-	jwt string
+	source jwt.TokenSource
 }
 
 func (g *gRPCClientJwt) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	t, err := g.source.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
 	return map[string]string{
-		"authorization": "Bearer " + g.jwt,
+		"authorization": "Bearer " + t,
 	}, nil
 }
 
@@ -88,5 +96,27 @@ func (g *gRPCClientJwt) RequireTransportSecurity() bool {
 }
 
 func NewGRPCClientJwt(t string) *gRPCClientJwt {
-	return &gRPCClientJwt{jwt: t}
+	return &gRPCClientJwt{source: jwt.StaticSource(t)}
+}
+
+// NewGRPCClientJwtSource creates per-RPC credentials that get a fresh token from source for each call
+func NewGRPCClientJwtSource(source jwt.TokenSource) *gRPCClientJwt {
+	return &gRPCClientJwt{source: source}
+}
+
+// JwtAuthentication returns an authentication func that verifies bearer tokens with verifier.
+// The verified token is accessible with jwt.TokenFromContext
+func JwtAuthentication(verifier *jwt.Verifier) func(ctx context.Context) (context.Context, error) {
+	return func(ctx context.Context) (context.Context, error) {
+		raw, err := grpc_auth.AuthFromMD(ctx, "bearer")
+		if err != nil {
+			return ctx, err
+		}
+
+		t, err := verifier.Verify(raw)
+		if err != nil {
+			return ctx, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return jwt.ContextWithToken(ctx, t), nil
+	}
 }
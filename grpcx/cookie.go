@@ -3,25 +3,89 @@ package grpcx
 import (
 	"context"
 	"github.com/omecodes/common/errors"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 	"net/http"
+	"time"
 )
 
-func Get(ctx context.Context, name string) (*http.Cookie, error) {
+// gRPCCookie is the metadata key of cookies sent by native gRPC clients
+const gRPCCookie = "cookie"
+
+// Cookies returns the cookies of the call, forwarded by the gateway or sent by gRPC clients in the cookie metadata
+func Cookies(ctx context.Context) []*http.Cookie {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return nil, errors.NotFound
+		return nil
 	}
 
-	setCookieValues := md.Get("grpcgateway-cookie")
-	if len(setCookieValues) == 0 {
+	hr := &http.Request{
+		Header: http.Header{},
+	}
+	for _, key := range []string{gRPCHeaderCookie, gRPCCookie} {
+		for _, value := range md.Get(key) {
+			hr.Header.Add(httpHeaderCookie, value)
+		}
+	}
+	return hr.Cookies()
+}
+
+// Get returns the cookie name of the call
+func Get(ctx context.Context, name string) (*http.Cookie, error) {
+	if _, ok := metadata.FromIncomingContext(ctx); !ok {
 		return nil, errors.NotFound
 	}
 
-	hr := &http.Request{
-		Header: http.Header{},
+	for _, c := range Cookies(ctx) {
+		if c.Name == name {
+			return c, nil
+		}
 	}
+	return nil, http.ErrNoCookie
+}
+
+type cookieOptions struct {
+	partitioned bool
+}
+
+// CookieOption adds attributes http.Cookie does not have to cookies set with SetCookie
+type CookieOption func(opts *cookieOptions)
+
+// Partitioned adds the Partitioned attribute, which keys third-party cookies by the top-level site (CHIPS).
+// The cookie must be Secure
+func Partitioned() CookieOption {
+	return func(opts *cookieOptions) {
+		opts.partitioned = true
+	}
+}
+
+// SetCookie adds cookie to the response header. The gateway sends it in its own Set-Cookie header with all its
+// attributes. It can be called several times
+func SetCookie(ctx context.Context, cookie *http.Cookie, opts ...CookieOption) error {
+	options := &cookieOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	value := cookie.String()
+	if value == "" {
+		return errors.Create(errors.BadInput, "invalid cookie %q", cookie.Name)
+	}
+	if options.partitioned {
+		if !cookie.Secure {
+			return errors.Create(errors.BadInput, "partitioned cookie %q must be secure", cookie.Name)
+		}
+		value += "; Partitioned"
+	}
+	return grpc.SetHeader(ctx, metadata.Pairs(gRPCSetCookie, value))
+}
 
-	hr.Header.Add("Cookie", setCookieValues[0])
-	return hr.Cookie(name)
+// DeleteCookie tells the client to delete the cookie name set with the path "/"
+func DeleteCookie(ctx context.Context, name string) error {
+	return SetCookie(ctx, &http.Cookie{
+		Name:    name,
+		Path:    "/",
+		MaxAge:  -1,
+		Expires: time.Unix(1, 0),
+	})
 }
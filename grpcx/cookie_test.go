@@ -0,0 +1,84 @@
+package grpcx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestCookies(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.MD{
+		gRPCHeaderCookie: []string{"a=1; b=2", "c=3"},
+		gRPCCookie:       []string{"d=4"},
+	})
+
+	cookies := Cookies(ctx)
+	if len(cookies) != 4 {
+		t.Fatal("all the cookies must be returned", cookies)
+	}
+
+	c, err := Get(ctx, "c")
+	if err != nil || c.Value != "3" {
+		t.Fatal("unexpected cookie", c, err)
+	}
+	if _, err = Get(ctx, "e"); err != http.ErrNoCookie {
+		t.Fatal("unexpected error", err)
+	}
+}
+
+func TestSetCookie(t *testing.T) {
+	stream := new(transportStream)
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+
+	err := SetCookie(ctx, &http.Cookie{
+		Name:     "theme",
+		Value:    "dark",
+		Path:     "/",
+		MaxAge:   3600,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = DeleteCookie(ctx, "session"); err != nil {
+		t.Fatal(err)
+	}
+	if err = SetCookie(ctx, &http.Cookie{Name: "embed", Value: "1", Secure: true, SameSite: http.SameSiteNoneMode}, Partitioned()); err != nil {
+		t.Fatal(err)
+	}
+	if err = SetCookie(ctx, &http.Cookie{Name: "invalid name"}); err == nil {
+		t.Fatal("invalid cookies must be rejected")
+	}
+	if err = SetCookie(ctx, &http.Cookie{Name: "embed", Value: "1"}, Partitioned()); err == nil {
+		t.Fatal("partitioned cookies must be secure")
+	}
+
+	// the gateway forwards each value in its own header
+	w := httptest.NewRecorder()
+	ctx = runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{HeaderMD: stream.header})
+	if err = SetCookieFromGRPCMetadata(ctx, w, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	values := w.Header()["Set-Cookie"]
+	if len(values) != 3 {
+		t.Fatal("all the cookies must be forwarded", values)
+	}
+	if values[0] != "theme=dark; Path=/; Max-Age=3600; Secure; SameSite=Strict" {
+		t.Fatal("cookie attributes must be kept", values[0])
+	}
+
+	cookies := w.Result().Cookies()
+	if cookies[1].Name != "session" || cookies[1].MaxAge != -1 {
+		t.Fatal("deleted cookie must expire", cookies[1])
+	}
+	if values[2] != "embed=1; Secure; SameSite=None; Partitioned" {
+		t.Fatal("partitioned attribute must be kept", values[2])
+	}
+}
@@ -0,0 +1,60 @@
+package grpcx
+
+import (
+	"context"
+
+	"github.com/omecodes/common/errors"
+	"google.golang.org/grpc"
+)
+
+// toStatusError converts the errors.Error and errors.Failure values found in the chain of err to gRPC status errors
+func toStatusError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return errors.GRPCStatus(err).Err()
+}
+
+func errorsUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	rsp, err := handler(ctx, req)
+	return rsp, toStatusError(err)
+}
+
+func errorsStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return toStatusError(handler(srv, ss))
+}
+
+// ErrorsUnaryClientInterceptor converts the statuses returned by servers to errors.Failure values,
+// so that checks like errors.IsNotFound work on client side
+func ErrorsUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return errors.FromGRPC(invoker(ctx, method, req, reply, cc, opts...))
+	}
+}
+
+// ErrorsStreamClientInterceptor is the stream version of ErrorsUnaryClientInterceptor
+func ErrorsStreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, errors.FromGRPC(err)
+		}
+		return &errorsClientStream{ClientStream: cs}, nil
+	}
+}
+
+type errorsClientStream struct {
+	grpc.ClientStream
+}
+
+func (s *errorsClientStream) SendMsg(m interface{}) error {
+	return errors.FromGRPC(s.ClientStream.SendMsg(m))
+}
+
+func (s *errorsClientStream) RecvMsg(m interface{}) error {
+	return errors.FromGRPC(s.ClientStream.RecvMsg(m))
+}
+
+func (s *errorsClientStream) CloseSend() error {
+	return errors.FromGRPC(s.ClientStream.CloseSend())
+}
@@ -0,0 +1,44 @@
+package grpcx
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/omecodes/common/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorsRoundTrip(t *testing.T) {
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("loading item: %w", errors.NotFound)
+	}
+
+	_, err := errorsUnaryInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+	if status.Code(err) != codes.NotFound {
+		t.Fatal("wrapped codes must be converted to statuses", err)
+	}
+
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return err
+	}
+
+	err = ErrorsUnaryClientInterceptor()(context.Background(), "/test", nil, nil, nil, invoker)
+	if !errors.IsNotFound(err) {
+		t.Fatal("statuses must be converted back to codes", err)
+	}
+}
+
+func TestUncodedErrorsKeepTheirMessage(t *testing.T) {
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("plain error")
+	}
+
+	_, err := errorsUnaryInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+	st, _ := status.FromError(err)
+	if st.Code() != codes.Unknown || st.Message() != "plain error" {
+		t.Fatal("unexpected status", st)
+	}
+}
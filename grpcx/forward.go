@@ -0,0 +1,142 @@
+package grpcx
+
+import (
+	"context"
+	"net/http"
+	"net/textproto"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/omecodes/common/errors"
+)
+
+const (
+	// MetadataHTTPCode is the header metadata key of the status the gateway responds with
+	MetadataHTTPCode = "x-http-code"
+
+	// MetadataLocation is the header metadata key of the gateway redirect location
+	MetadataLocation = "location"
+
+	gRPCContentType = "content-type"
+)
+
+// SetHTTPStatus makes the gateway respond with code instead of 200
+func SetHTTPStatus(ctx context.Context, code int) error {
+	if code < 100 || code > 999 {
+		return errors.Create(errors.BadInput, "invalid http status code %d", code)
+	}
+	return grpc.SetHeader(ctx, metadata.Pairs(MetadataHTTPCode, strconv.Itoa(code)))
+}
+
+// Redirect makes the gateway redirect the client to location. code is a 3xx status, 302 when 0
+func Redirect(ctx context.Context, location string, code int) error {
+	if code == 0 {
+		code = http.StatusFound
+	}
+	if code < 300 || code > 399 {
+		return errors.Create(errors.BadInput, "invalid redirect status code %d", code)
+	}
+	return grpc.SetHeader(ctx, metadata.Pairs(MetadataHTTPCode, strconv.Itoa(code), MetadataLocation, location))
+}
+
+// ForwardHTTPStatus is a gateway forward response option that applies the status and the redirect set with
+// SetHTTPStatus and Redirect. It writes the response header, so it must be the last option.
+// The gateway calls options for each message of server streams: the status is applied once, on the first call
+func ForwardHTTPStatus(ctx context.Context, w http.ResponseWriter, msg proto.Message) error {
+	md, ok := runtime.ServerMetadataFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	codes := md.HeaderMD.Get(MetadataHTTPCode)
+	if len(codes) == 0 {
+		return nil
+	}
+	locations := md.HeaderMD.Get(MetadataLocation)
+
+	// the metadata map is shared by the calls of a response, so the next ones find nothing to apply
+	delete(md.HeaderMD, MetadataHTTPCode)
+	delete(md.HeaderMD, MetadataLocation)
+
+	code, err := strconv.Atoi(codes[len(codes)-1])
+	if err != nil || code < 100 || code > 999 {
+		return errors.Create(errors.Internal, "invalid %s metadata %q", MetadataHTTPCode, codes[len(codes)-1])
+	}
+
+	if len(locations) > 0 && code >= 300 && code < 400 {
+		w.Header().Set("Location", locations[len(locations)-1])
+	}
+	w.WriteHeader(code)
+	return nil
+}
+
+// headerRules matches header names and metadata keys with path.Match patterns, case insensitively
+type headerRules []string
+
+func (rules headerRules) match(name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range rules {
+		if ok, _ := path.Match(strings.ToLower(pattern), name); ok {
+			return ok
+		}
+	}
+	return false
+}
+
+// incomingHeaderMatcher forwards the request headers matching rules as metadata of the same name, in lower case.
+// Other headers are forwarded as the gateway does by default
+func incomingHeaderMatcher(rules headerRules) runtime.HeaderMatcherFunc {
+	return func(key string) (string, bool) {
+		name, ok := runtime.DefaultHeaderMatcher(key)
+		if rules.match(key) {
+			name, ok = strings.ToLower(key), true
+		}
+		// the metadata set by the gateway itself cannot come from clients
+		if lower := strings.ToLower(name); lower == metadataGatewayToken || lower == metadataGatewayClient {
+			return "", false
+		}
+		return name, ok
+	}
+}
+
+// outgoingHeaderMatcher forwards the header metadata matching rules as headers of the same name. The keys read by
+// the forward response options and the gRPC content type are not forwarded. Other keys are forwarded with the
+// Grpc-Metadata- prefix as the gateway does by default
+func outgoingHeaderMatcher(rules headerRules) runtime.HeaderMatcherFunc {
+	return func(key string) (string, bool) {
+		switch strings.ToLower(key) {
+		case gRPCSetCookie, MetadataHTTPCode, MetadataLocation, gRPCContentType:
+			return "", false
+		}
+		if rules.match(key) {
+			return textproto.CanonicalMIMEHeaderKey(key), true
+		}
+		return runtime.MetadataHeaderPrefix + key, true
+	}
+}
+
+// forwardResponseMetadata adds the header metadata matching rules to the header of w. It is used for errors,
+// which the gateway writes without forwarding metadata
+func forwardResponseMetadata(ctx context.Context, w http.ResponseWriter, rules headerRules) {
+	md, ok := runtime.ServerMetadataFromContext(ctx)
+	if !ok {
+		return
+	}
+	matcher := outgoingHeaderMatcher(rules)
+	for key, values := range md.HeaderMD {
+		if !rules.match(key) {
+			continue
+		}
+		if name, ok := matcher(key); ok {
+			for _, value := range values {
+				w.Header().Add(name, value)
+			}
+		}
+	}
+}
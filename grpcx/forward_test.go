@@ -0,0 +1,102 @@
+package grpcx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestHeaderMatchers(t *testing.T) {
+	incoming := incomingHeaderMatcher(headerRules{"X-Tenant-ID", "Accept-Language"})
+	if key, ok := incoming("X-Tenant-Id"); !ok || key != "x-tenant-id" {
+		t.Fatal("unexpected key", key, ok)
+	}
+	if key, ok := incoming("Accept-Language"); !ok || key != "accept-language" {
+		t.Fatal("unexpected key", key, ok)
+	}
+	if key, ok := incoming("Authorization"); !ok || key != "grpcgateway-Authorization" {
+		t.Fatal("other headers must be forwarded by default", key, ok)
+	}
+	if _, ok := incoming("X-Other"); ok {
+		t.Fatal("unknown headers must not be forwarded")
+	}
+
+	outgoing := outgoingHeaderMatcher(headerRules{"x-ratelimit-*"})
+	if name, ok := outgoing("x-ratelimit-remaining"); !ok || name != "X-Ratelimit-Remaining" {
+		t.Fatal("unexpected header", name, ok)
+	}
+	if name, ok := outgoing("x-trace"); !ok || name != "Grpc-Metadata-x-trace" {
+		t.Fatal("other metadata must be forwarded by default", name, ok)
+	}
+	for _, key := range []string{"content-type", "set-cookie", MetadataHTTPCode, MetadataLocation} {
+		if _, ok := outgoing(key); ok {
+			t.Fatal("metadata must not be forwarded", key)
+		}
+	}
+}
+
+func TestForwardHTTPStatus(t *testing.T) {
+	stream := new(transportStream)
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+	if err := Redirect(ctx, "/login", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := Redirect(ctx, "/login", http.StatusOK); err == nil {
+		t.Fatal("non 3xx redirects must be rejected")
+	}
+
+	w := httptest.NewRecorder()
+	ctx = runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{HeaderMD: stream.header})
+	if err := ForwardHTTPStatus(ctx, w, nil); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/login" {
+		t.Fatal("unexpected redirect", w.Code, w.Header())
+	}
+
+	stream = new(transportStream)
+	ctx = grpc.NewContextWithServerTransportStream(context.Background(), stream)
+	if err := SetHTTPStatus(ctx, http.StatusCreated); err != nil {
+		t.Fatal(err)
+	}
+	hw := &headerCounter{ResponseWriter: httptest.NewRecorder()}
+	ctx = runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{HeaderMD: stream.header})
+
+	// called once before the messages of a stream, then for each of them
+	for i := 0; i < 3; i++ {
+		if err := ForwardHTTPStatus(ctx, hw, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if hw.status != http.StatusCreated || hw.calls != 1 {
+		t.Fatal("status must be written once", hw.status, hw.calls)
+	}
+}
+
+type headerCounter struct {
+	http.ResponseWriter
+	status int
+	calls  int
+}
+
+func (w *headerCounter) WriteHeader(status int) {
+	w.status = status
+	w.calls++
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func TestForwardResponseMetadata(t *testing.T) {
+	md := metadata.Pairs("x-ratelimit-limit", "10", "x-trace", "abc", "content-type", "application/grpc")
+	ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{HeaderMD: md})
+
+	w := httptest.NewRecorder()
+	forwardResponseMetadata(ctx, w, headerRules{"x-ratelimit-*"})
+	if w.Header().Get("X-Ratelimit-Limit") != "10" || len(w.Header()) != 1 {
+		t.Fatal("only matching metadata must be forwarded on errors", w.Header())
+	}
+}
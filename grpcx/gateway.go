@@ -0,0 +1,111 @@
+package grpcx
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net"
+	"net/http"
+
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/omecodes/common/utils/jcon"
+)
+
+const (
+	// metadataGatewayToken is the metadata key of the secret the gateway of a Server sends with its calls
+	metadataGatewayToken = "x-gateway-token"
+
+	// metadataGatewayClient is the metadata key of the address of the HTTP client a gateway call is made for
+	metadataGatewayClient = "x-gateway-client"
+)
+
+const ctxGatewayCall = jcon.String("gateway_call")
+
+// newGatewayToken generates the secret the gateway of a server is recognized by
+func newGatewayToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// gatewayMetadata is the gateway annotator that marks its calls with token and the address of the HTTP client
+func gatewayMetadata(token string) func(ctx context.Context, r *http.Request) metadata.MD {
+	return func(ctx context.Context, r *http.Request) metadata.MD {
+		md := metadata.Pairs(metadataGatewayToken, token)
+		// requests of unix socket listeners have no client address
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			md.Set(metadataGatewayClient, host)
+		}
+		return md
+	}
+}
+
+// gatewayCall is stored in the context of the calls of the gateway
+type gatewayCall struct {
+	client string
+}
+
+// fromGateway tells if the call of ctx was made by the gateway of the server. The connection of the gateway
+// presents the certificate of the server, which is not the principal of the HTTP clients it serves
+func fromGateway(ctx context.Context) bool {
+	_, ok := ctx.Value(ctxGatewayCall).(*gatewayCall)
+	return ok
+}
+
+// gatewayClient returns the address of the HTTP client the gateway call of ctx is made for
+func gatewayClient(ctx context.Context) string {
+	if call, ok := ctx.Value(ctxGatewayCall).(*gatewayCall); ok {
+		return call.client
+	}
+	return ""
+}
+
+// gatewayContext marks ctx as a gateway call when its metadata holds token. The gateway metadata is removed
+// in any case, so that handlers never see the token and other calls cannot choose their client address
+func gatewayContext(ctx context.Context, token string) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md.Get(metadataGatewayToken)) == 0 && len(md.Get(metadataGatewayClient)) == 0 {
+		return ctx
+	}
+
+	gateway := false
+	for _, value := range md.Get(metadataGatewayToken) {
+		if subtle.ConstantTimeCompare([]byte(value), []byte(token)) == 1 {
+			gateway = true
+		}
+	}
+
+	call := &gatewayCall{}
+	if clients := md.Get(metadataGatewayClient); len(clients) > 0 {
+		call.client = clients[len(clients)-1]
+	}
+
+	md = md.Copy()
+	delete(md, metadataGatewayToken)
+	delete(md, metadataGatewayClient)
+	ctx = metadata.NewIncomingContext(ctx, md)
+	if gateway {
+		ctx = context.WithValue(ctx, ctxGatewayCall, call)
+	}
+	return ctx
+}
+
+func gatewayUnaryInterceptor(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(gatewayContext(ctx, token), req)
+	}
+}
+
+func gatewayStreamInterceptor(token string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		wrapped := grpc_middleware.WrapServerStream(ss)
+		wrapped.WrappedContext = gatewayContext(ss.Context(), token)
+		return handler(srv, wrapped)
+	}
+}
@@ -0,0 +1,58 @@
+package grpcx
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/omecodes/common/netx"
+)
+
+func TestGatewayCallPrincipal(t *testing.T) {
+	token := newGatewayToken()
+
+	// the gateway connection presents the certificate of the server
+	server := &x509.Certificate{Subject: pkix.Name{CommonName: "server"}}
+	call := func(md metadata.MD) context.Context {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{
+			Addr:     &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 5000},
+			AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{server}}}},
+		})
+		ctx = metadata.NewIncomingContext(ctx, md)
+		return principalContext(gatewayContext(ctx, token))
+	}
+
+	ctx := call(metadata.Pairs(metadataGatewayToken, token))
+	if p := netx.PrincipalFromContext(ctx); p != nil {
+		t.Fatal("gateway requests without client certificate must have no principal", p.CommonName)
+	}
+	if key := KeyByPrincipal(ctx); key != "" {
+		t.Fatal("gateway requests must not be counted by the server identity", key)
+	}
+	if md, _ := metadata.FromIncomingContext(ctx); len(md.Get(metadataGatewayToken)) != 0 {
+		t.Fatal("the gateway token must be removed from the metadata")
+	}
+
+	for _, md := range []metadata.MD{nil, metadata.Pairs(metadataGatewayToken, "guess")} {
+		if p := netx.PrincipalFromContext(call(md)); p == nil || p.CommonName != "server" {
+			t.Fatal("other calls must have the principal of their certificate", md)
+		}
+	}
+
+	if _, ok := incomingHeaderMatcher(headerRules{"x-*"})("Grpc-Metadata-X-Gateway-Token"); ok {
+		t.Fatal("clients must not send the gateway token")
+	}
+	if _, ok := incomingHeaderMatcher(headerRules{"x-*"})("X-Gateway-Token"); ok {
+		t.Fatal("clients must not send the gateway token")
+	}
+	if _, ok := incomingHeaderMatcher(nil)("Grpc-Metadata-X-Gateway-Client"); ok {
+		t.Fatal("clients must not send their address")
+	}
+}
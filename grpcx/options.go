@@ -2,20 +2,35 @@ package grpcx
 
 import (
 	"context"
+	"github.com/omecodes/common/httpx"
 	"github.com/omecodes/common/netx"
+	"github.com/omecodes/common/session"
+	"github.com/omecodes/common/utils/lang"
+	"github.com/omecodes/common/utils/ratelimit"
 	"google.golang.org/grpc"
 	"net/http"
 )
 
 type options struct {
 	listenOptions   []netx.ListenOption
+	grpcListen      []netx.ListenOption
+	httpListen      []netx.ListenOption
 	gRPCPort        int
 	httpPort        int
-	grpcSession     bool
+	grpcAddress     string
+	httpAddress     string
 	grpcOpts        []grpc.ServerOption
 	endpointMappers map[string]endpointMapping
 	middlewareList  []func(handler http.Handler) http.Handler
 	authFunc        func(ctx context.Context) (context.Context, error)
+	i18n            *lang.I18n
+	rateLimiter     *rateLimiter
+	panicReporter   httpx.PanicReporter
+	sessions        *session.Manager
+	requestHeaders  headerRules
+	responseHeaders headerRules
+	streamRoutes    []StreamRoute
+	streamOptions   []StreamOption
 }
 
 type Option func(opts *options)
@@ -26,6 +41,36 @@ func ListenOptions(no ...netx.ListenOption) Option {
 	}
 }
 
+// GrpcListenOptions are only applied to the gRPC listener
+func GrpcListenOptions(no ...netx.ListenOption) Option {
+	return func(opts *options) {
+		opts.grpcListen = append(opts.grpcListen, no...)
+	}
+}
+
+// HttpListenOptions are only applied to the HTTP gateway listener. The gateway dials the gRPC
+// listener directly, so options like netx.ProxyProtocol usually belong here
+func HttpListenOptions(no ...netx.ListenOption) Option {
+	return func(opts *options) {
+		opts.httpListen = append(opts.httpListen, no...)
+	}
+}
+
+// GrpcAddress sets the full gRPC listen address, like "unix:///run/app/grpc.sock" or "systemd://grpc".
+// It takes precedence over the server host and Grpc port
+func GrpcAddress(address string) Option {
+	return func(opts *options) {
+		opts.grpcAddress = address
+	}
+}
+
+// HttpAddress sets the full HTTP gateway listen address. It takes precedence over the server host and Http port
+func HttpAddress(address string) Option {
+	return func(opts *options) {
+		opts.httpAddress = address
+	}
+}
+
 func Grpc(port int) Option {
 	return func(opts *options) {
 		opts.gRPCPort = port
@@ -62,9 +107,11 @@ func Authentication(authFunc func(ctx context.Context) (context.Context, error))
 	}
 }
 
+// GRPCSession is kept for compatibility
+//
+// Deprecated: cookies are always forwarded by the gateway. See SetCookie and Sessions
 func GRPCSession(enable bool) Option {
 	return func(opts *options) {
-		opts.grpcSession = true
 	}
 }
 
@@ -73,3 +120,58 @@ func GrpcOptions(gopts ...grpc.ServerOption) Option {
 		opts.grpcOpts = append(opts.grpcOpts, gopts...)
 	}
 }
+
+// I18n translates the messages of errors written by the gateway. See httpx.WriteLocalizedError
+func I18n(manager *lang.I18n) Option {
+	return func(opts *options) {
+		opts.i18n = manager
+	}
+}
+
+// RateLimit installs the rate limit interceptors on the gRPC server. They run after authentication,
+// so calls can be counted with KeyByPrincipal
+func RateLimit(limit ratelimit.Limit, opts ...RateLimitOption) Option {
+	return func(o *options) {
+		o.rateLimiter = newRateLimiter(limit, opts...)
+	}
+}
+
+// PanicReporter sets the reporter of the panics recovered in gRPC handlers and in the gateway
+func PanicReporter(reporter httpx.PanicReporter) Option {
+	return func(opts *options) {
+		opts.panicReporter = reporter
+	}
+}
+
+// Sessions installs the session interceptors, which load the session of calls from manager. Handlers get it with
+// SessionFromContext
+func Sessions(manager *session.Manager) Option {
+	return func(opts *options) {
+		opts.sessions = manager
+	}
+}
+
+// ForwardRequestHeaders makes the gateway forward the request headers matching names to gRPC metadata of the same
+// name in lower case. Names are path.Match patterns like "X-Tenant-*"
+func ForwardRequestHeaders(names ...string) Option {
+	return func(opts *options) {
+		opts.requestHeaders = append(opts.requestHeaders, names...)
+	}
+}
+
+// ForwardResponseMetadata makes the gateway send the header metadata matching patterns as response headers of the
+// same name, on success and on errors. Patterns are path.Match patterns like "x-ratelimit-*". Other metadata is sent
+// with the Grpc-Metadata- prefix on success
+func ForwardResponseMetadata(patterns ...string) Option {
+	return func(opts *options) {
+		opts.responseHeaders = append(opts.responseHeaders, patterns...)
+	}
+}
+
+// Streaming serves the gateway streaming routes to browsers with WebSocket or Server-Sent Events. See StreamRoute
+func Streaming(routes []StreamRoute, opts ...StreamOption) Option {
+	return func(o *options) {
+		o.streamRoutes = append(o.streamRoutes, routes...)
+		o.streamOptions = append(o.streamOptions, opts...)
+	}
+}
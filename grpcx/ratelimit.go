@@ -0,0 +1,181 @@
+package grpcx
+
+import (
+	"context"
+	"math"
+	"net"
+	"strings"
+
+	"github.com/omecodes/common/errors"
+	"github.com/omecodes/common/httpx"
+	"github.com/omecodes/common/jwt"
+	"github.com/omecodes/common/netx"
+	"github.com/omecodes/common/utils/log"
+	"github.com/omecodes/common/utils/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// CallKey returns the key calls are counted by. An empty key lets the next CallKey decide
+type CallKey func(ctx context.Context) string
+
+// KeyByPeer counts calls by peer IP address. Calls of the gateway of a Server, recognized by the secret it sends,
+// are counted by the address of the HTTP client they are made for
+func KeyByPeer(ctx context.Context) string {
+	if client := gatewayClient(ctx); client != "" {
+		return "ip:" + client
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+
+	return "ip:" + host
+}
+
+// KeyByPrincipal counts calls by the subject of the verified JWT or the common name of the client certificate
+func KeyByPrincipal(ctx context.Context) string {
+	if t := jwt.TokenFromContext(ctx); t != nil && t.Claims != nil && t.Claims.Subject != "" {
+		return "sub:" + t.Claims.Subject
+	}
+
+	p := netx.PrincipalFromContext(ctx)
+	if p == nil {
+		p = PrincipalFromPeer(ctx)
+	}
+	if p != nil && p.CommonName != "" {
+		return "cn:" + p.CommonName
+	}
+	return ""
+}
+
+// KeyByAPIKey counts calls by the access key stored in the context by the authentication func once its secret
+// is verified. See httpx.KeyByAPIKey
+func KeyByAPIKey(ctx context.Context) string {
+	if key := httpx.AccessKeyFromContext(ctx); key != "" {
+		return "key:" + key
+	}
+	return ""
+}
+
+type rateLimitOptions struct {
+	store ratelimit.Store
+	rules *ratelimit.Rules
+	keys  []CallKey
+}
+
+// RateLimitOption configures the rate limit interceptors
+type RateLimitOption func(opts *rateLimitOptions)
+
+// RateLimitStore sets the store of counters. Default is an in-memory store
+func RateLimitStore(store ratelimit.Store) RateLimitOption {
+	return func(opts *rateLimitOptions) {
+		opts.store = store
+	}
+}
+
+// RateLimitMethod sets the limit of the methods matching pattern. Patterns are full method names like
+// "/pkg.Service/Method" or prefixes like "/pkg.Service/*". A zero limit disables limiting for them
+func RateLimitMethod(pattern string, limit ratelimit.Limit) RateLimitOption {
+	return func(opts *rateLimitOptions) {
+		opts.rules.Set(pattern, limit)
+	}
+}
+
+// RateLimitKey sets the functions that compute the keys calls are counted by. They are tried in order
+// and KeyByPeer is used when they all return empty keys
+func RateLimitKey(keys ...CallKey) RateLimitOption {
+	return func(opts *rateLimitOptions) {
+		opts.keys = keys
+	}
+}
+
+type rateLimiter struct {
+	*rateLimitOptions
+}
+
+func newRateLimiter(limit ratelimit.Limit, opts ...RateLimitOption) *rateLimiter {
+	options := &rateLimitOptions{
+		rules: ratelimit.NewRules(limit),
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.store == nil {
+		options.store = ratelimit.NewMemoryStore()
+	}
+	return &rateLimiter{rateLimitOptions: options}
+}
+
+// take counts a call of method. It returns the metadata to send in headers and an error when the call is rejected
+func (l *rateLimiter) take(ctx context.Context, method string) (metadata.MD, error) {
+	pattern, limit := l.rules.Match(method)
+	if limit.IsZero() {
+		return nil, nil
+	}
+
+	key := ""
+	for _, callKey := range l.keys {
+		if key = callKey(ctx); key != "" {
+			break
+		}
+	}
+	if key == "" {
+		key = KeyByPeer(ctx)
+	}
+
+	result, err := l.store.Take(ctx, pattern+"|"+key, limit)
+	if err != nil {
+		log.Error("rate limit store failure", log.Err(err), log.Field("key", key))
+		return nil, nil
+	}
+
+	md := metadata.MD{}
+	for name, value := range httpx.RateLimitHeaders(result) {
+		md.Set(strings.ToLower(name), value)
+	}
+	if !result.Allowed {
+		return md, errors.Create(errors.TooManyRequests, "rate limit exceeded").
+			WithDetail("retry_after", int(math.Ceil(result.RetryAfter.Seconds())))
+	}
+	return md, nil
+}
+
+func (l *rateLimiter) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	md, err := l.take(ctx, info.FullMethod)
+	if md != nil {
+		_ = grpc.SetHeader(ctx, md)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (l *rateLimiter) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	md, err := l.take(ss.Context(), info.FullMethod)
+	if md != nil {
+		_ = ss.SetHeader(md)
+	}
+	if err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// RateLimitUnaryInterceptor rejects calls over limit with a ResourceExhausted status. Responses carry
+// ratelimit-limit, ratelimit-remaining, ratelimit-reset and retry-after header metadata
+func RateLimitUnaryInterceptor(limit ratelimit.Limit, opts ...RateLimitOption) grpc.UnaryServerInterceptor {
+	return newRateLimiter(limit, opts...).unaryInterceptor
+}
+
+// RateLimitStreamInterceptor is the stream version of RateLimitUnaryInterceptor
+func RateLimitStreamInterceptor(limit ratelimit.Limit, opts ...RateLimitOption) grpc.StreamServerInterceptor {
+	return newRateLimiter(limit, opts...).streamInterceptor
+}
@@ -0,0 +1,43 @@
+package grpcx
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/omecodes/common/httpx"
+)
+
+func TestRateLimitKeys(t *testing.T) {
+	call := func(addr string, md metadata.MD) context.Context {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(addr), Port: 5000}})
+		return metadata.NewIncomingContext(ctx, md)
+	}
+
+	token := newGatewayToken()
+	gateway := metadata.Pairs(metadataGatewayToken, token, metadataGatewayClient, "198.51.100.4")
+	if key := KeyByPeer(gatewayContext(call("127.0.0.1", gateway), token)); key != "ip:198.51.100.4" {
+		t.Fatal("gateway calls must be counted by the client address", key)
+	}
+	unix := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.UnixAddr{Name: "/run/app.sock", Net: "unix"}})
+	if key := KeyByPeer(gatewayContext(metadata.NewIncomingContext(unix, gateway), token)); key != "ip:198.51.100.4" {
+		t.Fatal("gateway calls over unix sockets must be counted by the client address", key)
+	}
+
+	// local processes cannot choose their bucket
+	spoofed := metadata.Pairs("x-forwarded-for", "198.51.100.4", metadataGatewayToken, "guess", metadataGatewayClient, "198.51.100.4")
+	if key := KeyByPeer(gatewayContext(call("127.0.0.1", spoofed), token)); key != "ip:127.0.0.1" {
+		t.Fatal("client addresses of other peers must be ignored", key)
+	}
+
+	ctx := call("192.0.2.1", metadata.Pairs("authorization", "Access app:secret"))
+	if key := KeyByAPIKey(ctx); key != "" {
+		t.Fatal("unverified access keys must not be used", key)
+	}
+	if key := KeyByAPIKey(httpx.ContextWithAccessKey(ctx, "app")); key != "key:app" {
+		t.Fatal("verified access keys must be used", key)
+	}
+}
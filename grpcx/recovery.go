@@ -0,0 +1,54 @@
+package grpcx
+
+import (
+	"context"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	grpc_recovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
+	"github.com/omecodes/common/errors"
+	"github.com/omecodes/common/httpx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDFromContext returns the request ID stored in ctx or the valid one sent in the x-request-id metadata
+func RequestIDFromContext(ctx context.Context) string {
+	if id := httpx.RequestIDFromContext(ctx); id != "" {
+		return id
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(strings.ToLower(httpx.RequestIDHeader)); len(ids) > 0 && httpx.ValidRequestID(ids[0]) {
+			return ids[0]
+		}
+	}
+	return ""
+}
+
+// requestIDMetadata forwards the request ID of gateway requests to the gRPC server
+func requestIDMetadata(ctx context.Context, r *http.Request) metadata.MD {
+	id := httpx.RequestIDFromContext(ctx)
+	if id == "" {
+		return nil
+	}
+	return metadata.Pairs(strings.ToLower(httpx.RequestIDHeader), id)
+}
+
+// RecoveryHandler returns a grpc_recovery handler that logs panics with their stack and request ID,
+// passes them to reporter if it is not nil, and returns an Internal status
+func RecoveryHandler(reporter httpx.PanicReporter) grpc_recovery.RecoveryHandlerFuncContext {
+	return func(ctx context.Context, p interface{}) error {
+		requestID := RequestIDFromContext(ctx)
+		ctx = httpx.ContextWithRequestID(ctx, requestID)
+
+		call, _ := grpc.Method(ctx)
+		httpx.ReportPanic(ctx, call, p, debug.Stack(), reporter)
+
+		failure := errors.Create(errors.Internal, "internal server error")
+		if requestID != "" {
+			failure = failure.WithDetail("request_id", requestID)
+		}
+		return failure.GRPCStatus().Err()
+	}
+}
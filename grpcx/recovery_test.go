@@ -0,0 +1,24 @@
+package grpcx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/omecodes/common/httpx"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestRecoveryHandler(t *testing.T) {
+	var reportedID string
+	reporter := func(ctx context.Context, recovered interface{}, stack []byte) {
+		reportedID = httpx.RequestIDFromContext(ctx)
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "req-1"))
+	err := RecoveryHandler(reporter)(ctx, "boom")
+	if status.Code(err) != codes.Internal || reportedID != "req-1" {
+		t.Fatal("panics must be reported and converted to Internal statuses", err, reportedID)
+	}
+}
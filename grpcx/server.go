@@ -13,11 +13,11 @@ import (
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
 	"github.com/grpc-ecosystem/grpc-gateway/runtime"
 	"github.com/omecodes/common/errors"
+	"github.com/omecodes/common/httpx"
 	"github.com/omecodes/common/netx"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/status"
 	"net"
 	"net/http"
 	"strings"
@@ -37,16 +37,30 @@ type Server struct {
 	httpAddress  string
 	httpListener net.Listener
 	stopped      bool
+
+	// gatewayToken marks the calls of the gateway
+	gatewayToken string
 }
 
 func (s *Server) listenHttp() error {
 	if s.options.endpointMappers != nil {
+		lopts := joinListenOptions(s.options.listenOptions, s.options.httpListen)
+
+		if s.options.httpAddress != "" {
+			l, err := netx.Listen(s.options.httpAddress, lopts...)
+			if err != nil {
+				return err
+			}
+			s.httpListener = l
+			s.httpAddress = netx.DialAddress(l)
+			return nil
+		}
 
 		address := fmt.Sprintf("%s:", s.host)
 		if s.options.httpPort > 0 {
 			address = fmt.Sprintf("%s%d", address, s.options.httpPort)
 		}
-		l, err := netx.Listen(address, s.options.listenOptions...)
+		l, err := netx.Listen(address, lopts...)
 		if err != nil {
 			return err
 		}
@@ -55,7 +69,7 @@ func (s *Server) listenHttp() error {
 		if s.options.httpPort > 0 {
 			s.httpAddress = address
 		} else {
-			s.httpAddress = address + strings.Split(l.Addr().String(), ":")[1]
+			s.httpAddress = address + listenerPort(l)
 		}
 
 		//log.Info("[gRPC-http] starting HTTP server", log.Field("at", s.httpAddress))
@@ -64,12 +78,23 @@ func (s *Server) listenHttp() error {
 }
 
 func (s *Server) listenGRPC() error {
+	lopts := joinListenOptions(s.options.listenOptions, s.options.grpcListen)
+
+	if s.options.grpcAddress != "" {
+		l, err := netx.Listen(s.options.grpcAddress, lopts...)
+		if err != nil {
+			return err
+		}
+		s.grpcListener = l
+		s.grpcAddress = netx.DialAddress(l)
+		return nil
+	}
 
 	address := fmt.Sprintf("%s:", s.host)
 	if s.options.gRPCPort > 0 {
 		address = fmt.Sprintf("%s%d", address, s.options.gRPCPort)
 	}
-	l, err := netx.Listen(address, s.options.listenOptions...)
+	l, err := netx.Listen(address, lopts...)
 	if err != nil {
 		return err
 	}
@@ -78,13 +103,36 @@ func (s *Server) listenGRPC() error {
 	if s.options.gRPCPort > 0 {
 		s.grpcAddress = address
 	} else {
-		s.grpcAddress = address + strings.Split(l.Addr().String(), ":")[1]
+		s.grpcAddress = address + listenerPort(l)
 	}
 	//log.Info("[gRPC-http] starting gRPC server", log.Field("at", s.grpcAddress))
 
 	return nil
 }
 
+func joinListenOptions(common, specific []netx.ListenOption) []netx.ListenOption {
+	var all []netx.ListenOption
+	all = append(all, common...)
+	return append(all, specific...)
+}
+
+// grpcListenOptions returns the options the gRPC listener is created with
+func (s *Server) grpcListenOptions() netx.ListenOptions {
+	var lopts netx.ListenOptions
+	for _, o := range joinListenOptions(s.options.listenOptions, s.options.grpcListen) {
+		o(&lopts)
+	}
+	return lopts
+}
+
+func listenerPort(l net.Listener) string {
+	_, port, err := net.SplitHostPort(l.Addr().String())
+	if err != nil {
+		return strings.Split(l.Addr().String(), ":")[1]
+	}
+	return port
+}
+
 func (s *Server) init() error {
 	if s.initialized {
 		return nil
@@ -129,32 +177,26 @@ func (s *Server) startHTTP() {
 		}
 
 		var serverOpts []runtime.ServeMuxOption
-		if s.options.grpcSession {
-			serverOpts = append(serverOpts, runtime.WithForwardResponseOption(SetCookieFromGRPCMetadata))
-		}
+		serverOpts = append(serverOpts, runtime.WithIncomingHeaderMatcher(incomingHeaderMatcher(s.options.requestHeaders)))
+		serverOpts = append(serverOpts, runtime.WithOutgoingHeaderMatcher(outgoingHeaderMatcher(s.options.responseHeaders)))
+		serverOpts = append(serverOpts, runtime.WithForwardResponseOption(SetCookieFromGRPCMetadata))
+		// writes the header, so it comes last
+		serverOpts = append(serverOpts, runtime.WithForwardResponseOption(ForwardHTTPStatus))
 		serverOpts = append(serverOpts, runtime.WithProtoErrorHandler(s.HandlerError))
+		serverOpts = append(serverOpts, runtime.WithMetadata(requestIDMetadata))
+		serverOpts = append(serverOpts, runtime.WithMetadata(gatewayMetadata(s.gatewayToken)))
 		s.mux = runtime.NewServeMux(serverOpts...)
 
 		var opts []grpc.DialOption
 
-		var lopts netx.ListenOptions
-		for _, o := range s.options.listenOptions {
-			o(&lopts)
-		}
-
+		lopts := s.grpcListenOptions()
 		if lopts.Secure {
-			if lopts.TLS != nil {
-				cert := lopts.TLS.Certificates[0]
-				c := credentials.NewServerTLSFromCert(&cert)
-				opts = append(opts, grpc.WithTransportCredentials(c))
-			} else {
-				c, err := credentials.NewClientTLSFromFile(lopts.CertFilename, "")
-				if err != nil {
-					s.handleError(err)
-					return
-				}
-				opts = append(opts, grpc.WithTransportCredentials(c))
+			tc, err := lopts.ClientTLS()
+			if err != nil {
+				s.handleError(err)
+				return
 			}
+			opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tc)))
 		} else {
 			opts = append(opts, grpc.WithInsecure())
 		}
@@ -167,15 +209,15 @@ func (s *Server) startHTTP() {
 			}
 		}
 
-		var handler http.Handler
-		if len(s.options.middlewareList) > 0 {
-			handler = s.mux
-			for _, middleware := range s.options.middlewareList {
-				handler = middleware(handler)
-			}
-		} else {
-			handler = s.mux
+		var handler http.Handler = s.mux
+		if len(s.options.streamRoutes) > 0 {
+			handler = newStreamHandler(handler, s.options.streamRoutes, s.options.sessions, s.options.streamOptions...)
 		}
+		handler = httpx.Recovery(httpx.RecoveryReporter(s.options.panicReporter))(handler)
+		for _, middleware := range s.options.middlewareList {
+			handler = middleware(handler)
+		}
+		handler = httpx.RequestID(handler)
 
 		err := http.Serve(s.httpListener, handler)
 		if err != nil {
@@ -211,22 +253,45 @@ func (s *Server) GRPCServer() *grpc.Server {
 		}
 	}
 	if s.grpcServer == nil {
-		s.grpcServer = grpc.NewServer(
-			grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(
-				grpc_ctxtags.StreamServerInterceptor(),
-				grpc_opentracing.StreamServerInterceptor(),
-				grpc_prometheus.StreamServerInterceptor,
-				grpc_auth.StreamServerInterceptor(s.options.authFunc),
-				grpc_recovery.StreamServerInterceptor(),
-			)),
-			grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(
-				grpc_ctxtags.UnaryServerInterceptor(),
-				grpc_opentracing.UnaryServerInterceptor(),
-				grpc_prometheus.UnaryServerInterceptor,
-				grpc_auth.UnaryServerInterceptor(s.options.authFunc),
-				grpc_recovery.UnaryServerInterceptor(),
-			)),
-		)
+		streamInterceptors := []grpc.StreamServerInterceptor{
+			grpc_ctxtags.StreamServerInterceptor(),
+			grpc_opentracing.StreamServerInterceptor(),
+			grpc_prometheus.StreamServerInterceptor,
+			errorsStreamInterceptor,
+			gatewayStreamInterceptor(s.gatewayToken),
+			principalStreamInterceptor,
+			grpc_auth.StreamServerInterceptor(s.options.authFunc),
+		}
+		unaryInterceptors := []grpc.UnaryServerInterceptor{
+			grpc_ctxtags.UnaryServerInterceptor(),
+			grpc_opentracing.UnaryServerInterceptor(),
+			grpc_prometheus.UnaryServerInterceptor,
+			errorsUnaryInterceptor,
+			gatewayUnaryInterceptor(s.gatewayToken),
+			principalUnaryInterceptor,
+			grpc_auth.UnaryServerInterceptor(s.options.authFunc),
+		}
+		if s.options.rateLimiter != nil {
+			streamInterceptors = append(streamInterceptors, s.options.rateLimiter.streamInterceptor)
+			unaryInterceptors = append(unaryInterceptors, s.options.rateLimiter.unaryInterceptor)
+		}
+		if s.options.sessions != nil {
+			streamInterceptors = append(streamInterceptors, SessionStreamInterceptor(s.options.sessions))
+			unaryInterceptors = append(unaryInterceptors, SessionUnaryInterceptor(s.options.sessions))
+		}
+		recovery := grpc_recovery.WithRecoveryHandlerContext(RecoveryHandler(s.options.panicReporter))
+		streamInterceptors = append(streamInterceptors, grpc_recovery.StreamServerInterceptor(recovery))
+		unaryInterceptors = append(unaryInterceptors, grpc_recovery.UnaryServerInterceptor(recovery))
+
+		serverOpts := []grpc.ServerOption{
+			grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(streamInterceptors...)),
+			grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(unaryInterceptors...)),
+		}
+		// the connections of plain listeners must not be reported as TLS
+		if s.grpcListenOptions().Secure {
+			serverOpts = append(serverOpts, grpc.Creds(listenerTLS{}))
+		}
+		s.grpcServer = grpc.NewServer(append(serverOpts, s.options.grpcOpts...)...)
 	}
 	return s.grpcServer
 }
@@ -265,18 +330,21 @@ func (s *Server) Errors() chan error {
 
 func (s *Server) HandlerError(ctx context.Context, mux *runtime.ServeMux, m runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
 	//log.Info("caught error", log.Field("err", err))
-	st, ok := status.FromError(err)
-	if !ok {
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+	if s.options.i18n != nil {
+		r = r.WithContext(httpx.ContextWithI18n(r.Context(), s.options.i18n))
 	}
-	w.WriteHeader(errors.HttpStatus(errors.New(st.Message())))
+	// cookies and forwarded metadata set before the failure, like a deleted session cookie or rate limit
+	// headers, are kept
+	forwardResponseMetadata(ctx, w, s.options.responseHeaders)
+	_ = SetCookieFromGRPCMetadata(ctx, w, nil)
+	httpx.WriteLocalizedError(w, r, errors.FromGRPC(err))
 }
 
 func New(host string, opts ...Option) *Server {
 	s := &Server{
 		host:         host,
 		errorChannel: make(chan error, 2),
+		gatewayToken: newGatewayToken(),
 	}
 
 	for _, o := range opts {
@@ -3,17 +3,16 @@ package grpcx
 import (
 	"context"
 	"github.com/golang/protobuf/proto"
-	"github.com/gorilla/securecookie"
-	"github.com/gorilla/sessions"
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	"github.com/grpc-ecosystem/grpc-gateway/runtime"
-	"github.com/omecodes/common/errors"
+	"github.com/omecodes/common/httpx"
+	"github.com/omecodes/common/session"
+	"github.com/omecodes/common/utils/log"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 	"net/http"
 )
 
-type ctxSession struct{}
-
 const (
 	gRPCSetCookie         = "set-cookie"
 	gRPCHeaderSetCookie   = "Grpc-Metadata-Set-Cookie"
@@ -24,75 +23,94 @@ const (
 	httpHeaderCookie    = "Cookie"
 )
 
-type Session struct {
-	Values map[interface{}]interface{}
-	Name   string
-	Store  *sessions.CookieStore
+// Session is the session API shared with httpx. See session.Session
+type Session = session.Session
+
+// SessionFromContext returns the session loaded by the session interceptors. It is nil when the server has no
+// Sessions option
+func SessionFromContext(ctx context.Context) Session {
+	return httpx.SessionFromContext(ctx)
 }
 
-func (s *Session) Save(ctx context.Context) error {
-	encoded, err := securecookie.EncodeMulti(s.Name, s.Values, s.Store.Codecs...)
-	if err != nil {
-		return err
+// openSession opens the session referenced by the cookie forwarded by the gateway. Cookies are sent in the
+// response header, which must not be sent yet
+func openSession(ctx context.Context, manager *session.Manager) (Session, error) {
+	var token string
+	if c, err := Get(ctx, manager.CookieName()); err == nil {
+		token = c.Value
 	}
-	value := sessions.NewCookie(s.Name, encoded, s.Store.Options).String()
-	return grpc.SendHeader(ctx, metadata.Pairs(gRPCSetCookie, value))
+	return manager.Open(ctx, token, func(cookie *http.Cookie) error {
+		return SetCookie(ctx, cookie)
+	})
 }
 
-func SessionFromContext(ctx context.Context, store *sessions.CookieStore, name string) (*Session, error) {
-	o := ctx.Value(ctxSession{})
-	if o != nil {
-		s, ok := o.(*Session)
-		if !ok {
-			return nil, errors.New("invalid type for context 'session' key")
-		}
-		return s, nil
+func saveSession(s Session) {
+	if err := s.Save(); err != nil {
+		log.Error("could not save session", log.Err(err))
 	}
+}
 
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		return nil, errors.NotFound
+// SessionUnaryInterceptor loads the session of calls from manager. It is saved when the handler returns
+func SessionUnaryInterceptor(manager *session.Manager) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		s, err := openSession(ctx, manager)
+		if err != nil {
+			return nil, err
+		}
+		defer saveSession(s)
+		return handler(httpx.ContextWithSession(ctx, s), req)
 	}
+}
 
-	setCookieValues := md.Get(gRPCHeaderCookie)
-	if len(setCookieValues) == 0 {
-		return &Session{
-			Values: map[interface{}]interface{}{},
-			Name:   name,
-			Store:  store,
-		}, nil
-	}
-	hr := &http.Request{
-		Header: http.Header{},
+// SessionStreamInterceptor loads the session of streams from manager. It is saved before the header is sent,
+// and when the handler returns
+func SessionStreamInterceptor(manager *session.Manager) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		s, err := openSession(ss.Context(), manager)
+		if err != nil {
+			return err
+		}
+		stream := &sessionStream{
+			WrappedServerStream: grpc_middleware.WrapServerStream(ss),
+			session:             s,
+		}
+		stream.WrappedContext = httpx.ContextWithSession(ss.Context(), s)
+		defer saveSession(s)
+		return handler(srv, stream)
 	}
+}
+
+// sessionStream saves the session before the header is sent
+type sessionStream struct {
+	*grpc_middleware.WrappedServerStream
+	session Session
+	started bool
+}
 
-	hr.Header.Add(httpHeaderCookie, setCookieValues[0])
-	c, err := hr.Cookie(name)
-	if err != nil {
-		return nil, err
+func (ss *sessionStream) start() {
+	if !ss.started {
+		ss.started = true
+		saveSession(ss.session)
 	}
+}
 
-	values := make(map[interface{}]interface{})
+func (ss *sessionStream) SendHeader(md metadata.MD) error {
+	ss.start()
+	return ss.WrappedServerStream.SendHeader(md)
+}
 
-	err = securecookie.DecodeMulti(name, c.Value, &values, store.Codecs...)
-	if err == nil {
-		s := &Session{
-			Values: values,
-			Name:   name,
-			Store:  store,
-		}
-		ctx = context.WithValue(ctx, ctxSession{}, s)
-		return s, nil
-	}
-	return nil, err
+func (ss *sessionStream) SendMsg(m interface{}) error {
+	ss.start()
+	return ss.WrappedServerStream.SendMsg(m)
 }
 
+// SetCookieFromGRPCMetadata is a gateway forward response option that sends each set-cookie header value of
+// the call in its own Set-Cookie header. Values are forwarded as is, so all their attributes are kept
 func SetCookieFromGRPCMetadata(ctx context.Context, w http.ResponseWriter, msg proto.Message) error {
 	md, ok := runtime.ServerMetadataFromContext(ctx)
 	if ok {
-		values := md.HeaderMD.Get(gRPCSetCookie)
-		if len(values) > 0 {
-			w.Header().Set(httpHeaderSetCookie, values[0])
+		for _, value := range md.HeaderMD.Get(gRPCSetCookie) {
+			w.Header().Add(httpHeaderSetCookie, value)
 		}
 	}
 	w.Header().Del(gRPCHeaderSetCookie)
@@ -0,0 +1,87 @@
+package grpcx
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/omecodes/common/session"
+)
+
+// transportStream records the header set by handlers
+type transportStream struct {
+	header metadata.MD
+}
+
+func (s *transportStream) Method() string {
+	return "/test.Service/Call"
+}
+
+func (s *transportStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *transportStream) SendHeader(md metadata.MD) error {
+	return s.SetHeader(md)
+}
+
+func (s *transportStream) SetTrailer(md metadata.MD) error {
+	return nil
+}
+
+func TestSessionUnaryInterceptor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grpcx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := session.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	interceptor := SessionUnaryInterceptor(session.NewManager(store))
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Call"}
+
+	call := func(cookie string, handler grpc.UnaryHandler) metadata.MD {
+		stream := new(transportStream)
+		ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+		if cookie != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(gRPCHeaderCookie, cookie))
+		}
+		if _, err := interceptor(ctx, nil, info, handler); err != nil {
+			t.Fatal(err)
+		}
+		return stream.header
+	}
+
+	header := call("", func(ctx context.Context, req interface{}) (interface{}, error) {
+		SessionFromContext(ctx).Set("name", "alice")
+		return nil, nil
+	})
+	values := header.Get(gRPCSetCookie)
+	if len(values) != 1 {
+		t.Fatal("changed session must be saved", header)
+	}
+
+	cookies := (&http.Response{Header: http.Header{"Set-Cookie": values}}).Cookies()
+	if len(cookies) != 1 {
+		t.Fatal("invalid session cookie", values)
+	}
+	cookie := cookies[0]
+
+	var name string
+	header = call(cookie.Name+"="+cookie.Value, func(ctx context.Context, req interface{}) (interface{}, error) {
+		name = SessionFromContext(ctx).GetString("name")
+		return nil, nil
+	})
+	if name != "alice" || len(header.Get(gRPCSetCookie)) != 0 {
+		t.Fatal("session must be loaded from the forwarded cookie", name, header)
+	}
+}
@@ -0,0 +1,540 @@
+package grpcx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/omecodes/common/errors"
+	"github.com/omecodes/common/httpx"
+	"github.com/omecodes/common/session"
+)
+
+// StreamRoute selects a gateway streaming route that browsers reach with WebSocket or Server-Sent Events.
+// Other requests to the route are served by the gateway as newline-delimited JSON
+type StreamRoute struct {
+	// Path is the route path or a path.Match pattern like "/v1/rooms/*/messages"
+	Path string
+
+	// Method is the method of the gateway route requests are forwarded to. Default is GET. WebSocket handshakes and
+	// EventSource requests are always GET, so client and bidirectional streaming routes usually need POST
+	Method string
+
+	// WebSocket upgrades WebSocket handshakes. Each message received is a JSON request message, an empty message
+	// ends the requests. Messages are ignored when Method is GET, HEAD or DELETE. Each response message is sent as
+	// a text message holding the gateway {"result": ...} or {"error": ...} chunk. Failures that occur before the
+	// stream starts close the connection with the code 4000 + HTTP status, after sending the error as a text message
+	WebSocket bool
+
+	// EventStream serves the requests that accept text/event-stream as Server-Sent Events. Response messages are
+	// sent as "message" events and the stream error as an "error" event
+	EventStream bool
+
+	// Authenticated rejects requests whose session cookie does not reference a session bound to a user. It requires
+	// the Sessions option. The cookie is also forwarded to the call, as for other gateway routes
+	Authenticated bool
+}
+
+type streamOptions struct {
+	heartbeat      time.Duration
+	origins        []string
+	maxMessageSize int64
+}
+
+// StreamOption configures the streaming routes
+type StreamOption func(opts *streamOptions)
+
+// StreamHeartbeat sets the interval of WebSocket pings and Server-Sent Events comments that keep idle streams
+// alive. WebSocket connections that do not answer pings within two intervals are closed. Default is 30 seconds,
+// 0 disables heartbeats
+func StreamHeartbeat(interval time.Duration) StreamOption {
+	return func(opts *streamOptions) {
+		opts.heartbeat = interval
+	}
+}
+
+// StreamOrigins sets the origins allowed to open WebSockets, "*" allows all. By default only the origin of the
+// gateway is allowed, which protects cookie authenticated streams from cross-site requests
+func StreamOrigins(origins ...string) StreamOption {
+	return func(opts *streamOptions) {
+		opts.origins = append(opts.origins, origins...)
+	}
+}
+
+// StreamMaxMessageSize sets the maximum size of WebSocket messages received. Default is 1MB
+func StreamMaxMessageSize(size int64) StreamOption {
+	return func(opts *streamOptions) {
+		opts.maxMessageSize = size
+	}
+}
+
+// streamHandler serves the streaming routes and passes other requests to the gateway
+type streamHandler struct {
+	routes   []StreamRoute
+	options  streamOptions
+	sessions *session.Manager
+	upgrader websocket.Upgrader
+	next     http.Handler
+}
+
+func newStreamHandler(next http.Handler, routes []StreamRoute, sessions *session.Manager, opts ...StreamOption) *streamHandler {
+	h := &streamHandler{
+		routes:   routes,
+		sessions: sessions,
+		next:     next,
+		options: streamOptions{
+			heartbeat:      30 * time.Second,
+			maxMessageSize: 1 << 20,
+		},
+	}
+	for _, opt := range opts {
+		opt(&h.options)
+	}
+	if len(h.options.origins) > 0 {
+		h.upgrader.CheckOrigin = h.checkOrigin
+	}
+	return h
+}
+
+func (h *streamHandler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	for _, allowed := range h.options.origins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func acceptsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// route returns the streaming route of r, or nil
+func (h *streamHandler) route(r *http.Request) *StreamRoute {
+	if r.Method != http.MethodGet {
+		return nil
+	}
+	for i := range h.routes {
+		route := &h.routes[i]
+		if ok, _ := path.Match(route.Path, r.URL.Path); !ok {
+			continue
+		}
+		if route.WebSocket && websocket.IsWebSocketUpgrade(r) || route.EventStream && acceptsEventStream(r) {
+			return route
+		}
+	}
+	return nil
+}
+
+func (h *streamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route := h.route(r)
+	if route == nil {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	if route.Authenticated && !h.authenticated(r) {
+		httpx.WriteError(w, errors.Unauthorized)
+		return
+	}
+
+	method := route.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	if websocket.IsWebSocketUpgrade(r) {
+		h.serveWebSocket(w, r, method)
+	} else {
+		h.serveEventStream(w, r, method)
+	}
+}
+
+// authenticated tells if the session cookie of r references a session bound to a user
+func (h *streamHandler) authenticated(r *http.Request) bool {
+	if h.sessions == nil {
+		return false
+	}
+	record, err := h.sessions.LoadRequest(r)
+	return err == nil && record.UserID != ""
+}
+
+// heartbeat calls beat at every heartbeat interval until stop is called or beat fails
+func (h *streamHandler) heartbeat(beat func() error) (stop func()) {
+	if h.options.heartbeat <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(h.options.heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if beat() != nil {
+					return
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
+
+// gatewayRequest returns the request forwarded to the gateway route for r
+func gatewayRequest(ctx context.Context, r *http.Request, method string, body io.ReadCloser) *http.Request {
+	req := r.WithContext(ctx)
+	req.Method = method
+	req.Header = r.Header.Clone()
+	for _, name := range []string{"Connection", "Upgrade", "Sec-Websocket-Key", "Sec-Websocket-Version", "Sec-Websocket-Extensions", "Sec-Websocket-Protocol"} {
+		req.Header.Del(name)
+	}
+
+	req.Body = http.NoBody
+	req.ContentLength = 0
+	if body != nil {
+		req.Body = body
+		req.ContentLength = -1
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req
+}
+
+func (h *streamHandler) serveWebSocket(w http.ResponseWriter, r *http.Request, method string) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader responded with the error
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// gateway routes of methods without body never read the requests, the messages are then dropped
+	var body io.ReadCloser
+	var requests *io.PipeWriter
+	if hasBody(method) {
+		reader, writer := io.Pipe()
+		defer reader.Close()
+		body, requests = reader, writer
+	}
+
+	conn.SetReadLimit(h.options.maxMessageSize)
+	h.extendReadDeadline(conn)
+	conn.SetPongHandler(func(string) error {
+		h.extendReadDeadline(conn)
+		return nil
+	})
+	go h.readMessages(conn, requests, cancel)
+
+	stop := h.heartbeat(func() error {
+		return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(h.writeTimeout()))
+	})
+	ws := &wsWriter{conn: conn, header: http.Header{}}
+	h.next.ServeHTTP(ws, gatewayRequest(ctx, r, method, body))
+	stop()
+	ws.close(h.writeTimeout())
+}
+
+func (h *streamHandler) writeTimeout() time.Duration {
+	if h.options.heartbeat > 0 && h.options.heartbeat < 10*time.Second {
+		return h.options.heartbeat
+	}
+	return 10 * time.Second
+}
+
+func (h *streamHandler) extendReadDeadline(conn *websocket.Conn) {
+	if h.options.heartbeat > 0 {
+		_ = conn.SetReadDeadline(time.Now().Add(2 * h.options.heartbeat))
+	}
+}
+
+// hasBody tells if requests of the gateway routes of method have a body
+func hasBody(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+		return false
+	default:
+		return true
+	}
+}
+
+// readMessages writes the messages received on conn to requests, one JSON value per line, or drops them when
+// requests is nil. The call is canceled when the connection fails or is closed by the client
+func (h *streamHandler) readMessages(conn *websocket.Conn, requests *io.PipeWriter, cancel context.CancelFunc) {
+	defer cancel()
+	for {
+		_, message, err := conn.ReadMessage()
+		if requests == nil {
+			if err != nil {
+				return
+			}
+			h.extendReadDeadline(conn)
+			continue
+		}
+
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				_ = requests.Close()
+			} else {
+				_ = requests.CloseWithError(err)
+			}
+			return
+		}
+		h.extendReadDeadline(conn)
+
+		if len(bytes.TrimSpace(message)) == 0 {
+			_ = requests.Close()
+			continue
+		}
+		// writes fail once the requests are ended, later messages are ignored
+		_, _ = requests.Write(append(message, '\n'))
+	}
+}
+
+func (h *streamHandler) serveEventStream(w http.ResponseWriter, r *http.Request, method string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		httpx.WriteError(w, errors.Create(errors.Internal, "streaming is not supported"))
+		return
+	}
+
+	es := &eventWriter{w: w, flusher: flusher, header: http.Header{}}
+	stop := h.heartbeat(es.beat)
+	h.next.ServeHTTP(es, gatewayRequest(r.Context(), r, method, nil))
+	stop()
+	es.close()
+}
+
+// chunks buffers the newline-delimited chunks written by the gateway
+type chunks struct {
+	bytes.Buffer
+}
+
+// each calls fn with each complete chunk
+func (c *chunks) each(fn func(chunk []byte) error) error {
+	for {
+		i := bytes.IndexByte(c.Bytes(), '\n')
+		if i < 0 {
+			return nil
+		}
+		chunk := bytes.TrimSpace(c.Next(i + 1))
+		if len(chunk) == 0 {
+			continue
+		}
+		if err := fn(chunk); err != nil {
+			return err
+		}
+	}
+}
+
+// rest returns the last chunk, which the gateway does not terminate when it is an error
+func (c *chunks) rest() []byte {
+	chunk := bytes.TrimSpace(c.Bytes())
+	c.Reset()
+	return chunk
+}
+
+// wsWriter sends the chunks written by the gateway as WebSocket text messages
+type wsWriter struct {
+	conn   *websocket.Conn
+	header http.Header
+	status int
+	chunks chunks
+	err    error
+}
+
+func (ws *wsWriter) Header() http.Header {
+	return ws.header
+}
+
+func (ws *wsWriter) WriteHeader(status int) {
+	if ws.status == 0 {
+		ws.status = status
+	}
+}
+
+func (ws *wsWriter) Write(b []byte) (int, error) {
+	if ws.err != nil {
+		return 0, ws.err
+	}
+	ws.WriteHeader(http.StatusOK)
+	ws.chunks.Write(b)
+
+	// failures are sent as a whole when the stream ends
+	if ws.status < http.StatusBadRequest {
+		ws.err = ws.chunks.each(ws.send)
+	}
+	return len(b), ws.err
+}
+
+func (ws *wsWriter) Flush() {}
+
+func (ws *wsWriter) send(chunk []byte) error {
+	return ws.conn.WriteMessage(websocket.TextMessage, chunk)
+}
+
+// close sends the rest of the response and closes the connection
+func (ws *wsWriter) close(timeout time.Duration) {
+	if ws.err != nil {
+		return
+	}
+	if rest := ws.chunks.rest(); len(rest) > 0 {
+		if err := ws.send(rest); err != nil {
+			return
+		}
+	}
+
+	code, reason := websocket.CloseNormalClosure, ""
+	if ws.status >= http.StatusBadRequest {
+		code, reason = 4000+ws.status, http.StatusText(ws.status)
+	}
+	_ = ws.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(timeout))
+}
+
+// eventWriter sends the chunks written by the gateway as Server-Sent Events. Failures that occur before the
+// stream starts are responded as is
+type eventWriter struct {
+	sync.Mutex
+	w           http.ResponseWriter
+	flusher     http.Flusher
+	header      http.Header
+	status      int
+	started     bool
+	passthrough bool
+	chunks      chunks
+}
+
+func (es *eventWriter) Header() http.Header {
+	return es.header
+}
+
+func (es *eventWriter) WriteHeader(status int) {
+	es.Lock()
+	defer es.Unlock()
+	es.start(status)
+}
+
+// start writes the response header. Must be called with the lock held
+func (es *eventWriter) start(status int) {
+	if es.started {
+		return
+	}
+	es.started = true
+	es.status = status
+
+	if status >= http.StatusBadRequest {
+		es.passthrough = true
+		for name, values := range es.header {
+			es.w.Header()[name] = values
+		}
+		es.w.WriteHeader(status)
+		return
+	}
+
+	// headers set by the gateway and its options, like cookies, are kept. Content headers describe the gateway
+	// encoding of the stream, not the events
+	header := es.w.Header()
+	for name, values := range es.header {
+		switch name {
+		case "Content-Type", "Content-Length", "Content-Encoding", "Transfer-Encoding":
+			continue
+		}
+		header[name] = values
+	}
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+	es.w.WriteHeader(http.StatusOK)
+	es.flusher.Flush()
+}
+
+func (es *eventWriter) Write(b []byte) (int, error) {
+	es.Lock()
+	defer es.Unlock()
+
+	es.start(http.StatusOK)
+	if es.passthrough {
+		return es.w.Write(b)
+	}
+
+	es.chunks.Write(b)
+	if err := es.chunks.each(es.send); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (es *eventWriter) Flush() {
+	es.Lock()
+	defer es.Unlock()
+	if es.started {
+		es.flusher.Flush()
+	}
+}
+
+// send writes chunk as an event named after its gateway envelope
+func (es *eventWriter) send(chunk []byte) error {
+	event, data := "message", chunk
+
+	var envelope struct {
+		Result json.RawMessage `json:"result"`
+		Error  json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(chunk, &envelope); err == nil {
+		if envelope.Error != nil {
+			event, data = "error", envelope.Error
+		} else if envelope.Result != nil {
+			data = envelope.Result
+		}
+	}
+
+	if _, err := fmt.Fprintf(es.w, "event: %s\ndata: %s\n\n", event, bytes.Replace(data, []byte("\n"), nil, -1)); err != nil {
+		return err
+	}
+	es.flusher.Flush()
+	return nil
+}
+
+// beat writes a comment that keeps the connection alive
+func (es *eventWriter) beat() error {
+	es.Lock()
+	defer es.Unlock()
+
+	es.start(http.StatusOK)
+	if es.passthrough {
+		return nil
+	}
+	if _, err := io.WriteString(es.w, ": heartbeat\n\n"); err != nil {
+		return err
+	}
+	es.flusher.Flush()
+	return nil
+}
+
+// close sends the last chunk
+func (es *eventWriter) close() {
+	es.Lock()
+	defer es.Unlock()
+	if !es.started || es.passthrough {
+		return
+	}
+	if rest := es.chunks.rest(); len(rest) > 0 {
+		_ = es.send(rest)
+	}
+}
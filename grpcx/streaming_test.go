@@ -0,0 +1,187 @@
+package grpcx
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/gorilla/websocket"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+
+	"github.com/omecodes/common/errors"
+)
+
+// echoGateway streams back the upper-cased strings of the request body like a gateway bidirectional route.
+// A "fail" request ends the stream with an error
+func echoGateway(t *testing.T) http.Handler {
+	mux := runtime.NewServeMux()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Error("unexpected method", r.Method)
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		ctx := runtime.NewServerMetadataContext(r.Context(), runtime.ServerMetadata{})
+		runtime.ForwardResponseStream(ctx, mux, &runtime.JSONPb{}, w, r, func() (proto.Message, error) {
+			var text string
+			if err := decoder.Decode(&text); err != nil {
+				return nil, err
+			}
+			if text == "fail" {
+				return nil, errors.Forbidden
+			}
+			return &wrappers.StringValue{Value: strings.ToUpper(text)}, nil
+		})
+	})
+}
+
+func TestStreamWebSocket(t *testing.T) {
+	routes := []StreamRoute{{Path: "/v1/echo", Method: http.MethodPost, WebSocket: true}}
+	server := httptest.NewServer(newStreamHandler(echoGateway(t), routes, nil))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/v1/echo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	for _, text := range []string{"hello", "world"} {
+		if err = conn.WriteMessage(websocket.TextMessage, []byte(`"`+text+`"`)); err != nil {
+			t.Fatal(err)
+		}
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(message) != `{"result":"`+strings.ToUpper(text)+`"}` {
+			t.Fatal("unexpected message", string(message))
+		}
+	}
+
+	// an empty message ends the requests
+	if err = conn.WriteMessage(websocket.TextMessage, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Fatal("connection must be closed when the stream ends", err)
+	}
+}
+
+func TestStreamWebSocketWithoutBody(t *testing.T) {
+	done := make(chan struct{})
+	gateway := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+		if r.Method != http.MethodGet || r.Body != http.NoBody {
+			t.Error("server streaming requests must have no body", r.Method)
+		}
+		// like a server streaming route, the stream goes on until the call is canceled
+		_, _ = w.Write([]byte(`{"result":"A"}` + "\n"))
+		<-r.Context().Done()
+	})
+
+	routes := []StreamRoute{{Path: "/v1/list", WebSocket: true}}
+	server := httptest.NewServer(newStreamHandler(gateway, routes, nil))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/v1/list", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, message, err := conn.ReadMessage(); err != nil || string(message) != `{"result":"A"}` {
+		t.Fatal("unexpected message", string(message), err)
+	}
+
+	// the message is dropped and the close is still seen
+	if err = conn.WriteMessage(websocket.TextMessage, []byte(`"ignored"`)); err != nil {
+		t.Fatal(err)
+	}
+	err = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the call must be canceled when the client closes the connection")
+	}
+}
+
+// listGateway streams "A", "B" and an error like a gateway server streaming route that sets a cookie
+func listGateway() http.Handler {
+	mux := runtime.NewServeMux()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "seen", Value: "1"})
+		messages := []string{"A", "B"}
+		ctx := runtime.NewServerMetadataContext(r.Context(), runtime.ServerMetadata{})
+		runtime.ForwardResponseStream(ctx, mux, &runtime.JSONPb{}, w, r, func() (proto.Message, error) {
+			if len(messages) == 0 {
+				return nil, errors.Forbidden
+			}
+			message := messages[0]
+			messages = messages[1:]
+			return &wrappers.StringValue{Value: message}, nil
+		})
+	})
+}
+
+func TestStreamEventStream(t *testing.T) {
+	routes := []StreamRoute{{Path: "/v1/*", EventStream: true}}
+	handler := newStreamHandler(listGateway(), routes, nil, StreamHeartbeat(0))
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/list", nil)
+	r.Header.Set("Accept", "text/event-stream")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Header().Get("Content-Type") != "text/event-stream" || w.Header().Get("Transfer-Encoding") != "" {
+		t.Fatal("unexpected content type", w.Header())
+	}
+	if cookies := w.Result().Cookies(); len(cookies) != 1 || cookies[0].Name != "seen" {
+		t.Fatal("gateway headers must be kept", w.Header())
+	}
+
+	var events, data []string
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "event: ") {
+			events = append(events, strings.TrimPrefix(line, "event: "))
+		} else if strings.HasPrefix(line, "data: ") {
+			data = append(data, strings.TrimPrefix(line, "data: "))
+		}
+	}
+	if strings.Join(events, ",") != "message,message,error" || data[0] != `"A"` || data[1] != `"B"` {
+		t.Fatal("unexpected events", events, data)
+	}
+
+	// requests that do not accept event streams are passed to the gateway
+	r = httptest.NewRequest(http.MethodGet, "/v1/list", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if !strings.HasPrefix(w.Body.String(), `{"result":"A"}`+"\n") {
+		t.Fatal("unexpected response", w.Body.String())
+	}
+}
+
+func TestStreamAuthenticated(t *testing.T) {
+	routes := []StreamRoute{{Path: "/v1/echo", WebSocket: true, EventStream: true, Authenticated: true}}
+	handler := newStreamHandler(echoGateway(t), routes, nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/echo", nil)
+	r.Header.Set("Accept", "text/event-stream")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatal("streams without session must be rejected", w.Code)
+	}
+}
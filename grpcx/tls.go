@@ -0,0 +1,87 @@
+package grpcx
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/omecodes/common/errors"
+	"github.com/omecodes/common/netx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// listenerTLS exposes the TLS state of connections accepted by a netx TLS listener to gRPC.
+// The handshake has already been configured by the listener so it is only completed here
+type listenerTLS struct{}
+
+func (l listenerTLS) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, errors.NotSupported
+}
+
+func (l listenerTLS) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	tc, ok := conn.(*tls.Conn)
+	if !ok {
+		return conn, nil, nil
+	}
+
+	err := tc.Handshake()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return conn, credentials.TLSInfo{
+		State:          tc.ConnectionState(),
+		CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.PrivacyAndIntegrity},
+	}, nil
+}
+
+func (l listenerTLS) Info() credentials.ProtocolInfo {
+	return credentials.ProtocolInfo{SecurityProtocol: "tls", SecurityVersion: "1.2"}
+}
+
+func (l listenerTLS) Clone() credentials.TransportCredentials {
+	return l
+}
+
+func (l listenerTLS) OverrideServerName(string) error {
+	return nil
+}
+
+// PrincipalFromPeer returns the principal of the verified client certificate of the peer stored in ctx.
+// Calls of the gateway have none: its certificate is the one of the server, not of the HTTP client
+func PrincipalFromPeer(ctx context.Context) *netx.Principal {
+	if fromGateway(ctx) {
+		return nil
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.AuthInfo == nil {
+		return nil
+	}
+
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil
+	}
+	return netx.PrincipalFromConnectionState(&info.State)
+}
+
+func principalContext(ctx context.Context) context.Context {
+	if p := PrincipalFromPeer(ctx); p != nil {
+		return netx.ContextWithPrincipal(ctx, p)
+	}
+	return ctx
+}
+
+func principalUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(principalContext(ctx), req)
+}
+
+func principalStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	wrapped := grpc_middleware.WrapServerStream(ss)
+	wrapped.WrappedContext = principalContext(ss.Context())
+	return handler(srv, wrapped)
+}
@@ -0,0 +1,579 @@
+package httpx
+
+import (
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/omecodes/common/errors"
+)
+
+// DefaultMaxBodySize is the body size limit of Bind
+const DefaultMaxBodySize = 10 << 20
+
+type bindOptions struct {
+	maxBodySize  int64
+	maxMemory    int64
+	skipValidate bool
+}
+
+// BindOption configures Bind
+type BindOption func(opts *bindOptions)
+
+// MaxBodySize sets the body size over which Bind fails. Default is DefaultMaxBodySize
+func MaxBodySize(size int64) BindOption {
+	return func(opts *bindOptions) {
+		opts.maxBodySize = size
+	}
+}
+
+// MaxMemory sets the part of multipart bodies kept in memory, the rest is stored in temporary files. Default is 32MB
+func MaxMemory(size int64) BindOption {
+	return func(opts *bindOptions) {
+		opts.maxMemory = size
+	}
+}
+
+// SkipValidation disables the validation of bound values
+func SkipValidation() BindOption {
+	return func(opts *bindOptions) {
+		opts.skipValidate = true
+	}
+}
+
+var fileHeaderType = reflect.TypeOf(&multipart.FileHeader{})
+
+// Bind decodes the body of r into the struct pointed by v according to its Content-Type: JSON, XML,
+// url-encoded or multipart form. Form values are bound to fields with a "form" tag and multipart files to
+// *multipart.FileHeader fields. Then path variables, query parameters and headers are bound to fields with
+// "path", "query" and "header" tags, and v is validated with Validate.
+// Errors are BadInput failures with a violation per invalid field
+func Bind(r *http.Request, v interface{}, opts ...BindOption) error {
+	options := &bindOptions{
+		maxBodySize: DefaultMaxBodySize,
+		maxMemory:   32 << 20,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return errors.Create(errors.Internal, "httpx.Bind requires a pointer to a struct, got %T", v)
+	}
+
+	if err := bindBody(r, v, options); err != nil {
+		return err
+	}
+
+	failure := bindValues(rv.Elem(), r)
+	if failure != nil {
+		return failure
+	}
+
+	if options.skipValidate {
+		return nil
+	}
+	return Validate(v)
+}
+
+func bindBody(r *http.Request, v interface{}, options *bindOptions) error {
+	if r.Body == nil || r.Body == http.NoBody || r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return nil
+	}
+	if r.ContentLength > options.maxBodySize {
+		return bodyTooLarge(options.maxBodySize)
+	}
+
+	mediaType := "application/json"
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		var err error
+		mediaType, _, err = mime.ParseMediaType(contentType)
+		if err != nil {
+			return errors.Wrap(errors.BadInput, err, "malformed Content-Type header")
+		}
+	}
+
+	body := &limitedBody{ReadCloser: r.Body, remaining: options.maxBodySize}
+	r.Body = body
+
+	var err error
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		err = r.ParseForm()
+		if err == nil {
+			return bindForm(reflect.ValueOf(v).Elem(), r.PostForm, nil)
+		}
+
+	case mediaType == "multipart/form-data":
+		err = r.ParseMultipartForm(options.maxMemory)
+		if err == nil {
+			return bindForm(reflect.ValueOf(v).Elem(), r.MultipartForm.Value, r.MultipartForm.File)
+		}
+
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		err = json.NewDecoder(body).Decode(v)
+		if err == io.EOF {
+			return nil
+		}
+
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		err = xml.NewDecoder(body).Decode(v)
+		if err == io.EOF {
+			return nil
+		}
+
+	default:
+		return errors.Create(errors.UnsupportedMediaType, "unsupported content type %s", mediaType)
+	}
+
+	if body.exceeded {
+		return bodyTooLarge(options.maxBodySize)
+	}
+	if err != nil {
+		return errors.Wrap(errors.BadInput, err, "malformed request body")
+	}
+	return nil
+}
+
+func bodyTooLarge(max int64) error {
+	return errors.Create(errors.TooLarge, "request body is larger than %d bytes", max).WithDetail("max_body_size", max)
+}
+
+// limitedBody fails reads past the limit
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	exceeded  bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// check whether the body really goes further
+		n, _ := b.ReadCloser.Read(make([]byte, 1))
+		if n > 0 {
+			b.exceeded = true
+			return 0, fmt.Errorf("request body too large")
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+func bindForm(v reflect.Value, values map[string][]string, files map[string][]*multipart.FileHeader) error {
+	var failure *errors.Failure
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("form")
+		if name == "" || name == "-" || field.PkgPath != "" {
+			continue
+		}
+
+		fv := v.Field(i)
+		if fh := files[name]; len(fh) > 0 {
+			switch {
+			case fv.Type() == fileHeaderType:
+				fv.Set(reflect.ValueOf(fh[0]))
+			case fv.Kind() == reflect.Slice && fv.Type().Elem() == fileHeaderType:
+				fv.Set(reflect.ValueOf(fh))
+			}
+			continue
+		}
+
+		if raw, found := values[name]; found {
+			if err := setValues(fv, raw); err != nil {
+				failure = violation(failure, name, err.Error())
+			}
+		}
+	}
+
+	if failure != nil {
+		return failure
+	}
+	return nil
+}
+
+// bindValues binds path variables, query parameters and headers
+func bindValues(v reflect.Value, r *http.Request) *errors.Failure {
+	var failure *errors.Failure
+	vars := mux.Vars(r)
+	query := r.URL.Query()
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		var name string
+		var raw []string
+		if name = field.Tag.Get("path"); name != "" {
+			if value, found := vars[name]; found {
+				raw = []string{value}
+			}
+		} else if name = field.Tag.Get("query"); name != "" {
+			raw = query[name]
+		} else if name = field.Tag.Get("header"); name != "" {
+			raw = r.Header[http.CanonicalHeaderKey(name)]
+		} else {
+			continue
+		}
+
+		if len(raw) == 0 {
+			continue
+		}
+		if err := setValues(v.Field(i), raw); err != nil {
+			failure = violation(failure, name, err.Error())
+		}
+	}
+	return failure
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// setValues sets v from raw values. Slices get all the values, other kinds the first one
+func setValues(v reflect.Value, raw []string) error {
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 && !reflect.PtrTo(v.Type()).Implements(textUnmarshalerType) {
+		slice := reflect.MakeSlice(v.Type(), len(raw), len(raw))
+		for i, value := range raw {
+			if err := setValue(slice.Index(i), value); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+	return setValue(v, raw[0])
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func setValue(v reflect.Value, raw string) error {
+	if v.Kind() == reflect.Ptr {
+		ptr := reflect.New(v.Type().Elem())
+		if err := setValue(ptr.Elem(), raw); err != nil {
+			return err
+		}
+		v.Set(ptr)
+		return nil
+	}
+
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw)); err != nil {
+			return fmt.Errorf("is invalid")
+		}
+		return nil
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("must be a duration")
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("must be a boolean")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be a positive integer")
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be a number")
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		v.SetBytes([]byte(raw))
+	default:
+		return fmt.Errorf("cannot be bound")
+	}
+	return nil
+}
+
+// Validate checks the fields of the struct pointed by v against the rules of their "validate" tag, and the
+// fields of nested structs. Rules are separated by commas:
+//
+//	required      the value must not be the zero value
+//	min=n, max=n  bounds of numbers, or of the length of strings, slices and maps
+//	len=n         exact length of strings, slices and maps
+//	oneof=a b c   the value, when set, must be one of the space separated values
+//	email         the value must look like an email address
+//	pattern=re    the value must match the regular expression re. It must be the last rule, since re takes
+//	              the rest of the tag, commas included
+//
+// Rules are parsed once per struct type, and an invalid rule panics at the first validation of its type.
+// It returns a BadInput failure with a violation per invalid field
+func Validate(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	if failure := validateStruct(rv, "", nil); failure != nil {
+		return failure
+	}
+	return nil
+}
+
+func validateStruct(v reflect.Value, prefix string, failure *errors.Failure) *errors.Failure {
+	t := v.Type()
+	for _, field := range structRules(t) {
+		name := prefix + field.name
+		fv := v.Field(field.index)
+		for _, r := range field.rules {
+			if msg := r.check(fv); msg != "" {
+				failure = violation(failure, name, msg)
+				break
+			}
+		}
+		failure = validateNested(fv, name, failure)
+	}
+	return failure
+}
+
+func validateNested(v reflect.Value, name string, failure *errors.Failure) *errors.Failure {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return failure
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return failure
+		}
+		return validateStruct(v, name+".", failure)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			failure = validateNested(v.Index(i), fmt.Sprintf("%s[%d]", name, i), failure)
+		}
+	}
+	return failure
+}
+
+// fieldName returns the name clients know field by
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "query", "path", "header", "xml"} {
+		if name := strings.Split(field.Tag.Get(tag), ",")[0]; name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+var (
+	emailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	typesRules   = &sync.Map{}
+)
+
+// fieldRules are the parsed validation rules of an exported field
+type fieldRules struct {
+	index int
+	name  string
+	rules []rule
+}
+
+// structRules returns the field rules of struct type t, parsed on first use
+func structRules(t reflect.Type) []fieldRules {
+	if cached, found := typesRules.Load(t); found {
+		return cached.([]fieldRules)
+	}
+
+	var fields []fieldRules
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		fields = append(fields, fieldRules{
+			index: i,
+			name:  fieldName(field),
+			rules: parseRules(t, field),
+		})
+	}
+	typesRules.Store(t, fields)
+	return fields
+}
+
+type rule struct {
+	name    string
+	arg     string
+	bound   float64
+	pattern *regexp.Regexp
+}
+
+// parseRules parses the "validate" tag of field. It panics on invalid rules
+func parseRules(t reflect.Type, field reflect.StructField) []rule {
+	tag := field.Tag.Get("validate")
+	if tag == "" || tag == "-" {
+		return nil
+	}
+
+	var rules []rule
+	for tag != "" {
+		var part string
+		if strings.HasPrefix(strings.TrimSpace(tag), "pattern=") {
+			part, tag = strings.TrimSpace(tag), ""
+		} else if i := strings.Index(tag, ","); i >= 0 {
+			part, tag = strings.TrimSpace(tag[:i]), tag[i+1:]
+		} else {
+			part, tag = strings.TrimSpace(tag), ""
+		}
+
+		r := rule{name: part}
+		if i := strings.Index(part, "="); i >= 0 {
+			r.name, r.arg = part[:i], part[i+1:]
+		}
+
+		var err error
+		switch r.name {
+		case "required", "email":
+		case "min", "max", "len":
+			r.bound, err = strconv.ParseFloat(r.arg, 64)
+		case "oneof":
+			if len(strings.Fields(r.arg)) == 0 {
+				err = fmt.Errorf("no values")
+			}
+		case "pattern":
+			r.pattern, err = regexp.Compile(r.arg)
+		default:
+			err = fmt.Errorf("unknown rule")
+		}
+		if err != nil {
+			panic(fmt.Sprintf("httpx: invalid validation rule %q of %s.%s: %s", part, t, field.Name, err))
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// check returns the violation description of r for v, or an empty string if v satisfies it
+func (r rule) check(v reflect.Value) string {
+	if r.name == "required" {
+		if isZero(v) {
+			return "is required"
+		}
+		return ""
+	}
+
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	switch r.name {
+	case "min", "max", "len":
+		size, unit := measure(v)
+		switch {
+		case r.name == "len" && size != r.bound:
+			return fmt.Sprintf("must have %s %s", r.arg, unit)
+		case r.name == "min" && size < r.bound && unit != "":
+			return fmt.Sprintf("must have at least %s %s", r.arg, unit)
+		case r.name == "min" && size < r.bound:
+			return fmt.Sprintf("must be greater than or equal to %s", r.arg)
+		case r.name == "max" && size > r.bound && unit != "":
+			return fmt.Sprintf("must have at most %s %s", r.arg, unit)
+		case r.name == "max" && size > r.bound:
+			return fmt.Sprintf("must be less than or equal to %s", r.arg)
+		}
+
+	case "oneof":
+		if isZero(v) {
+			return ""
+		}
+		value := fmt.Sprint(v.Interface())
+		for _, allowed := range strings.Fields(r.arg) {
+			if value == allowed {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of %s", strings.Join(strings.Fields(r.arg), ", "))
+
+	case "email":
+		if v.Kind() == reflect.String && v.String() != "" && !emailPattern.MatchString(v.String()) {
+			return "must be an email address"
+		}
+
+	case "pattern":
+		if v.Kind() == reflect.String && v.String() != "" && !r.pattern.MatchString(v.String()) {
+			return "has an invalid format"
+		}
+	}
+	return ""
+}
+
+// measure returns the value of numbers, and the length of strings, slices and maps with its unit
+func measure(v reflect.Value) (float64, string) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(len([]rune(v.String()))), "characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), "items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), ""
+	default:
+		return 0, ""
+	}
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
@@ -0,0 +1,152 @@
+package httpx
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/omecodes/common/errors"
+)
+
+type createItemRequest struct {
+	Collection string        `path:"collection" validate:"required"`
+	DryRun     bool          `query:"dry_run"`
+	Fields     []string      `query:"field"`
+	Tenant     string        `header:"X-Tenant-ID" validate:"required"`
+	Timeout    time.Duration `query:"timeout"`
+
+	Name     string                `json:"name" xml:"name" form:"name" validate:"required,min=3,max=20"`
+	Email    string                `json:"email" xml:"email" form:"email" validate:"email"`
+	Kind     string                `json:"kind" xml:"kind" form:"kind" validate:"oneof=file folder"`
+	Size     int                   `json:"size" xml:"size" form:"size" validate:"min=0,max=100"`
+	Labels   []label               `json:"labels"`
+	Document *multipart.FileHeader `form:"document"`
+}
+
+type label struct {
+	Key string `json:"key" validate:"required,pattern=^[a-z]{1,8}$"`
+}
+
+func bindRequest(method, contentType string, body []byte) (*createItemRequest, error) {
+	r := httptest.NewRequest(method, "/items/files?dry_run=true&field=a&field=b&timeout=2s", bytes.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	r.Header.Set("X-Tenant-ID", "acme")
+	r = mux.SetURLVars(r, map[string]string{"collection": "files"})
+
+	req := new(createItemRequest)
+	return req, Bind(r, req)
+}
+
+func TestBindJSON(t *testing.T) {
+	req, err := bindRequest(http.MethodPost, "application/json", []byte(`{"name":"report","email":"a@b.io","kind":"file","size":3,"labels":[{"key":"work"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Collection != "files" || !req.DryRun || len(req.Fields) != 2 || req.Tenant != "acme" ||
+		req.Timeout != 2*time.Second || req.Name != "report" || req.Size != 3 || req.Labels[0].Key != "work" {
+		t.Fatalf("unexpected request %+v", req)
+	}
+}
+
+func TestBindXMLAndForm(t *testing.T) {
+	req, err := bindRequest(http.MethodPost, "application/xml", []byte(`<item><name>report</name><kind>folder</kind></item>`))
+	if err != nil || req.Name != "report" || req.Kind != "folder" {
+		t.Fatal("unexpected xml binding", req, err)
+	}
+
+	req, err = bindRequest(http.MethodPost, "application/x-www-form-urlencoded", []byte(`name=report&kind=file&size=7`))
+	if err != nil || req.Name != "report" || req.Size != 7 {
+		t.Fatal("unexpected form binding", req, err)
+	}
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	_ = mw.WriteField("name", "report")
+	_ = mw.WriteField("kind", "file")
+	fw, _ := mw.CreateFormFile("document", "report.txt")
+	_, _ = fw.Write([]byte("content"))
+	_ = mw.Close()
+
+	req, err = bindRequest(http.MethodPost, mw.FormDataContentType(), body.Bytes())
+	if err != nil || req.Name != "report" || req.Document == nil || req.Document.Filename != "report.txt" {
+		t.Fatal("unexpected multipart binding", req, err)
+	}
+}
+
+func TestBindValidation(t *testing.T) {
+	_, err := bindRequest(http.MethodPost, "application/json", []byte(`{"name":"ab","email":"nope","kind":"link","size":101,"labels":[{"key":"UP"}]}`))
+	failure := errors.FailureOf(err)
+	if failure == nil || failure.Code != errors.BadInput {
+		t.Fatal("validation must fail with bad input", err)
+	}
+
+	invalid := map[string]bool{}
+	for _, v := range failure.Violations {
+		invalid[v.Field] = true
+	}
+	for _, field := range []string{"name", "email", "kind", "size", "labels[0].key"} {
+		if !invalid[field] {
+			t.Fatal("missing violation", field, failure.Violations)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	WriteError(w, err)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid-params") {
+		t.Fatal("validation errors must be rendered as bad requests", w.Code, w.Body.String())
+	}
+}
+
+func TestBindLimits(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"`+strings.Repeat("a", 100)+`"}`))
+	r.ContentLength = -1
+	err := Bind(r, new(createItemRequest), MaxBodySize(20))
+	if errors.Code(err) != errors.TooLarge || !strings.Contains(err.Error(), "larger") {
+		t.Fatal("bodies over the limit must be rejected", err)
+	}
+	w := httptest.NewRecorder()
+	WriteError(w, err)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatal("bodies over the limit must be rendered as 413", w.Code)
+	}
+
+	_, err = bindRequest(http.MethodPost, "application/json", []byte(`{"size":"big"}`))
+	if errors.Code(err) != errors.BadInput {
+		t.Fatal("malformed bodies must be rejected", err)
+	}
+
+	_, err = bindRequest(http.MethodPost, "text/csv", []byte(`a,b`))
+	if errors.Code(err) != errors.UnsupportedMediaType {
+		t.Fatal("unsupported content types must be rejected", err)
+	}
+	w = httptest.NewRecorder()
+	WriteError(w, err)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Fatal("unsupported content types must be rendered as 415", w.Code)
+	}
+}
+
+func TestValidationRules(t *testing.T) {
+	if err := Validate(&label{Key: "abcdefghi"}); err == nil {
+		t.Fatal("patterns with commas must be kept whole")
+	}
+	if err := Validate(&label{Key: "work"}); err != nil {
+		t.Fatal(err)
+	}
+
+	type invalid struct {
+		Key string `validate:"pattern=[a-"`
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("invalid rules must panic")
+		}
+	}()
+	_ = Validate(&invalid{})
+}
@@ -0,0 +1,389 @@
+package httpx
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	EncodingBrotli  = "br"
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+
+	defaultCompressMinSize = 1024
+)
+
+// encodings in order of preference when clients accept several with the same quality
+var encodingPreference = []string{EncodingBrotli, EncodingGzip, EncodingDeflate}
+
+// incompressible media types, matched by prefix
+var defaultIncompressibleTypes = []string{
+	"image/", "video/", "audio/", "font/woff",
+	"application/zip", "application/gzip", "application/x-gzip", "application/x-brotli",
+	"application/x-7z-compressed", "application/x-rar-compressed", "application/pdf",
+	"application/octet-stream", "multipart/byteranges",
+}
+
+type compressionOptions struct {
+	level         int
+	minSize       int
+	skippedTypes  []string
+	allowedCodecs []string
+}
+
+// CompressionOption configures the Compression middleware
+type CompressionOption func(opts *compressionOptions)
+
+// CompressionLevel sets the level used by all encoders, from 1 (fastest) to 9 (best compression)
+func CompressionLevel(level int) CompressionOption {
+	return func(opts *compressionOptions) {
+		opts.level = level
+	}
+}
+
+// CompressionMinSize sets the body size under which responses are not compressed
+func CompressionMinSize(size int) CompressionOption {
+	return func(opts *compressionOptions) {
+		opts.minSize = size
+	}
+}
+
+// CompressionSkippedTypes adds media types, or media type prefixes like "image/", that are not compressed
+func CompressionSkippedTypes(types ...string) CompressionOption {
+	return func(opts *compressionOptions) {
+		opts.skippedTypes = append(opts.skippedTypes, types...)
+	}
+}
+
+// CompressionEncodings restricts the encodings that can be used, among "br", "gzip" and "deflate"
+func CompressionEncodings(encodings ...string) CompressionOption {
+	return func(opts *compressionOptions) {
+		opts.allowedCodecs = encodings
+	}
+}
+
+// Compression compresses responses with the encoding negotiated from Accept-Encoding. Responses that already
+// have a Content-Encoding, partial responses, incompressible media types and bodies smaller than the minimum size
+// are sent as they are. Upgrade requests, like WebSockets, are not wrapped
+func Compression(opts ...CompressionOption) func(http.Handler) http.Handler {
+	options := &compressionOptions{
+		level:         6,
+		minSize:       defaultCompressMinSize,
+		skippedTypes:  append([]string{}, defaultIncompressibleTypes...),
+		allowedCodecs: encodingPreference,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	pools := map[string]*sync.Pool{}
+	for _, encoding := range options.allowedCodecs {
+		pools[encoding] = newEncoderPool(encoding, options.level)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Upgrade") != "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := NegotiateEncoding(r.Header.Get("Accept-Encoding"), options.allowedCodecs...)
+			if encoding == "" || r.Header.Get("Range") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				options:        options,
+				encoding:       encoding,
+				pool:           pools[encoding],
+			}
+			defer cw.close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// NegotiateEncoding returns the preferred encoding among available that is accepted by the
+// Accept-Encoding header value. It returns an empty string when the body must not be encoded
+func NegotiateEncoding(acceptEncoding string, available ...string) string {
+	if len(available) == 0 {
+		available = encodingPreference
+	}
+
+	qualities := map[string]float64{}
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		if name == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+
+		if name == "*" {
+			wildcard = quality
+		} else {
+			qualities[name] = quality
+		}
+	}
+
+	best, bestQuality := "", 0.0
+	for _, encoding := range encodingPreference {
+		if !contains(available, encoding) {
+			continue
+		}
+
+		quality, found := qualities[encoding]
+		if !found && encoding == EncodingGzip {
+			quality, found = qualities["x-gzip"]
+		}
+		if !found {
+			if wildcard < 0 {
+				continue
+			}
+			quality = wildcard
+		}
+
+		if quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+	return best
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+func newEncoderPool(encoding string, level int) *sync.Pool {
+	return &sync.Pool{New: func() interface{} {
+		switch encoding {
+		case EncodingBrotli:
+			return brotli.NewWriterLevel(nil, level)
+
+		case EncodingGzip:
+			w, err := gzip.NewWriterLevel(nil, level)
+			if err != nil {
+				w = gzip.NewWriter(nil)
+			}
+			return w
+
+		default:
+			w, err := flate.NewWriter(nil, level)
+			if err != nil {
+				w, _ = flate.NewWriter(nil, flate.DefaultCompression)
+			}
+			return w
+		}
+	}}
+}
+
+// compressWriter buffers the first bytes of the body until it can decide whether to compress it
+type compressWriter struct {
+	http.ResponseWriter
+	options  *compressionOptions
+	encoding string
+	pool     *sync.Pool
+
+	status   int
+	buffer   []byte
+	decided  bool
+	compress bool
+	encoder  encoder
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.status == 0 {
+		cw.status = status
+	}
+
+	// bodiless and informational responses are sent right away
+	if status < 200 || status == http.StatusNoContent || status == http.StatusNotModified {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+
+	if !cw.decided {
+		if !cw.compressible() {
+			cw.decide(false)
+		} else {
+			cw.buffer = append(cw.buffer, b...)
+			if len(cw.buffer) < cw.options.minSize {
+				return len(b), nil
+			}
+			cw.decide(true)
+			return len(b), cw.flushBuffer()
+		}
+	}
+
+	if cw.compress {
+		return cw.encoder.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// compressible checks the response headers
+func (cw *compressWriter) compressible() bool {
+	h := cw.Header()
+	if cw.status != http.StatusOK || h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+
+	if length, err := strconv.Atoi(h.Get("Content-Length")); err == nil && length < cw.options.minSize {
+		return false
+	}
+
+	contentType := h.Get("Content-Type")
+	if contentType == "" {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if mediaType == "image/svg+xml" {
+		return true
+	}
+	for _, skipped := range cw.options.skippedTypes {
+		if strings.HasPrefix(mediaType, skipped) {
+			return false
+		}
+	}
+	return true
+}
+
+func (cw *compressWriter) decide(compress bool) {
+	if cw.decided {
+		return
+	}
+	cw.decided = true
+	cw.compress = compress
+
+	h := cw.Header()
+	if compress {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", cw.encoding)
+		if h.Get("Content-Type") == "" {
+			// net/http would sniff the compressed bytes
+			h.Set("Content-Type", http.DetectContentType(cw.buffer))
+		}
+		// the compressed representation is not byte for byte identical
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+		cw.encoder = cw.pool.Get().(encoder)
+		cw.encoder.Reset(cw.ResponseWriter)
+	}
+
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	if !compress && len(cw.buffer) > 0 {
+		_ = cw.flushBuffer()
+	}
+}
+
+func (cw *compressWriter) flushBuffer() error {
+	buffer := cw.buffer
+	cw.buffer = nil
+	if len(buffer) == 0 {
+		return nil
+	}
+
+	var err error
+	if cw.compress {
+		_, err = cw.encoder.Write(buffer)
+	} else {
+		_, err = cw.ResponseWriter.Write(buffer)
+	}
+	return err
+}
+
+// close writes what is left once the handler returned
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		if cw.status == 0 && len(cw.buffer) == 0 {
+			return
+		}
+		// the whole body is smaller than the minimum size
+		cw.decide(false)
+		return
+	}
+
+	if cw.compress {
+		_ = cw.encoder.Close()
+		cw.encoder.Reset(nil)
+		cw.pool.Put(cw.encoder)
+		cw.encoder = nil
+	}
+}
+
+// Flush sends the buffered data, compressing it when the headers allow it
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.decide(cw.compressible())
+		if cw.compress {
+			_ = cw.flushBuffer()
+		}
+	}
+
+	if cw.compress {
+		_ = cw.encoder.Flush()
+	}
+
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("httpx: response writer does not support hijacking")
+	}
+	return hijacker.Hijack()
+}
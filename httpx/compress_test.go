@@ -0,0 +1,125 @@
+package httpx
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	cases := map[string]string{
+		"":                        "",
+		"gzip, deflate, br":       EncodingBrotli,
+		"gzip;q=1, br;q=0.5":      EncodingGzip,
+		"br;q=0, *":               EncodingGzip,
+		"identity":                "",
+		"deflate;q=0.2, x-gzip":   EncodingGzip,
+		"*;q=0.1, deflate;q=0.05": EncodingBrotli,
+	}
+	for header, expected := range cases {
+		if encoding := NegotiateEncoding(header); encoding != expected {
+			t.Fatalf("%q: expected %q, got %q", header, expected, encoding)
+		}
+	}
+}
+
+func compressed(handler http.HandlerFunc, headers map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	for name, value := range headers {
+		r.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	Compression(CompressionMinSize(100))(handler).ServeHTTP(w, r)
+	return w
+}
+
+func TestCompression(t *testing.T) {
+	body := strings.Repeat("compressible text ", 100)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(body))
+	}
+
+	w := compressed(handler, map[string]string{"Accept-Encoding": "gzip"})
+	if w.Header().Get("Content-Encoding") != EncodingGzip || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatal("response must be compressed", w.Header())
+	}
+
+	reader, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(reader)
+	if string(data) != body {
+		t.Fatal("unexpected decompressed body")
+	}
+
+	w = compressed(handler, map[string]string{"Accept-Encoding": "br"})
+	data, _ = ioutil.ReadAll(brotli.NewReader(w.Body))
+	if w.Header().Get("Content-Encoding") != EncodingBrotli || string(data) != body {
+		t.Fatal("response must be compressed with brotli")
+	}
+
+	small := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("small"))
+	}
+	w = compressed(small, map[string]string{"Accept-Encoding": "gzip"})
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != "small" {
+		t.Fatal("small bodies must not be compressed")
+	}
+
+	image := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte(body))
+	}
+	w = compressed(image, map[string]string{"Accept-Encoding": "gzip"})
+	if w.Header().Get("Content-Encoding") != "" {
+		t.Fatal("compressed media types must not be compressed again")
+	}
+
+	ranged := func(w http.ResponseWriter, r *http.Request) {
+		ServeContent(w, r, ContentInfo{ContentType: "text/plain"}, strings.NewReader(body))
+	}
+	w = compressed(ranged, map[string]string{"Accept-Encoding": "gzip", "Range": "bytes=0-9"})
+	if w.Code != http.StatusPartialContent || w.Header().Get("Content-Encoding") != "" || w.Body.String() != body[:10] {
+		t.Fatal("range responses must not be compressed", w.Code)
+	}
+}
+
+func TestServePrecompressedFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.js")
+	_ = ioutil.WriteFile(filename, []byte("console.log('plain')"), 0644)
+	_ = ioutil.WriteFile(filename+".br", []byte("brotli bytes"), 0644)
+
+	serveFile := func(acceptEncoding string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+		w := httptest.NewRecorder()
+		FileServer(dir).ServeHTTP(w, r)
+		return w
+	}
+
+	w := serveFile("gzip, br")
+	if w.Header().Get("Content-Encoding") != EncodingBrotli || w.Body.String() != "brotli bytes" ||
+		!strings.Contains(w.Header().Get("Content-Type"), "javascript") {
+		t.Fatal("precompressed sibling must be served", w.Header())
+	}
+
+	w = serveFile("gzip")
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != "console.log('plain')" {
+		t.Fatal("original file must be served", w.Header())
+	}
+}
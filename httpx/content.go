@@ -0,0 +1,343 @@
+package httpx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RangeReader is implemented by contents, like remote blobs, that are read by range rather than by seeking
+type RangeReader interface {
+	// Size returns the total size of the content
+	Size() int64
+
+	// ReadRange returns a reader of length bytes from offset
+	ReadRange(offset, length int64) (io.ReadCloser, error)
+}
+
+// ContentInfo describes a content served with ServeContent
+type ContentInfo struct {
+	// Name is used to detect the content type from its extension when neither ContentType
+	// nor the response Content-Type header is set
+	Name        string
+	ContentType string
+
+	// ModTime is sent as Last-Modified and compared to If-Modified-Since, If-Unmodified-Since and If-Range dates
+	ModTime time.Time
+
+	// ETag is the quoted entity tag. One is generated from the size and ModTime when empty
+	ETag string
+}
+
+var errInvalidRange = errors.New("invalid range")
+
+type byteRange struct {
+	start, length int64
+}
+
+func (br byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", br.start, br.start+br.length-1, size)
+}
+
+// ServeContent writes content, an io.ReadSeeker or a RangeReader, honouring the conditional request headers
+// and Range requests, including multiple ranges sent as multipart/byteranges
+func ServeContent(w http.ResponseWriter, r *http.Request, info ContentInfo, content interface{}) {
+	var size int64
+	switch c := content.(type) {
+	case RangeReader:
+		size = c.Size()
+
+	case io.ReadSeeker:
+		var err error
+		size, err = c.Seek(0, io.SeekEnd)
+		if err == nil {
+			_, err = c.Seek(0, io.SeekStart)
+		}
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+
+	default:
+		WriteError(w, fmt.Errorf("httpx: content of type %T can not be served", content))
+		return
+	}
+
+	etag := info.ETag
+	if etag == "" && !info.ModTime.IsZero() {
+		etag = fmt.Sprintf(`"%x-%x"`, info.ModTime.UnixNano(), size)
+	}
+
+	header := w.Header()
+	if etag != "" {
+		header.Set("ETag", etag)
+	}
+	if !info.ModTime.IsZero() {
+		header.Set("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+	}
+
+	if status := checkPreconditions(r, etag, info.ModTime); status != 0 {
+		if status == http.StatusNotModified {
+			header.Del("Content-Type")
+			header.Del("Content-Length")
+		}
+		w.WriteHeader(status)
+		return
+	}
+
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = header.Get("Content-Type")
+	}
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(info.Name))
+	}
+	if contentType == "" {
+		contentType = sniffContentType(content)
+	}
+	header.Set("Accept-Ranges", "bytes")
+
+	var ranges []byteRange
+	isRead := r.Method == http.MethodGet || r.Method == http.MethodHead
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" && isRead && checkIfRange(r, etag, info.ModTime) {
+		var err error
+		ranges, err = parseRanges(rangeHeader, size)
+		if err != nil {
+			header.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+			writeProblem(w, http.StatusRequestedRangeNotSatisfiable, "range not satisfiable", "")
+			return
+		}
+	}
+
+	switch len(ranges) {
+	case 0:
+		header.Set("Content-Type", contentType)
+		header.Set("Content-Length", strconv.FormatInt(size, 10))
+		w.WriteHeader(http.StatusOK)
+		if r.Method != http.MethodHead {
+			copyRange(w, content, byteRange{start: 0, length: size})
+		}
+
+	case 1:
+		header.Set("Content-Type", contentType)
+		header.Set("Content-Range", ranges[0].contentRange(size))
+		header.Set("Content-Length", strconv.FormatInt(ranges[0].length, 10))
+		w.WriteHeader(http.StatusPartialContent)
+		if r.Method != http.MethodHead {
+			copyRange(w, content, ranges[0])
+		}
+
+	default:
+		mw := multipart.NewWriter(w)
+		header.Set("Content-Type", "multipart/byteranges; boundary="+mw.Boundary())
+		w.WriteHeader(http.StatusPartialContent)
+		if r.Method == http.MethodHead {
+			return
+		}
+
+		for _, br := range ranges {
+			part, err := mw.CreatePart(textproto.MIMEHeader{
+				"Content-Type":  {contentType},
+				"Content-Range": {br.contentRange(size)},
+			})
+			if err != nil {
+				return
+			}
+			if !copyRange(part, content, br) {
+				return
+			}
+		}
+		_ = mw.Close()
+	}
+}
+
+func copyRange(w io.Writer, content interface{}, br byteRange) bool {
+	var err error
+	switch c := content.(type) {
+	case RangeReader:
+		var reader io.ReadCloser
+		reader, err = c.ReadRange(br.start, br.length)
+		if err == nil {
+			_, err = io.CopyN(w, reader, br.length)
+			_ = reader.Close()
+		}
+
+	case io.ReadSeeker:
+		_, err = c.Seek(br.start, io.SeekStart)
+		if err == nil {
+			_, err = io.CopyN(w, c, br.length)
+		}
+	}
+
+	if err != nil {
+		log.Println("[xhttp]:\tcould not write response:", err)
+		return false
+	}
+	return true
+}
+
+func sniffContentType(content interface{}) string {
+	rs, ok := content.(io.ReadSeeker)
+	if !ok {
+		return "application/octet-stream"
+	}
+
+	buffer := make([]byte, 512)
+	n, _ := io.ReadFull(rs, buffer)
+	_, err := rs.Seek(0, io.SeekStart)
+	if err != nil || n == 0 {
+		return "application/octet-stream"
+	}
+	return http.DetectContentType(buffer[:n])
+}
+
+// parseRanges parses a "bytes=" Range header. Ranges starting after the content are dropped
+// and an error is returned when none is satisfiable
+func parseRanges(header string, size int64) ([]byteRange, error) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(header, prefix) {
+		return nil, errInvalidRange
+	}
+
+	var ranges []byteRange
+	var total int64
+	for _, spec := range strings.Split(header[len(prefix):], ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		dash := strings.Index(spec, "-")
+		if dash < 0 {
+			return nil, errInvalidRange
+		}
+		first, last := strings.TrimSpace(spec[:dash]), strings.TrimSpace(spec[dash+1:])
+
+		var br byteRange
+		if first == "" {
+			// suffix range: the last n bytes
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n < 0 {
+				return nil, errInvalidRange
+			}
+			if n == 0 {
+				continue
+			}
+			if n > size {
+				n = size
+			}
+			br = byteRange{start: size - n, length: n}
+
+		} else {
+			start, err := strconv.ParseInt(first, 10, 64)
+			if err != nil || start < 0 {
+				return nil, errInvalidRange
+			}
+			if start >= size {
+				continue
+			}
+
+			end := size - 1
+			if last != "" {
+				end, err = strconv.ParseInt(last, 10, 64)
+				if err != nil || end < start {
+					return nil, errInvalidRange
+				}
+				if end >= size {
+					end = size - 1
+				}
+			}
+			br = byteRange{start: start, length: end - start + 1}
+		}
+
+		if br.length > 0 {
+			ranges = append(ranges, br)
+			total += br.length
+		}
+	}
+
+	if len(ranges) == 0 {
+		return nil, errInvalidRange
+	}
+
+	// sending more than the content is wasteful, the whole content is sent instead
+	if total > size {
+		return nil, nil
+	}
+	return ranges, nil
+}
+
+// checkPreconditions evaluates the conditional headers in the RFC 7232 order.
+// It returns 0 when the request must be served
+func checkPreconditions(r *http.Request, etag string, modTime time.Time) int {
+	if match := r.Header.Get("If-Match"); match != "" {
+		if !etagListMatches(match, etag, true) {
+			return http.StatusPreconditionFailed
+		}
+
+	} else if since, err := http.ParseTime(r.Header.Get("If-Unmodified-Since")); err == nil && !modTime.IsZero() {
+		if modTime.Truncate(time.Second).After(since) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	isRead := r.Method == http.MethodGet || r.Method == http.MethodHead
+	if noneMatch := r.Header.Get("If-None-Match"); noneMatch != "" {
+		if etagListMatches(noneMatch, etag, false) {
+			if isRead {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+
+	} else if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && isRead && !modTime.IsZero() {
+		if !modTime.Truncate(time.Second).After(since) {
+			return http.StatusNotModified
+		}
+	}
+	return 0
+}
+
+// checkIfRange tells whether the Range header must be honoured
+func checkIfRange(r *http.Request, etag string, modTime time.Time) bool {
+	ifRange := r.Header.Get("If-Range")
+	if ifRange == "" {
+		return true
+	}
+
+	if strings.HasPrefix(ifRange, `"`) || strings.HasPrefix(ifRange, "W/") {
+		return etagMatches(ifRange, etag, true)
+	}
+
+	date, err := http.ParseTime(ifRange)
+	return err == nil && !modTime.IsZero() && modTime.Truncate(time.Second).Equal(date)
+}
+
+func etagListMatches(list, etag string, strong bool) bool {
+	if etag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || etagMatches(candidate, etag, strong) {
+			return true
+		}
+	}
+	return false
+}
+
+func etagMatches(a, b string, strong bool) bool {
+	if strong {
+		return !strings.HasPrefix(a, "W/") && !strings.HasPrefix(b, "W/") && a == b
+	}
+	return strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
+}
@@ -0,0 +1,92 @@
+package httpx
+
+import (
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var contentModTime = time.Date(2020, 11, 30, 10, 0, 0, 0, time.UTC)
+
+func serve(headers map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/file.txt", nil)
+	for name, value := range headers {
+		r.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	ServeContent(w, r, ContentInfo{Name: "file.txt", ModTime: contentModTime}, strings.NewReader("0123456789"))
+	return w
+}
+
+func TestServeContentRanges(t *testing.T) {
+	w := serve(nil)
+	if w.Code != http.StatusOK || w.Body.String() != "0123456789" || w.Header().Get("ETag") == "" {
+		t.Fatal("unexpected full response", w.Code, w.Body.String())
+	}
+
+	w = serve(map[string]string{"Range": "bytes=-3"})
+	if w.Code != http.StatusPartialContent || w.Body.String() != "789" || w.Header().Get("Content-Range") != "bytes 7-9/10" {
+		t.Fatal("unexpected suffix range response", w.Code, w.Body.String(), w.Header().Get("Content-Range"))
+	}
+
+	w = serve(map[string]string{"Range": "bytes=20-30"})
+	if w.Code != http.StatusRequestedRangeNotSatisfiable || w.Header().Get("Content-Range") != "bytes */10" {
+		t.Fatal("unsatisfiable ranges must be refused", w.Code)
+	}
+
+	w = serve(map[string]string{"Range": "bytes=0-1,5-6"})
+	mediaType, params, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if err != nil || w.Code != http.StatusPartialContent || mediaType != "multipart/byteranges" {
+		t.Fatal("unexpected multiple ranges response", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	var parts []string
+	reader := multipart.NewReader(w.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadAll(part)
+		parts = append(parts, part.Header.Get("Content-Range")+"="+string(data))
+	}
+
+	if strings.Join(parts, ";") != "bytes 0-1/10=01;bytes 5-6/10=56" {
+		t.Fatal("unexpected parts", parts)
+	}
+}
+
+func TestServeContentConditions(t *testing.T) {
+	etag := serve(nil).Header().Get("ETag")
+
+	if w := serve(map[string]string{"If-None-Match": etag}); w.Code != http.StatusNotModified {
+		t.Fatal("matching etags must not be modified", w.Code)
+	}
+
+	if w := serve(map[string]string{"If-Modified-Since": contentModTime.Format(http.TimeFormat)}); w.Code != http.StatusNotModified {
+		t.Fatal("unmodified contents must not be sent", w.Code)
+	}
+
+	if w := serve(map[string]string{"If-Match": `"other"`}); w.Code != http.StatusPreconditionFailed {
+		t.Fatal("failed preconditions must be reported", w.Code)
+	}
+
+	w := serve(map[string]string{"Range": "bytes=0-1", "If-Range": `"other"`})
+	if w.Code != http.StatusOK {
+		t.Fatal("ranges must be ignored when If-Range does not match", w.Code)
+	}
+
+	w = serve(map[string]string{"Range": "bytes=0-1", "If-Range": etag})
+	if w.Code != http.StatusPartialContent {
+		t.Fatal("ranges must be served when If-Range matches", w.Code)
+	}
+}
@@ -0,0 +1,154 @@
+package httpx
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type corsOptions struct {
+	origins          []string
+	methods          []string
+	headers          []string
+	exposedHeaders   []string
+	allowCredentials bool
+	maxAge           time.Duration
+}
+
+// CORSOption configures the CORS middleware
+type CORSOption func(opts *corsOptions)
+
+// CORSOrigins sets the allowed origins. "*" allows all of them and "https://*.example.com" all the subdomains of example.com
+func CORSOrigins(origins ...string) CORSOption {
+	return func(opts *corsOptions) {
+		opts.origins = origins
+	}
+}
+
+// CORSMethods sets the allowed methods. GET, HEAD and POST are allowed by default
+func CORSMethods(methods ...string) CORSOption {
+	return func(opts *corsOptions) {
+		opts.methods = methods
+	}
+}
+
+// CORSHeaders sets the allowed request headers. "*" allows the headers requested by preflight requests
+func CORSHeaders(headers ...string) CORSOption {
+	return func(opts *corsOptions) {
+		opts.headers = headers
+	}
+}
+
+// CORSExposedHeaders sets the response headers readable by scripts
+func CORSExposedHeaders(headers ...string) CORSOption {
+	return func(opts *corsOptions) {
+		opts.exposedHeaders = headers
+	}
+}
+
+// CORSCredentials allows requests with cookies and authorization headers. It cannot be combined with the "*" origin
+func CORSCredentials(allow bool) CORSOption {
+	return func(opts *corsOptions) {
+		opts.allowCredentials = allow
+	}
+}
+
+// CORSMaxAge sets how long browsers can cache preflight responses
+func CORSMaxAge(maxAge time.Duration) CORSOption {
+	return func(opts *corsOptions) {
+		opts.maxAge = maxAge
+	}
+}
+
+func (opts *corsOptions) allowsOrigin(origin string) bool {
+	for _, allowed := range opts.origins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+
+		if i := strings.Index(allowed, "*."); i >= 0 {
+			prefix, suffix := allowed[:i], allowed[i+1:]
+			if strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) && len(origin) > len(prefix)+len(suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (opts *corsOptions) allowsMethod(method string) bool {
+	for _, m := range opts.methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// CORS answers preflight requests and sets the CORS headers of responses to allowed origins
+func CORS(opts ...CORSOption) func(http.Handler) http.Handler {
+	options := &corsOptions{
+		methods: []string{http.MethodGet, http.MethodHead, http.MethodPost},
+		headers: []string{"Accept", "Accept-Language", "Content-Language", "Content-Type", "Authorization"},
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.allowCredentials && options.allowsOrigin("*") {
+		// any site could send credentialed requests and read the responses
+		panic("httpx.CORS: credentials cannot be allowed for all origins, list them explicitly")
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			h := w.Header()
+			h.Add("Vary", "Origin")
+
+			if origin == "" || !options.allowsOrigin(origin) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !options.allowsOrigin("*") {
+				h.Set("Access-Control-Allow-Origin", origin)
+			} else {
+				h.Set("Access-Control-Allow-Origin", "*")
+			}
+			if options.allowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			requestedMethod := r.Header.Get("Access-Control-Request-Method")
+			if r.Method != http.MethodOptions || requestedMethod == "" {
+				if len(options.exposedHeaders) > 0 {
+					h.Set("Access-Control-Expose-Headers", strings.Join(options.exposedHeaders, ", "))
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// preflight request
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			if !options.allowsMethod(requestedMethod) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			h.Set("Access-Control-Allow-Methods", strings.Join(options.methods, ", "))
+			if len(options.headers) == 1 && options.headers[0] == "*" {
+				if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+					h.Set("Access-Control-Allow-Headers", requested)
+				}
+			} else if len(options.headers) > 0 {
+				h.Set("Access-Control-Allow-Headers", strings.Join(options.headers, ", "))
+			}
+			if options.maxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(int(options.maxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
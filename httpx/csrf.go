@@ -0,0 +1,211 @@
+package httpx
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/omecodes/common/errors"
+	"github.com/omecodes/common/session"
+	"github.com/omecodes/common/utils/jcon"
+)
+
+const (
+	ctxCSRFToken = jcon.String("csrf_token")
+
+	csrfSessionKey = "csrf_token"
+	csrfTokenSize  = 32
+)
+
+type csrfOptions struct {
+	cookieName     string
+	headerName     string
+	fieldName      string
+	cookiePath     string
+	trustedOrigins []string
+	exempt         func(r *http.Request) bool
+}
+
+// CSRFOption configures the CSRF middleware
+type CSRFOption func(opts *csrfOptions)
+
+// CSRFCookieName sets the name of the cookie that mirrors the token. Default is "csrf_token"
+func CSRFCookieName(name string) CSRFOption {
+	return func(opts *csrfOptions) {
+		opts.cookieName = name
+	}
+}
+
+// CSRFHeaderName sets the request header that carries the token. Default is "X-CSRF-Token"
+func CSRFHeaderName(name string) CSRFOption {
+	return func(opts *csrfOptions) {
+		opts.headerName = name
+	}
+}
+
+// CSRFFieldName sets the form field that carries the token when the header is not set. Default is "csrf_token"
+func CSRFFieldName(name string) CSRFOption {
+	return func(opts *csrfOptions) {
+		opts.fieldName = name
+	}
+}
+
+// CSRFCookiePath sets the path of the token cookie. Default is "/"
+func CSRFCookiePath(path string) CSRFOption {
+	return func(opts *csrfOptions) {
+		opts.cookiePath = path
+	}
+}
+
+// CSRFTrustedOrigins adds origins, other than the request host, allowed to send unsafe requests
+func CSRFTrustedOrigins(origins ...string) CSRFOption {
+	return func(opts *csrfOptions) {
+		opts.trustedOrigins = append(opts.trustedOrigins, origins...)
+	}
+}
+
+// CSRFExempt sets a function that tells which requests are not checked, like API calls authenticated with a bearer token
+func CSRFExempt(exempt func(r *http.Request) bool) CSRFOption {
+	return func(opts *csrfOptions) {
+		opts.exempt = exempt
+	}
+}
+
+// CSRFToken returns the CSRF token of the request, to be rendered in forms or templates
+func CSRFToken(r *http.Request) string {
+	token, _ := r.Context().Value(ctxCSRFToken).(string)
+	return token
+}
+
+// CSRF protects handlers against cross-site request forgery with double-submit cookies. A random token is stored in
+// the session of manager and mirrored in a cookie readable by scripts. Requests with unsafe methods must send the
+// cookie and the same token in the header or form field, otherwise they are rejected with 403.
+// See OpenSession for how the session is shared with the Sessions middleware
+func CSRF(manager *session.Manager, opts ...CSRFOption) func(http.Handler) http.Handler {
+	options := &csrfOptions{
+		cookieName: "csrf_token",
+		headerName: "X-CSRF-Token",
+		fieldName:  "csrf_token",
+		cookiePath: "/",
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s, err := OpenSession(manager, w, r)
+			if err != nil {
+				WriteLocalizedError(w, r, err)
+				return
+			}
+			token := s.GetString(csrfSessionKey)
+
+			var cookieToken string
+			if cookie, err := r.Cookie(options.cookieName); err == nil {
+				cookieToken = cookie.Value
+			}
+
+			if token == "" {
+				token, err = newCSRFToken()
+				if err != nil {
+					WriteError(w, errors.Wrap(errors.Internal, err, "could not generate csrf token"))
+					return
+				}
+				s.Set(csrfSessionKey, token)
+				if err = s.Save(); err != nil {
+					WriteError(w, errors.Wrap(errors.Internal, err, "could not save csrf session"))
+					return
+				}
+			}
+
+			if cookieToken != token {
+				http.SetCookie(w, &http.Cookie{
+					Name:     options.cookieName,
+					Value:    token,
+					Path:     options.cookiePath,
+					Secure:   r.TLS != nil,
+					SameSite: http.SameSiteLaxMode,
+				})
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), ctxCSRFToken, token))
+
+			if isSafeMethod(r.Method) || (options.exempt != nil && options.exempt(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !options.trustsOrigin(r) {
+				WriteError(w, errors.Create(errors.Forbidden, "csrf origin not allowed"))
+				return
+			}
+
+			submitted := r.Header.Get(options.headerName)
+			if submitted == "" {
+				submitted = r.PostFormValue(options.fieldName)
+			}
+
+			if !sameToken(cookieToken, token) || !sameToken(submitted, token) {
+				WriteError(w, errors.Create(errors.Forbidden, "csrf token mismatch"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// trustsOrigin checks the Origin header, or the Referer header when it is missing
+func (opts *csrfOptions) trustsOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		referer := r.Header.Get("Referer")
+		if referer == "" {
+			return true
+		}
+		u, err := url.Parse(referer)
+		if err != nil {
+			return false
+		}
+		origin = u.Scheme + "://" + u.Host
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, trusted := range opts.trustedOrigins {
+		if strings.EqualFold(trusted, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+func sameToken(a, b string) bool {
+	return a != "" && subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func newCSRFToken() (string, error) {
+	b := make([]byte, csrfTokenSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
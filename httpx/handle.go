@@ -0,0 +1,227 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/omecodes/common/session"
+	"github.com/omecodes/common/utils/jcon"
+)
+
+const (
+	ctxRequest = jcon.String("request")
+	ctxSession = jcon.String("session")
+
+	ctxSessionManager = jcon.String("session_manager")
+)
+
+// StatusCoder is implemented by responses that set their status code
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// RequestFromContext returns the request of handlers adapted with Handle
+func RequestFromContext(ctx context.Context) *http.Request {
+	r, _ := ctx.Value(ctxRequest).(*http.Request)
+	return r
+}
+
+type handleOptions struct {
+	bindOptions []BindOption
+	manager     *session.Manager
+	status      int
+}
+
+// HandleOption configures handlers adapted with Handle
+type HandleOption func(opts *handleOptions)
+
+// HandleBindOptions sets the options used to bind requests
+func HandleBindOptions(opts ...BindOption) HandleOption {
+	return func(o *handleOptions) {
+		o.bindOptions = append(o.bindOptions, opts...)
+	}
+}
+
+// HandleSession loads the session of manager into the handler context, as the Sessions middleware does.
+// See SessionFromContext
+func HandleSession(manager *session.Manager) HandleOption {
+	return func(opts *handleOptions) {
+		opts.manager = manager
+	}
+}
+
+// HandleStatus sets the status of successful responses. Default is 200, or 204 when the response is nil
+func HandleStatus(status int) HandleOption {
+	return func(opts *handleOptions) {
+		opts.status = status
+	}
+}
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Handle adapts fn to an http.HandlerFunc. fn must be one of
+//
+//	func(ctx context.Context, req Req) (Resp, error)
+//	func(ctx context.Context, req Req) error
+//	func(ctx context.Context) (Resp, error)
+//	func(ctx context.Context) error
+//
+// where Req is a struct or a pointer to a struct bound with Bind. The context holds the response writer under
+// CtxResponseWriter, the request and the session if any. Errors are written with WriteLocalizedError and
+// responses with Respond, in the encoding negotiated from the Accept header. Handle panics if fn does not
+// have a supported signature
+func Handle(fn interface{}, opts ...HandleOption) http.HandlerFunc {
+	fv := reflect.ValueOf(fn)
+	reqType, hasResponse := handlerSignature(fv.Type())
+
+	options := handleOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		ctx := handlerContext(w, r)
+
+		args := []reflect.Value{reflect.ValueOf(ctx)}
+		if reqType != nil {
+			req, err := bindReflected(r, reqType, options.bindOptions)
+			if err != nil {
+				WriteLocalizedError(w, r, err)
+				return
+			}
+			args = append(args, req)
+		}
+
+		out := fv.Call(args)
+
+		var rsp interface{}
+		if hasResponse {
+			if !isNilValue(out[0]) {
+				rsp = out[0].Interface()
+			}
+			out = out[1:]
+		}
+
+		var err error
+		if !out[0].IsNil() {
+			err = out[0].Interface().(error)
+		}
+		writeHandled(w, r, &options, rsp, err)
+	}
+	return withSession(handler, &options)
+}
+
+// withSession loads the session of the HandleSession manager around handler
+func withSession(handler http.HandlerFunc, options *handleOptions) http.HandlerFunc {
+	if options.manager == nil {
+		return handler
+	}
+	return Sessions(options.manager)(handler).ServeHTTP
+}
+
+// HandleRoute creates a route served by Handle(fn). Its request and response types are documented
+func HandleRoute(name, method, pattern string, fn interface{}, opts ...HandleOption) Route {
+	route := Route{
+		Name:        name,
+		Method:      []string{method},
+		Pattern:     pattern,
+		HandlerFunc: Handle(fn, opts...),
+	}
+
+	t := reflect.TypeOf(fn)
+	if t.NumIn() == 2 && method != http.MethodGet && method != http.MethodHead && method != http.MethodDelete {
+		route.Request = reflect.Zero(t.In(1)).Interface()
+		if t.In(1).Kind() == reflect.Ptr {
+			route.Request = reflect.New(t.In(1).Elem()).Interface()
+		}
+	}
+	if t.NumOut() == 2 {
+		rspType := t.Out(0)
+		if rspType.Kind() == reflect.Ptr {
+			route.Response = reflect.New(rspType.Elem()).Interface()
+		} else if rspType.Kind() != reflect.Interface {
+			route.Response = reflect.Zero(rspType).Interface()
+		}
+	}
+	return route
+}
+
+// handlerSignature checks the signature of t and returns the request type and whether it returns a response
+func handlerSignature(t reflect.Type) (reflect.Type, bool) {
+	if t.Kind() != reflect.Func {
+		panic(fmt.Sprintf("httpx.Handle: %s is not a function", t))
+	}
+	if t.NumIn() < 1 || t.NumIn() > 2 || t.In(0) != contextType {
+		panic(fmt.Sprintf("httpx.Handle: %s must take a context.Context and an optional request", t))
+	}
+	if t.NumOut() < 1 || t.NumOut() > 2 || t.Out(t.NumOut()-1) != errorType {
+		panic(fmt.Sprintf("httpx.Handle: %s must return an optional response and an error", t))
+	}
+
+	var reqType reflect.Type
+	if t.NumIn() == 2 {
+		reqType = t.In(1)
+		structType := reqType
+		if structType.Kind() == reflect.Ptr {
+			structType = structType.Elem()
+		}
+		if structType.Kind() != reflect.Struct {
+			panic(fmt.Sprintf("httpx.Handle: request type %s must be a struct or a pointer to a struct", reqType))
+		}
+	}
+	return reqType, t.NumOut() == 2
+}
+
+func bindReflected(r *http.Request, reqType reflect.Type, opts []BindOption) (reflect.Value, error) {
+	if reqType.Kind() == reflect.Ptr {
+		req := reflect.New(reqType.Elem())
+		return req, Bind(r, req.Interface(), opts...)
+	}
+	req := reflect.New(reqType)
+	return req.Elem(), Bind(r, req.Interface(), opts...)
+}
+
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
+
+// handlerContext returns the request context with the response writer and the request
+func handlerContext(w http.ResponseWriter, r *http.Request) context.Context {
+	ctx := context.WithValue(r.Context(), CtxResponseWriter, w)
+	return context.WithValue(ctx, ctxRequest, r)
+}
+
+// writeHandled writes the outcome of an adapted handler
+func writeHandled(w http.ResponseWriter, r *http.Request, options *handleOptions, rsp interface{}, err error) {
+	if err != nil {
+		WriteLocalizedError(w, r, err)
+		return
+	}
+
+	status := options.status
+	if coder, ok := rsp.(StatusCoder); ok {
+		status = coder.StatusCode()
+	}
+	if status == 0 {
+		status = http.StatusOK
+		if rsp == nil {
+			status = http.StatusNoContent
+		}
+	}
+
+	if rsp == nil {
+		w.WriteHeader(status)
+		return
+	}
+	Respond(w, r, status, rsp)
+}
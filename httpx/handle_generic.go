@@ -0,0 +1,50 @@
+//go:build generics && go1.18
+// +build generics,go1.18
+
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// HandleTyped is the type safe version of Handle, for func(ctx context.Context, req Req) (Resp, error) handlers.
+// Req must be a struct type. It requires go 1.18 and the "generics" build tag
+func HandleTyped[Req any, Resp any](fn func(ctx context.Context, req Req) (Resp, error), opts ...HandleOption) http.HandlerFunc {
+	if t := reflect.TypeOf((*Req)(nil)).Elem(); t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("httpx.HandleTyped: request type %s must be a struct", t))
+	}
+
+	options := handleOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		ctx := handlerContext(w, r)
+
+		var req Req
+		if err := Bind(r, &req, options.bindOptions...); err != nil {
+			WriteLocalizedError(w, r, err)
+			return
+		}
+
+		rsp, err := fn(ctx, req)
+
+		var data interface{}
+		if !isNilValue(reflect.ValueOf(&rsp).Elem()) {
+			data = rsp
+		}
+		writeHandled(w, r, &options, data, err)
+	}
+	return withSession(handler, &options)
+}
+
+// HandleTypedRoute creates a route served by HandleTyped(fn). Its request and response types are documented
+func HandleTypedRoute[Req any, Resp any](name, method, pattern string, fn func(ctx context.Context, req Req) (Resp, error), opts ...HandleOption) Route {
+	route := HandleRoute(name, method, pattern, fn, opts...)
+	route.HandlerFunc = HandleTyped(fn, opts...)
+	return route
+}
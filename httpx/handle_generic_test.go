@@ -0,0 +1,51 @@
+//go:build generics && go1.18
+// +build generics,go1.18
+
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/omecodes/common/errors"
+	"github.com/omecodes/common/session"
+)
+
+func TestHandleTyped(t *testing.T) {
+	get := HandleTyped(func(ctx context.Context, req getItemRequest) (*item, error) {
+		if req.ID == 404 {
+			return nil, errors.NotFound
+		}
+		return &item{ID: req.ID}, nil
+	})
+
+	w := serveHandled(get, http.MethodGet, "/items/12", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"id":12`) {
+		t.Fatal("unexpected response", w.Code, w.Body.String())
+	}
+	if w = serveHandled(get, http.MethodGet, "/items/404", ""); w.Code != http.StatusNotFound {
+		t.Fatal("errors must be mapped to statuses", w.Code)
+	}
+}
+
+func TestHandleTypedSession(t *testing.T) {
+	manager := session.NewManager(session.NewCookieStore("session", []byte("0123456789abcdef0123456789abcdef")))
+	get := HandleTyped(func(ctx context.Context, req getItemRequest) (*item, error) {
+		s := SessionFromContext(ctx)
+		if s == nil {
+			return nil, errors.Internal
+		}
+		s.Set("item", req.ID)
+		return &item{ID: req.ID}, nil
+	}, HandleSession(manager))
+
+	w := serveHandled(get, http.MethodGet, "/items/12", "")
+	if w.Code != http.StatusOK {
+		t.Fatal("session must be loaded", w.Code, w.Body.String())
+	}
+	if cookies := w.Result().Cookies(); len(cookies) != 1 || cookies[0].Name != "session" {
+		t.Fatal("changed session must be saved", cookies)
+	}
+}
@@ -0,0 +1,103 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/omecodes/common/errors"
+)
+
+type getItemRequest struct {
+	ID int64 `path:"id" validate:"min=1"`
+}
+
+type created struct {
+	*item
+}
+
+func (created) StatusCode() int {
+	return http.StatusCreated
+}
+
+func serveHandled(handler http.HandlerFunc, method, uri, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, uri, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Accept", "application/json")
+	if i := strings.LastIndex(uri, "/"); i >= 0 {
+		r = mux.SetURLVars(r, map[string]string{"id": uri[i+1:]})
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+func TestHandle(t *testing.T) {
+	get := Handle(func(ctx context.Context, req getItemRequest) (*item, error) {
+		if ctx.Value(CtxResponseWriter) == nil || RequestFromContext(ctx) == nil {
+			t.Fatal("context must hold the response writer and the request")
+		}
+		if req.ID == 404 {
+			return nil, errors.NotFound
+		}
+		return &item{ID: req.ID, Name: "item"}, nil
+	})
+
+	w := serveHandled(get, http.MethodGet, "/items/12", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"id":12`) {
+		t.Fatal("unexpected response", w.Code, w.Body.String())
+	}
+
+	if w = serveHandled(get, http.MethodGet, "/items/404", ""); w.Code != http.StatusNotFound {
+		t.Fatal("errors must be mapped to statuses", w.Code)
+	}
+	if w = serveHandled(get, http.MethodGet, "/items/0", ""); w.Code != http.StatusBadRequest {
+		t.Fatal("invalid requests must be rejected", w.Code)
+	}
+
+	create := Handle(func(ctx context.Context, req *item) (created, error) {
+		return created{req}, nil
+	})
+	if w = serveHandled(create, http.MethodPost, "/items", `{"id":3,"name":"new"}`); w.Code != http.StatusCreated ||
+		!strings.Contains(w.Body.String(), `"name":"new"`) {
+		t.Fatal("responses must set their status", w.Code, w.Body.String())
+	}
+
+	remove := Handle(func(ctx context.Context) error { return nil })
+	if w = serveHandled(remove, http.MethodDelete, "/items/3", ""); w.Code != http.StatusNoContent {
+		t.Fatal("handlers without response must answer 204", w.Code)
+	}
+}
+
+func TestHandleRejectsInvalidSignatures(t *testing.T) {
+	for _, fn := range []interface{}{
+		func() error { return nil },
+		func(ctx context.Context, id int) error { return nil },
+		func(ctx context.Context) *item { return nil },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%T must be rejected", fn)
+				}
+			}()
+			Handle(fn)
+		}()
+	}
+}
+
+func TestHandleRoute(t *testing.T) {
+	route := HandleRoute("create-item", http.MethodPost, "/items", func(ctx context.Context, req *item) (*item, error) {
+		return req, nil
+	})
+	if _, ok := route.Request.(*item); !ok {
+		t.Fatal("request type must be documented", route.Request)
+	}
+	if _, ok := route.Response.(*item); !ok {
+		t.Fatal("response type must be documented", route.Response)
+	}
+}
@@ -1,9 +1,9 @@
 package httpx
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/omecodes/common/errors"
+	"github.com/omecodes/common/utils/codec"
 	"github.com/omecodes/common/utils/jcon"
 	"io"
 	"log"
@@ -56,9 +56,9 @@ type (
 	HttpMiddleware func(handler http.HandlerFunc) http.HandlerFunc
 )
 
+// WriteError writes the RFC 7807 problem document of err. See WriteLocalizedError for translated messages
 func WriteError(w http.ResponseWriter, err error) {
-	status := errors.HttpStatus(err)
-	w.WriteHeader(status)
+	errors.ProblemOf(err).Write(w)
 }
 
 func WriteResponse(w http.ResponseWriter, status int, data interface{}, headers ...HttpHeader) {
@@ -105,18 +105,16 @@ func WriteResponse(w http.ResponseWriter, status int, data interface{}, headers
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-
-	bytes, _ := json.Marshal(data)
-	_, err := w.Write(bytes)
-	if err != nil {
-		log.Println("error wile writing http response content: ", err)
-	}
+	writeEncoded(w, status, "application/json", codec.Json, data)
 }
 
+// WriteJSON writes data encoded in JSON. Raw responses like []byte or io.Reader are written as they are
 func WriteJSON(w http.ResponseWriter, status int, data interface{}) {
-	WriteResponse(w, status, data, HttpHeader{Name: "Content-Type", Value: "application/json"})
+	if data == nil || isRawResponse(data) {
+		WriteResponse(w, status, data, HttpHeader{Name: "Content-Type", Value: "application/json"})
+		return
+	}
+	writeEncoded(w, status, "application/json", codec.Json, data)
 }
 
 func Redirect(w http.ResponseWriter, url *RedirectURL) {
@@ -136,18 +134,9 @@ func writeContent(c *Content, w http.ResponseWriter) {
 
 	switch d := c.Data.(type) {
 	case io.Reader:
-		done := false
-		buffer := make([]byte, 1024)
-		for !done {
-			n, err := d.Read(buffer)
-			done = err == io.EOF
-			if !done && err != nil {
-				return
-			}
-			_, err = w.Write(buffer[:n])
-			if err != nil {
-				log.Println("[xhttp]:\tcould not write response:", err)
-			}
+		_, err := io.Copy(w, d)
+		if err != nil {
+			log.Println("[xhttp]:\tcould not write response:", err)
 		}
 	case []byte:
 		_, err := w.Write(d)
@@ -163,18 +152,9 @@ func writeContent(c *Content, w http.ResponseWriter) {
 
 func WriteData(w http.ResponseWriter, status int, reader io.Reader) {
 	w.WriteHeader(status)
-	done := false
-	buffer := make([]byte, 1024)
-	for !done {
-		n, err := reader.Read(buffer)
-		done = err == io.EOF
-		if !done && err != nil {
-			return
-		}
-		_, err = w.Write(buffer[:n])
-		if err != nil {
-			log.Println("[xhttp]:\tcould not write response:", err)
-		}
+	_, err := io.Copy(w, reader)
+	if err != nil {
+		log.Println("[xhttp]:\tcould not write response:", err)
 	}
 }
 
@@ -185,18 +165,9 @@ func WriteContent(w http.ResponseWriter, contentType string, size int64, reader
 	}
 	w.Header().Set("Content-Length", fmt.Sprintf("%d", size))
 	w.WriteHeader(http.StatusOK)
-	done := false
-	buffer := make([]byte, 1024)
-	for !done {
-		n, err := reader.Read(buffer)
-		done = err == io.EOF
-		if !done && err != nil {
-			return
-		}
-		_, err = w.Write(buffer[:n])
-		if err != nil {
-			log.Println("[xhttp]:\tcould not write response:", err)
-		}
+	_, err := io.Copy(w, reader)
+	if err != nil {
+		log.Println("[xhttp]:\tcould not write response:", err)
 	}
 }
 
@@ -205,18 +176,9 @@ func writeResource(status int, resource *Resource, w http.ResponseWriter) {
 	w.WriteHeader(status)
 
 	if resource.Stream != nil {
-		done := false
-		buffer := make([]byte, 1024)
-		for !done {
-			n, err := resource.Stream.Read(buffer)
-			done = err == io.EOF
-			if !done && err != nil {
-				return
-			}
-			_, err = w.Write(buffer[:n])
-			if err != nil {
-				log.Println("[xhttp]:\tcould not write response:", err)
-			}
+		_, err := io.Copy(w, resource.Stream)
+		if err != nil {
+			log.Println("[xhttp]:\tcould not write response:", err)
 		}
 	} else if resource.BytesData != nil {
 		_, _ = w.Write(resource.BytesData)
@@ -0,0 +1,49 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/omecodes/common/errors"
+	"github.com/omecodes/common/utils/jcon"
+	"github.com/omecodes/common/utils/lang"
+)
+
+const ctxI18n = jcon.String("i18n")
+
+// ContextWithI18n returns a copy of ctx that holds the translations manager
+func ContextWithI18n(ctx context.Context, manager *lang.I18n) context.Context {
+	return context.WithValue(ctx, ctxI18n, manager)
+}
+
+// I18nFromContext returns the translations manager stored in ctx
+func I18nFromContext(ctx context.Context) *lang.I18n {
+	manager, _ := ctx.Value(ctxI18n).(*lang.I18n)
+	return manager
+}
+
+// I18n stores manager in requests context so that WriteLocalizedError can translate error messages
+func I18n(manager *lang.I18n) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(ContextWithI18n(r.Context(), manager)))
+		})
+	}
+}
+
+// WriteLocalizedError writes the problem document of err like WriteError, with the message translated in the
+// language that best matches the request Accept-Language header. Error codes are translated with keys like
+// "error.not_found" and errors.Failure messages with their Key and Args. The default language of the manager
+// is used when the key is not translated in the matched language
+func WriteLocalizedError(w http.ResponseWriter, r *http.Request, err error) {
+	manager := I18nFromContext(r.Context())
+	if manager == nil {
+		WriteError(w, err)
+		return
+	}
+
+	accept := r.Header.Get("Accept-Language")
+	errors.LocalizedProblemOf(err, func(key string, args ...interface{}) (string, bool) {
+		return manager.TranslationFromAcceptLanguageHeader(accept, key, args...)
+	}).Write(w)
+}
@@ -0,0 +1,47 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/omecodes/common/errors"
+	"github.com/omecodes/common/utils/lang"
+	"golang.org/x/text/language"
+)
+
+func TestWriteLocalizedError(t *testing.T) {
+	manager := lang.NewManager("")
+	manager.SetDefaultLanguage(language.English)
+	_ = manager.AddEntry(language.English, lang.Entry{Key: "error.not_found", Value: "Not found"})
+	_ = manager.AddEntry(language.English, lang.Entry{Key: "user.missing", Value: "User %s does not exist"})
+	_ = manager.AddEntry(language.French, lang.Entry{Key: "error.not_found", Value: "Introuvable"})
+
+	detail := func(err error) string {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Language", "fr-FR,fr;q=0.9")
+		r = r.WithContext(ContextWithI18n(r.Context(), manager))
+
+		w := httptest.NewRecorder()
+		WriteLocalizedError(w, r, err)
+
+		var p errors.Problem
+		if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+			t.Fatal(err)
+		}
+		return p.Detail
+	}
+
+	if d := detail(errors.NotFound); d != "Introuvable" {
+		t.Fatal("code messages must be translated", d)
+	}
+
+	if d := detail(errors.Create(errors.NotFound, "user missing").Localized("user.missing", "bob")); d != "User bob does not exist" {
+		t.Fatal("untranslated keys must fall back to the default language", d)
+	}
+
+	if d := detail(errors.Create(errors.Forbidden, "access denied")); d != "access denied" {
+		t.Fatal("messages without translation must be kept", d)
+	}
+}
@@ -2,6 +2,7 @@ package httpx
 
 import (
 	"context"
+	"github.com/omecodes/common/netx"
 	"github.com/omecodes/common/utils/log"
 	"net/http"
 	"time"
@@ -77,3 +78,19 @@ func (updater *contextUpdater) Handle(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// ClientCertificate stores the principal of the verified client certificate in the request context.
+// It can be retrieved with netx.PrincipalFromContext
+func ClientCertificate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p := PrincipalFromRequest(r); p != nil {
+			r = r.WithContext(netx.ContextWithPrincipal(r.Context(), p))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// PrincipalFromRequest returns the principal of the verified client certificate of the request connection
+func PrincipalFromRequest(r *http.Request) *netx.Principal {
+	return netx.PrincipalFromConnectionState(r.TLS)
+}
@@ -0,0 +1,252 @@
+package httpx
+
+import (
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/omecodes/common/errors"
+	"github.com/omecodes/common/utils/codec"
+)
+
+type mediaEncoder struct {
+	mediaType string
+	encoder   codec.Encoder
+}
+
+var (
+	encodersMutex sync.RWMutex
+	encoders      = []mediaEncoder{
+		{mediaType: "application/json", encoder: codec.Json},
+		{mediaType: "application/xml", encoder: codec.Xml},
+		{mediaType: "text/xml", encoder: codec.Xml},
+		{mediaType: "application/x-protobuf", encoder: codec.Protobuf},
+		{mediaType: "application/protobuf", encoder: codec.Protobuf},
+		{mediaType: "application/msgpack", encoder: codec.MsgPack},
+		{mediaType: "application/x-msgpack", encoder: codec.MsgPack},
+		{mediaType: "application/yaml", encoder: codec.Yaml},
+		{mediaType: "application/x-yaml", encoder: codec.Yaml},
+		{mediaType: "text/yaml", encoder: codec.Yaml},
+		{mediaType: "text/csv", encoder: codec.Csv},
+	}
+)
+
+// RegisterEncoder registers the encoder of responses of mediaType. It replaces the encoder already registered
+// for mediaType. Encoders implementing codec.StreamEncoder write to responses without buffering and those
+// implementing codec.Checker are only used for the values they support
+func RegisterEncoder(mediaType string, encoder codec.Encoder) {
+	encodersMutex.Lock()
+	defer encodersMutex.Unlock()
+
+	for i, e := range encoders {
+		if e.mediaType == mediaType {
+			encoders[i].encoder = encoder
+			return
+		}
+	}
+	encoders = append(encoders, mediaEncoder{mediaType: mediaType, encoder: encoder})
+}
+
+type acceptedRange struct {
+	mediaType string
+	quality   float64
+	order     int
+}
+
+func (a acceptedRange) specificity() int {
+	switch {
+	case a.mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(a.mediaType, "/*"):
+		return 1
+	default:
+		return 2
+	}
+}
+
+func (a acceptedRange) matches(mediaType string) bool {
+	if a.mediaType == "*/*" || a.mediaType == mediaType {
+		return true
+	}
+	return strings.HasSuffix(a.mediaType, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(a.mediaType, "*"))
+}
+
+func parseAccept(header string) []acceptedRange {
+	if strings.TrimSpace(header) == "" {
+		return []acceptedRange{{mediaType: "*/*", quality: 1}}
+	}
+
+	var ranges []acceptedRange
+	for i, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, found := params["q"]; found {
+			quality, err = strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, acceptedRange{mediaType: mediaType, quality: quality, order: i})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].quality != ranges[j].quality {
+			return ranges[i].quality > ranges[j].quality
+		}
+		return ranges[i].specificity() > ranges[j].specificity()
+	})
+	return ranges
+}
+
+// Negotiate returns the media type and the encoder of data that best match the accept header value
+func Negotiate(accept string, data interface{}) (string, codec.Encoder, bool) {
+	ranges := parseAccept(accept)
+
+	encodersMutex.RLock()
+	defer encodersMutex.RUnlock()
+
+	// media types explicitly refused with q=0
+	refused := map[string]bool{}
+	for _, r := range ranges {
+		if r.quality == 0 && r.specificity() == 2 {
+			refused[r.mediaType] = true
+		}
+	}
+
+	for _, r := range ranges {
+		if r.quality <= 0 {
+			continue
+		}
+
+		for _, e := range encoders {
+			if refused[e.mediaType] || !r.matches(e.mediaType) {
+				continue
+			}
+			if checker, ok := e.encoder.(codec.Checker); ok && !checker.Supports(data) {
+				continue
+			}
+			return e.mediaType, e.encoder, true
+		}
+	}
+	return "", nil, false
+}
+
+// Respond writes data like WriteResponse, encoded in the format that best matches the request Accept header.
+// It responds with 406 Not Acceptable when none of the registered encoders can be used
+func Respond(w http.ResponseWriter, r *http.Request, status int, data interface{}, headers ...HttpHeader) {
+	if data == nil || isRawResponse(data) {
+		WriteResponse(w, status, data, headers...)
+		return
+	}
+
+	for _, h := range headers {
+		w.Header().Set(h.Name, h.Value)
+	}
+
+	mediaType, encoder, ok := Negotiate(r.Header.Get("Accept"), data)
+	if !ok {
+		writeNotAcceptable(w, data)
+		return
+	}
+
+	w.Header().Add("Vary", "Accept")
+	writeEncoded(w, status, mediaType, encoder, data)
+}
+
+func isRawResponse(data interface{}) bool {
+	switch data.(type) {
+	case *Content, io.Reader, []byte, *Resource, *RedirectURL, *RequireAuth:
+		return true
+	default:
+		return false
+	}
+}
+
+func writeNotAcceptable(w http.ResponseWriter, data interface{}) {
+	encodersMutex.RLock()
+	var available []string
+	for _, e := range encoders {
+		if checker, ok := e.encoder.(codec.Checker); ok && !checker.Supports(data) {
+			continue
+		}
+		available = append(available, e.mediaType)
+	}
+	encodersMutex.RUnlock()
+
+	writeProblem(w, http.StatusNotAcceptable, "not acceptable", "available media types: "+strings.Join(available, ", "))
+}
+
+// writeProblem writes a problem document for HTTP statuses that have no errors code
+func writeProblem(w http.ResponseWriter, status int, code, detail string) {
+	(&errors.Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}).Write(w)
+}
+
+// writeEncoded encodes data with encoder. The status is only written once encoding started,
+// so that encoding errors detected before any byte is written are reported with a problem document
+func writeEncoded(w http.ResponseWriter, status int, mediaType string, encoder codec.Encoder, data interface{}) {
+	if streamEncoder, ok := encoder.(codec.StreamEncoder); ok {
+		lw := &lazyHeaderWriter{w: w, status: status, mediaType: mediaType}
+		err := streamEncoder.EncodeTo(lw, data)
+		if err == nil && !lw.wroteHeader {
+			lw.writeHeader()
+		}
+
+		if err != nil {
+			if !lw.wroteHeader {
+				WriteError(w, errors.Wrap(errors.Internal, err, ""))
+				return
+			}
+			log.Println("[xhttp]:\tcould not encode response:", err)
+		}
+		return
+	}
+
+	encoded, err := encoder.Encode(data)
+	if err != nil {
+		WriteError(w, errors.Wrap(errors.Internal, err, ""))
+		return
+	}
+
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(encoded)))
+	w.WriteHeader(status)
+	_, err = w.Write(encoded)
+	if err != nil {
+		log.Println("[xhttp]:\tcould not write response:", err)
+	}
+}
+
+type lazyHeaderWriter struct {
+	w           http.ResponseWriter
+	status      int
+	mediaType   string
+	wroteHeader bool
+}
+
+func (lw *lazyHeaderWriter) writeHeader() {
+	lw.wroteHeader = true
+	lw.w.Header().Set("Content-Type", lw.mediaType)
+	lw.w.WriteHeader(lw.status)
+}
+
+func (lw *lazyHeaderWriter) Write(b []byte) (int, error) {
+	if !lw.wroteHeader {
+		lw.writeHeader()
+	}
+	return lw.w.Write(b)
+}
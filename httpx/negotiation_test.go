@@ -0,0 +1,77 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type negotiationItem struct {
+	Name  string `json:"name" xml:"name"`
+	Count int    `json:"count" xml:"count"`
+}
+
+func respond(accept string, data interface{}) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	Respond(w, r, http.StatusOK, data)
+	return w
+}
+
+func TestRespondNegotiation(t *testing.T) {
+	items := []negotiationItem{{Name: "a", Count: 1}, {Name: "b", Count: 2}}
+
+	w := respond("", items)
+	if w.Header().Get("Content-Type") != "application/json" {
+		t.Fatal("JSON must be the default", w.Header().Get("Content-Type"))
+	}
+
+	w = respond("text/html;q=0.9, text/csv", items)
+	if w.Header().Get("Content-Type") != "text/csv" || w.Body.String() != "name,count\na,1\nb,2\n" {
+		t.Fatal("unexpected CSV response", w.Header().Get("Content-Type"), w.Body.String())
+	}
+
+	w = respond("application/yaml;q=0.5, application/xml", items[0])
+	if w.Header().Get("Content-Type") != "application/xml" || !strings.Contains(w.Body.String(), "<name>a</name>") {
+		t.Fatal("unexpected XML response", w.Body.String())
+	}
+
+	w = respond("text/*", items[0])
+	if w.Header().Get("Content-Type") != "text/xml" {
+		t.Fatal("wildcards must match", w.Header().Get("Content-Type"))
+	}
+}
+
+func TestRespondNotAcceptable(t *testing.T) {
+	w := respond("application/x-protobuf", negotiationItem{Name: "a"})
+	if w.Code != http.StatusNotAcceptable {
+		t.Fatal("values not supported by the accepted encoders must be refused", w.Code)
+	}
+
+	w = respond("*/*, application/json;q=0", negotiationItem{Name: "a"})
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") == "application/json" {
+		t.Fatal("refused media types must not be used", w.Header().Get("Content-Type"))
+	}
+}
+
+func TestEncodingErrors(t *testing.T) {
+	w := respond("application/json", map[string]interface{}{"f": func() {}})
+	if w.Code != http.StatusInternalServerError {
+		t.Fatal("encoding errors must be reported", w.Code)
+	}
+
+	// encoding/xml cannot encode maps
+	w = respond("application/xml", map[string]interface{}{"name": "a"})
+	if w.Code != http.StatusNotAcceptable {
+		t.Fatal("maps must not be encoded in XML", w.Code)
+	}
+
+	w = respond("application/xml", struct{ F func() }{})
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "<?xml") {
+		t.Fatal("XML encoding errors must be reported before writing", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,298 @@
+package httpx
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/omecodes/common/errors"
+)
+
+// APIInfo describes the API in the OpenAPI documents served by NewRouter
+var APIInfo = OpenAPIInfo{Title: "API", Version: "1.0.0"}
+
+// OpenAPIInfo is the info object of an OpenAPI document
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// SecurityScheme documents an authentication scheme, like {Type: "http", Scheme: "bearer"}
+// or {Type: "apiKey", In: "header", Name: "X-Api-Key"}
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Description  string `json:"description,omitempty"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// OpenAPI is an OpenAPI 3 document
+type OpenAPI struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       OpenAPIInfo                      `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components *Components                      `json:"components,omitempty"`
+}
+
+// Components holds the security schemes of an OpenAPI document
+type Components struct {
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// Operation documents a route method
+type Operation struct {
+	OperationID string                `json:"operationId,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter documents a route param
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Required    bool    `json:"required,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody documents a request body
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response documents a response
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is a JSON schema
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+var pathVariable = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// OpenAPIDocument generates the OpenAPI document of routes. Prefix routes are not documented
+// and routes without methods are documented as GET operations
+func OpenAPIDocument(info OpenAPIInfo, routes ...Route) *OpenAPI {
+	doc := &OpenAPI{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Paths:   map[string]map[string]*Operation{},
+	}
+
+	errorResponse := &Response{
+		Description: "Error",
+		Content:     map[string]MediaType{errors.ProblemContentType: {Schema: SchemaOf(errors.Problem{})}},
+	}
+
+	for _, route := range routes {
+		if route.PathIsPrefix || route.Pattern == "" {
+			continue
+		}
+
+		path := pathVariable.ReplaceAllString(route.Pattern, "{$1}")
+		operations := doc.Paths[path]
+		if operations == nil {
+			operations = map[string]*Operation{}
+			doc.Paths[path] = operations
+		}
+
+		methods := route.Method
+		if len(methods) == 0 {
+			methods = []string{http.MethodGet}
+		}
+
+		for _, method := range methods {
+			op := &Operation{
+				OperationID: route.Name,
+				Summary:     route.Summary,
+				Description: route.Description,
+				Tags:        route.Tags,
+				Deprecated:  route.Deprecated,
+				Parameters:  documentedParams(route),
+				Responses:   map[string]*Response{"default": errorResponse},
+			}
+			if len(methods) > 1 && op.OperationID != "" {
+				op.OperationID += "_" + strings.ToLower(method)
+			}
+
+			if route.Request != nil {
+				op.RequestBody = &RequestBody{
+					Required: true,
+					Content:  map[string]MediaType{"application/json": {Schema: SchemaOf(route.Request)}},
+				}
+			}
+
+			ok := &Response{Description: http.StatusText(http.StatusOK)}
+			if route.Response != nil {
+				ok.Content = map[string]MediaType{"application/json": {Schema: SchemaOf(route.Response)}}
+			}
+			op.Responses["200"] = ok
+
+			for _, name := range route.Auth {
+				s, found := getAuthScheme(name)
+				if !found {
+					continue
+				}
+				if doc.Components == nil {
+					doc.Components = &Components{SecuritySchemes: map[string]SecurityScheme{}}
+				}
+				doc.Components.SecuritySchemes[name] = s.scheme
+				op.Security = append(op.Security, map[string][]string{name: {}})
+			}
+
+			operations[strings.ToLower(method)] = op
+		}
+	}
+	return doc
+}
+
+// documentedParams returns the declared params of route and its undeclared path variables as strings
+func documentedParams(route Route) []*Parameter {
+	var params []*Parameter
+	declared := map[string]bool{}
+	for _, p := range route.Params {
+		if p.In == InPath {
+			declared[p.Name] = true
+		}
+		typ := p.Type
+		if typ == "" {
+			typ = TypeString
+		}
+		params = append(params, &Parameter{
+			Name:        p.Name,
+			In:          p.In,
+			Required:    p.Required || p.In == InPath,
+			Description: p.Description,
+			Schema:      &Schema{Type: typ},
+		})
+	}
+
+	for _, match := range pathVariable.FindAllStringSubmatch(route.Pattern, -1) {
+		if !declared[match[1]] {
+			params = append(params, &Parameter{Name: match[1], In: InPath, Required: true, Schema: &Schema{Type: TypeString}})
+		}
+	}
+	return params
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// SchemaOf returns the JSON schema of the type of v, following the json tags of struct fields
+func SchemaOf(v interface{}) *Schema {
+	if v == nil {
+		return &Schema{}
+	}
+	return schemaOf(reflect.TypeOf(v), map[reflect.Type]bool{})
+}
+
+func schemaOf(t reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: TypeBoolean}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: TypeInteger, Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: TypeInteger, Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: TypeNumber, Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: TypeNumber, Format: "double"}
+	case reflect.String:
+		return &Schema{Type: TypeString}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: TypeString, Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: schemaOf(t.Elem(), visiting)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(t.Elem(), visiting)}
+	case reflect.Struct:
+		if visiting[t] {
+			return &Schema{Type: "object"}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		addProperties(s, t, visiting)
+		return s
+	default:
+		return &Schema{}
+	}
+}
+
+func addProperties(s *Schema, t reflect.Type, visiting map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			addProperties(s, fieldType, visiting)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		s.Properties[name] = schemaOf(field.Type, visiting)
+
+		omitEmpty := false
+		for _, option := range parts[1:] {
+			if option == "omitempty" {
+				omitEmpty = true
+			}
+		}
+		if !omitEmpty && field.Type.Kind() != reflect.Ptr {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/omecodes/common/errors"
+	"github.com/omecodes/common/utils/jcon"
+)
+
+// Param locations
+const (
+	InPath   = "path"
+	InQuery  = "query"
+	InHeader = "header"
+)
+
+// Param types
+const (
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+)
+
+const ctxParams = jcon.String("params")
+
+// Param declares a typed path, query or header parameter of a route
+type Param struct {
+	Name        string
+	In          string
+	Type        string
+	Required    bool
+	Description string
+}
+
+// PathParam declares a path variable of type typ
+func PathParam(name, typ string) Param {
+	return Param{Name: name, In: InPath, Type: typ, Required: true}
+}
+
+// QueryParam declares a query parameter of type typ
+func QueryParam(name, typ string, required bool) Param {
+	return Param{Name: name, In: InQuery, Type: typ, Required: required}
+}
+
+// HeaderParam declares a header of type typ
+func HeaderParam(name, typ string, required bool) Param {
+	return Param{Name: name, In: InHeader, Type: typ, Required: required}
+}
+
+func (p Param) raw(r *http.Request) (string, bool) {
+	switch p.In {
+	case InPath:
+		value, found := mux.Vars(r)[p.Name]
+		return value, found && value != ""
+	case InHeader:
+		value := r.Header.Get(p.Name)
+		return value, value != ""
+	default:
+		values, found := r.URL.Query()[p.Name]
+		if !found || len(values) == 0 || values[0] == "" {
+			return "", false
+		}
+		return values[0], true
+	}
+}
+
+func (p Param) parse(value string) (interface{}, error) {
+	switch p.Type {
+	case TypeInteger:
+		return strconv.ParseInt(value, 10, 64)
+	case TypeNumber:
+		return strconv.ParseFloat(value, 64)
+	case TypeBoolean:
+		return strconv.ParseBool(value)
+	default:
+		return value, nil
+	}
+}
+
+// validatedParams parses params and stores their values in the request context.
+// Missing and malformed params are reported together in a BadInput failure
+func validatedParams(params []Param, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		values := map[string]interface{}{}
+		var failure *errors.Failure
+
+		for _, p := range params {
+			raw, found := p.raw(r)
+			if !found {
+				if p.Required {
+					failure = violation(failure, p.Name, "is required")
+				}
+				continue
+			}
+
+			value, err := p.parse(raw)
+			if err != nil {
+				failure = violation(failure, p.Name, "must be a valid "+p.Type)
+				continue
+			}
+			values[p.Name] = value
+		}
+
+		if failure != nil {
+			WriteError(w, failure)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), ctxParams, values)))
+	}
+}
+
+func violation(failure *errors.Failure, field, description string) *errors.Failure {
+	if failure == nil {
+		return errors.Invalid(field, description)
+	}
+	return failure.WithViolation(field, description)
+}
+
+// ParamValue returns the parsed value of the declared param name. It is an int64, a float64, a bool or a string
+// depending on the param type, and nil if the param was not sent
+func ParamValue(r *http.Request, name string) interface{} {
+	values, _ := r.Context().Value(ctxParams).(map[string]interface{})
+	return values[name]
+}
+
+// StringParam returns the value of the declared string param name
+func StringParam(r *http.Request, name string) string {
+	value, _ := ParamValue(r, name).(string)
+	return value
+}
+
+// IntParam returns the value of the declared integer param name
+func IntParam(r *http.Request, name string) int64 {
+	value, _ := ParamValue(r, name).(int64)
+	return value
+}
+
+// FloatParam returns the value of the declared number param name
+func FloatParam(r *http.Request, name string) float64 {
+	value, _ := ParamValue(r, name).(float64)
+	return value
+}
+
+// BoolParam returns the value of the declared boolean param name
+func BoolParam(r *http.Request, name string) bool {
+	value, _ := ParamValue(r, name).(bool)
+	return value
+}
@@ -0,0 +1,188 @@
+package httpx
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/omecodes/common/errors"
+	"github.com/omecodes/common/jwt"
+	"github.com/omecodes/common/netx"
+	"github.com/omecodes/common/utils/jcon"
+	"github.com/omecodes/common/utils/log"
+	"github.com/omecodes/common/utils/ratelimit"
+)
+
+// RequestKey returns the key requests are counted by. An empty key lets the next RequestKey decide
+type RequestKey func(r *http.Request) string
+
+// KeyByIP counts requests by client IP address
+func KeyByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// KeyByPrincipal counts requests by the subject of the verified JWT or the common name of the client certificate
+func KeyByPrincipal(r *http.Request) string {
+	if t := jwt.TokenFromContext(r.Context()); t != nil && t.Claims != nil && t.Claims.Subject != "" {
+		return "sub:" + t.Claims.Subject
+	}
+
+	p := netx.PrincipalFromContext(r.Context())
+	if p == nil {
+		p = PrincipalFromRequest(r)
+	}
+	if p != nil && p.CommonName != "" {
+		return "cn:" + p.CommonName
+	}
+	return ""
+}
+
+// KeyByAPIKey counts requests by the access key stored in the request context once its secret is verified.
+// The key of the Authorization header is never used as is, since a made up key would get a bucket of its own
+// and the key of another client would drain its bucket. The authentication middleware must come first
+func KeyByAPIKey(r *http.Request) string {
+	if key := AccessKeyFromContext(r.Context()); key != "" {
+		return "key:" + key
+	}
+	return ""
+}
+
+const ctxAccessKey = jcon.String("access_key")
+
+// ContextWithAccessKey returns a context that holds key. It must only be called once the secret of key is verified
+func ContextWithAccessKey(parent context.Context, key string) context.Context {
+	return context.WithValue(parent, ctxAccessKey, key)
+}
+
+// AccessKeyFromContext returns the verified access key stored in ctx
+func AccessKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(ctxAccessKey).(string)
+	return key
+}
+
+// ParseAccessAuthorization parses authorization values of the "Access key:secret" scheme
+func ParseAccessAuthorization(authorization string) (key string, secret string, ok bool) {
+	const prefix = "access "
+	if len(authorization) < len(prefix) || !strings.EqualFold(authorization[:len(prefix)], prefix) {
+		return "", "", false
+	}
+
+	parts := strings.SplitN(strings.TrimSpace(authorization[len(prefix):]), ":", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+type rateLimitOptions struct {
+	store ratelimit.Store
+	rules *ratelimit.Rules
+	keys  []RequestKey
+}
+
+// RateLimitOption configures the RateLimit middleware
+type RateLimitOption func(opts *rateLimitOptions)
+
+// RateLimitStore sets the store of counters. Default is an in-memory store
+func RateLimitStore(store ratelimit.Store) RateLimitOption {
+	return func(opts *rateLimitOptions) {
+		opts.store = store
+	}
+}
+
+// RateLimitRoute sets the limit of the routes matching pattern. Patterns are paths like "/api/files" or
+// "/api/*", optionally preceded by a method like "POST /api/*". A zero limit disables limiting for them
+func RateLimitRoute(pattern string, limit ratelimit.Limit) RateLimitOption {
+	return func(opts *rateLimitOptions) {
+		opts.rules.Set(pattern, limit)
+	}
+}
+
+// RateLimitKey sets the functions that compute the keys requests are counted by. They are tried in order
+// and KeyByIP is used when they all return empty keys
+func RateLimitKey(keys ...RequestKey) RateLimitOption {
+	return func(opts *rateLimitOptions) {
+		opts.keys = keys
+	}
+}
+
+// RateLimit rejects requests over limit with 429 Too Many Requests and a Retry-After header. All responses of
+// limited routes carry RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers.
+// Requests are let through when the store fails
+func RateLimit(limit ratelimit.Limit, opts ...RateLimitOption) func(http.Handler) http.Handler {
+	options := &rateLimitOptions{
+		rules: ratelimit.NewRules(limit),
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.store == nil {
+		options.store = ratelimit.NewMemoryStore()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			pattern, routeLimit := options.rules.Match(r.Method+" "+r.URL.Path, r.URL.Path)
+			if routeLimit.IsZero() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := ""
+			for _, requestKey := range options.keys {
+				if key = requestKey(r); key != "" {
+					break
+				}
+			}
+			if key == "" {
+				key = KeyByIP(r)
+			}
+
+			result, err := options.store.Take(r.Context(), pattern+"|"+key, routeLimit)
+			if err != nil {
+				log.Error("rate limit store failure", log.Err(err), log.Field("key", key))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			for name, value := range RateLimitHeaders(result) {
+				w.Header().Set(name, value)
+			}
+			if !result.Allowed {
+				WriteError(w, errors.Create(errors.TooManyRequests, "rate limit exceeded").
+					WithDetail("retry_after", seconds(result.RetryAfter)))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RateLimitHeaders returns the RateLimit-* headers describing result, and Retry-After when it is not allowed
+func RateLimitHeaders(result *ratelimit.Result) map[string]string {
+	headers := map[string]string{
+		"RateLimit-Limit":     strconv.Itoa(result.Limit),
+		"RateLimit-Remaining": strconv.Itoa(result.Remaining),
+		"RateLimit-Reset":     strconv.Itoa(seconds(result.ResetIn)),
+	}
+	if !result.Allowed {
+		retryAfter := seconds(result.RetryAfter)
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		headers["Retry-After"] = strconv.Itoa(retryAfter)
+	}
+	return headers
+}
+
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
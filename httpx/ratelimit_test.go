@@ -0,0 +1,72 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/omecodes/common/utils/ratelimit"
+)
+
+func TestParseAccessAuthorization(t *testing.T) {
+	key, secret, ok := ParseAccessAuthorization("Access app:s3cr:et")
+	if !ok || key != "app" || secret != "s3cr:et" {
+		t.Fatal("unexpected access credentials", key, secret)
+	}
+
+	for _, invalid := range []string{"", "Bearer token", "Access nosecret", "Access :secret"} {
+		if _, _, ok := ParseAccessAuthorization(invalid); ok {
+			t.Fatal("invalid authorization must be rejected", invalid)
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	limit := RateLimit(ratelimit.PerMinute(2),
+		RateLimitRoute("/health", ratelimit.Limit{}),
+		RateLimitKey(KeyByAPIKey),
+	)(okHandler)
+
+	// stands for the authentication middleware, only "app:secret" is valid
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key, secret, ok := ParseAccessAuthorization(r.Header.Get("Authorization")); ok && key == "app" && secret == "secret" {
+			r = r.WithContext(ContextWithAccessKey(r.Context(), key))
+		}
+		limit.ServeHTTP(w, r)
+	})
+
+	call := func(path, remoteAddr, authorization string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.RemoteAddr = remoteAddr
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := call("/", "10.0.0.1:1234", ""); w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "2" {
+			t.Fatal("request must be allowed", w.Code, w.Header())
+		}
+	}
+
+	w := call("/", "10.0.0.1:4321", "")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatal("request over limit must be rejected", w.Code, w.Header())
+	}
+
+	if w = call("/", "10.0.0.1:4321", "Access app:secret"); w.Code != http.StatusOK {
+		t.Fatal("requests are counted by verified api key first", w.Code)
+	}
+	if w = call("/", "10.0.0.1:4321", "Access other:secret"); w.Code != http.StatusTooManyRequests {
+		t.Fatal("requests with unverified api keys are counted by client ip", w.Code)
+	}
+	if w = call("/", "10.0.0.2:1234", ""); w.Code != http.StatusOK {
+		t.Fatal("requests are counted by client ip", w.Code)
+	}
+	if w = call("/health", "10.0.0.1:1234", ""); w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "" {
+		t.Fatal("routes with a zero limit must not be limited", w.Code)
+	}
+}
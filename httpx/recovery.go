@@ -0,0 +1,190 @@
+package httpx
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/omecodes/common/errors"
+	"github.com/omecodes/common/utils/jcon"
+	"github.com/omecodes/common/utils/log"
+)
+
+const (
+	// RequestIDHeader is the header that carries request IDs
+	RequestIDHeader = "X-Request-Id"
+
+	ctxRequestID = jcon.String("request_id")
+)
+
+// ContextWithRequestID returns a new context that holds the request ID id
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxRequestID, id)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxRequestID).(string)
+	return id
+}
+
+// NewRequestID generates a random request ID
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// maxRequestIDLength is the maximum length of request IDs sent by clients
+const maxRequestIDLength = 64
+
+// ValidRequestID tells whether id, sent by a client, can be used as request ID. It must be a short token made of
+// letters, digits, '.', '_' and '-' so that it can be logged as is
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// RequestID stores the X-Request-Id of the request in the request context and sends it back in the response
+// headers. A new ID is generated when the request has none or an invalid one, see ValidRequestID
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !ValidRequestID(id) {
+			id = NewRequestID()
+			r.Header.Set(RequestIDHeader, id)
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(ContextWithRequestID(r.Context(), id)))
+	})
+}
+
+// PanicReporter receives the recovered panics with their stack, to send them to an error tracking service
+type PanicReporter func(ctx context.Context, recovered interface{}, stack []byte)
+
+type recoveryOptions struct {
+	reporter PanicReporter
+}
+
+// RecoveryOption configures the Recovery middleware
+type RecoveryOption func(opts *recoveryOptions)
+
+// RecoveryReporter sets the reporter recovered panics are passed to
+func RecoveryReporter(reporter PanicReporter) RecoveryOption {
+	return func(opts *recoveryOptions) {
+		opts.reporter = reporter
+	}
+}
+
+// Recovery recovers the panics of handlers. They are logged with their stack and the request ID, passed to the
+// reporter if any. A 500 Internal Server Error problem is sent if the response was not started, otherwise the
+// response is aborted with http.ErrAbortHandler
+func Recovery(opts ...RecoveryOption) func(http.Handler) http.Handler {
+	options := &recoveryOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &recoveryWriter{ResponseWriter: w}
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				// the handler asked to abort the response
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+
+				requestID := RequestIDFromContext(r.Context())
+				if requestID == "" {
+					requestID = r.Header.Get(RequestIDHeader)
+				}
+				ctx := ContextWithRequestID(r.Context(), requestID)
+				ReportPanic(ctx, r.Method+" "+r.RequestURI, p, debug.Stack(), options.reporter)
+
+				// the truncated response must not look complete to the client, the server closes the connection
+				if rw.started {
+					panic(http.ErrAbortHandler)
+				}
+				failure := errors.Create(errors.Internal, "internal server error")
+				if requestID != "" {
+					failure = failure.WithDetail("request_id", requestID)
+				}
+				WriteError(w, failure)
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// ReportPanic logs the panic p recovered while handling call, with its stack and the request ID stored in ctx.
+// Then it passes it to reporter if it is not nil
+func ReportPanic(ctx context.Context, call string, p interface{}, stack []byte, reporter PanicReporter) {
+	log.Error("recovered from panic",
+		log.Field("call", call),
+		log.Field("request_id", RequestIDFromContext(ctx)),
+		log.Field("panic", fmt.Sprint(p)),
+		log.Field("stack", string(stack)),
+	)
+
+	if reporter == nil {
+		return
+	}
+	defer func() {
+		if rp := recover(); rp != nil {
+			log.Error("panic reporter failed", log.Field("panic", fmt.Sprint(rp)))
+		}
+	}()
+	reporter(ctx, p, stack)
+}
+
+// recoveryWriter tracks whether the response was started
+type recoveryWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (rw *recoveryWriter) WriteHeader(status int) {
+	rw.started = true
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *recoveryWriter) Write(b []byte) (int, error) {
+	rw.started = true
+	return rw.ResponseWriter.Write(b)
+}
+
+func (rw *recoveryWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		rw.started = true
+		flusher.Flush()
+	}
+}
+
+func (rw *recoveryWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("httpx: response writer does not support hijacking")
+	}
+	rw.started = true
+	return hijacker.Hijack()
+}
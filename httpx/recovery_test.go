@@ -0,0 +1,87 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/omecodes/common/errors"
+)
+
+func TestRecovery(t *testing.T) {
+	var reported interface{}
+	var reportedID string
+	reporter := func(ctx context.Context, recovered interface{}, stack []byte) {
+		reported = recovered
+		reportedID = RequestIDFromContext(ctx)
+	}
+
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	handler := RequestID(Recovery(RecoveryReporter(reporter))(panicking))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(RequestIDHeader, "req-1")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusInternalServerError || w.Header().Get(RequestIDHeader) != "req-1" {
+		t.Fatal("unexpected response", w.Code, w.Header())
+	}
+	if reported != "boom" || reportedID != "req-1" {
+		t.Fatal("panic must be reported with the request ID", reported, reportedID)
+	}
+
+	problem := new(errors.Problem)
+	if err := json.NewDecoder(w.Body).Decode(problem); err != nil {
+		t.Fatal(err)
+	}
+	if problem.Details["request_id"] != "req-1" {
+		t.Fatal("problem must hold the request ID", problem)
+	}
+}
+
+func TestRequestIDValidation(t *testing.T) {
+	var id string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id = RequestIDFromContext(r.Context())
+	}))
+
+	for _, invalid := range []string{"a b", "id\nlevel=error", strings.Repeat("a", 65)} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(RequestIDHeader, invalid)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		if id == invalid || !ValidRequestID(id) {
+			t.Fatal("invalid request ID must be replaced", id)
+		}
+	}
+}
+
+func TestRecoveryAfterResponseStarted(t *testing.T) {
+	reported := false
+	handler := Recovery(RecoveryReporter(func(ctx context.Context, recovered interface{}, stack []byte) {
+		reported = true
+	}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("late")
+	}))
+
+	w := httptest.NewRecorder()
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Fatal("started responses must be aborted", p)
+		}
+		if !reported {
+			t.Fatal("the panic must be reported before aborting")
+		}
+		if w.Code != http.StatusAccepted || w.Body.Len() != 0 {
+			t.Fatal("started responses must not be overwritten", w.Code)
+		}
+	}()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	t.Fatal("started responses must be aborted")
+}
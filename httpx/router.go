@@ -2,25 +2,180 @@ package httpx
 
 import (
 	"context"
-	"github.com/gorilla/mux"
 	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+
+	"github.com/omecodes/common/errors"
 )
 
+// OpenAPIPath is the path of the OpenAPI document served by routers created with NewRouter
+const OpenAPIPath = "/openapi.json"
+
 type ContextWrapper func(context.Context) context.Context
 
+// Route describes an endpoint. Middleware is applied in order, the first one being the outermost.
+// Auth lists the names of the registered authenticators that can authenticate requests, any of them is enough.
+// Params are validated before the handler is called. Request and Response are sample values of the body types,
+// used to document the route
 type Route struct {
 	Name         string
 	Method       []string
 	Pattern      string
 	PathIsPrefix bool
 	HandlerFunc  http.HandlerFunc
+
+	Middleware  []HttpMiddleware
+	Auth        []string
+	Params      []Param
+	Summary     string
+	Description string
+	Tags        []string
+	Deprecated  bool
+	Request     interface{}
+	Response    interface{}
+}
+
+// Group shares a path prefix, middleware, auth requirements and tags between routes and sub groups
+type Group struct {
+	Prefix     string
+	Middleware []HttpMiddleware
+	Auth       []string
+	Tags       []string
+	Routes     []Route
+	Groups     []Group
+}
+
+// Flatten returns the routes of the group and its sub groups with the group settings applied
+func (g Group) Flatten() []Route {
+	prefix := strings.TrimSuffix(g.Prefix, "/")
+
+	var routes []Route
+	for _, route := range g.Routes {
+		route.Pattern = prefix + route.Pattern
+		route.Middleware = append(append([]HttpMiddleware{}, g.Middleware...), route.Middleware...)
+		if len(route.Auth) == 0 {
+			route.Auth = g.Auth
+		}
+		route.Tags = append(append([]string{}, g.Tags...), route.Tags...)
+		routes = append(routes, route)
+	}
+
+	for _, sub := range g.Groups {
+		sub.Prefix = prefix + sub.Prefix
+		sub.Middleware = append(append([]HttpMiddleware{}, g.Middleware...), sub.Middleware...)
+		if len(sub.Auth) == 0 {
+			sub.Auth = g.Auth
+		}
+		sub.Tags = append(append([]string{}, g.Tags...), sub.Tags...)
+		routes = append(routes, sub.Flatten()...)
+	}
+	return routes
+}
+
+// Authenticator authenticates requests. It returns the context of the authenticated request
+type Authenticator func(r *http.Request) (context.Context, error)
+
+type authScheme struct {
+	authenticator Authenticator
+	scheme        SecurityScheme
+}
+
+var (
+	authSchemesMutex = &sync.RWMutex{}
+	authSchemes      = map[string]authScheme{}
+)
+
+// RegisterAuthenticator registers authenticator under name, so that routes can require it in Route.Auth.
+// scheme is used to document it
+func RegisterAuthenticator(name string, scheme SecurityScheme, authenticator Authenticator) {
+	authSchemesMutex.Lock()
+	defer authSchemesMutex.Unlock()
+	authSchemes[name] = authScheme{authenticator: authenticator, scheme: scheme}
+}
+
+func getAuthScheme(name string) (authScheme, bool) {
+	authSchemesMutex.RLock()
+	defer authSchemesMutex.RUnlock()
+	s, found := authSchemes[name]
+	return s, found
+}
+
+// authenticated runs the authenticators of names until one of them succeeds
+func authenticated(names []string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var firstErr error
+		var challenges []string
+		for _, name := range names {
+			s, found := getAuthScheme(name)
+			if !found {
+				WriteError(w, errors.Create(errors.Internal, "authentication scheme %s is not registered", name))
+				return
+			}
+
+			ctx, err := s.authenticator(r)
+			if err == nil {
+				next(w, r.WithContext(ctx))
+				return
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			if s.scheme.Type == "http" && s.scheme.Scheme != "" {
+				challenges = append(challenges, strings.ToUpper(s.scheme.Scheme[:1])+s.scheme.Scheme[1:])
+			}
+		}
+
+		code := errors.Code(firstErr)
+		if code != errors.Unauthorized && code != errors.Forbidden {
+			firstErr = errors.Wrap(errors.Unauthorized, firstErr, "")
+		}
+		for _, challenge := range challenges {
+			w.Header().Add("WWW-Authenticate", challenge)
+		}
+		WriteError(w, firstErr)
+	}
 }
 
+// handler returns the route handler wrapped with its auth check, middleware and params validation
+func (route Route) handler() http.Handler {
+	handler := route.HandlerFunc
+	if len(route.Params) > 0 {
+		handler = validatedParams(route.Params, handler)
+	}
+	for i := len(route.Middleware) - 1; i >= 0; i-- {
+		handler = route.Middleware[i](handler)
+	}
+	if len(route.Auth) > 0 {
+		handler = authenticated(route.Auth, handler)
+	}
+	return handler
+}
+
+// NewRouter creates a router that serves routes and their OpenAPI document at OpenAPIPath.
+// Requests get a request ID and handler panics are recovered
 func NewRouter(routes ...Route) *mux.Router {
 	router := mux.NewRouter().StrictSlash(true)
+	router.Use(RequestID, Recovery())
+
+	documented := false
+	for _, route := range routes {
+		if route.Pattern == OpenAPIPath {
+			documented = true
+		}
+	}
+	// registered first so that prefix routes do not shadow it
+	if !documented {
+		document := OpenAPIDocument(APIInfo, routes...)
+		router.Methods(http.MethodGet).Path(OpenAPIPath).Name("openapi").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			WriteJSON(w, http.StatusOK, document)
+		})
+	}
+
 	for _, route := range routes {
-		var handler http.Handler
-		handler = route.HandlerFunc
+		handler := route.handler()
 
 		if route.PathIsPrefix {
 			sr := router.PathPrefix(route.Pattern).Subrouter()
@@ -0,0 +1,124 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/omecodes/common/errors"
+)
+
+type item struct {
+	ID    int64    `json:"id"`
+	Name  string   `json:"name"`
+	Tags  []string `json:"tags,omitempty"`
+	Owner *item    `json:"owner,omitempty"`
+}
+
+func tagged(tag string) HttpMiddleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Trace", tag)
+			next(w, r)
+		}
+	}
+}
+
+func testRoutes() []Route {
+	RegisterAuthenticator("test-token", SecurityScheme{Type: "http", Scheme: "bearer"}, func(r *http.Request) (context.Context, error) {
+		if r.Header.Get("Authorization") != "Bearer valid" {
+			return nil, errors.Unauthorized
+		}
+		return r.Context(), nil
+	})
+
+	api := Group{
+		Prefix:     "/api",
+		Middleware: []HttpMiddleware{tagged("api")},
+		Tags:       []string{"api"},
+		Groups: []Group{{
+			Prefix:     "/items",
+			Middleware: []HttpMiddleware{tagged("items")},
+			Auth:       []string{"test-token"},
+			Routes: []Route{{
+				Name:       "get-item",
+				Method:     []string{http.MethodGet},
+				Pattern:    "/{id:[0-9]+}",
+				Middleware: []HttpMiddleware{tagged("route")},
+				Params:     []Param{PathParam("id", TypeInteger), QueryParam("full", TypeBoolean, false)},
+				Response:   item{},
+				HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+					WriteJSON(w, http.StatusOK, &item{ID: IntParam(r, "id"), Name: "item"})
+				},
+			}},
+		}},
+	}
+	return api.Flatten()
+}
+
+func TestRouter(t *testing.T) {
+	router := NewRouter(testRoutes()...)
+
+	call := func(uri, authorization string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, uri, nil)
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := call("/api/items/12", "Bearer valid")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"id":12`) {
+		t.Fatal("unexpected response", w.Code, w.Body.String())
+	}
+	if trace := strings.Join(w.Header()["X-Trace"], ","); trace != "api,items,route" {
+		t.Fatal("middleware must run from the group to the route", trace)
+	}
+
+	w = call("/api/items/12", "")
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") != "Bearer" {
+		t.Fatal("unauthenticated requests must be rejected", w.Code, w.Header())
+	}
+
+	w = call("/api/items/12?full=maybe", "Bearer valid")
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "full") {
+		t.Fatal("invalid params must be rejected", w.Code, w.Body.String())
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	router := NewRouter(testRoutes()...)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, OpenAPIPath, nil))
+	if w.Code != http.StatusOK {
+		t.Fatal("document must be served", w.Code)
+	}
+
+	doc := new(OpenAPI)
+	if err := json.NewDecoder(w.Body).Decode(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	op := doc.Paths["/api/items/{id}"]["get"]
+	if op == nil {
+		t.Fatal("operation must be documented", doc.Paths)
+	}
+	if len(op.Parameters) != 2 || op.Parameters[0].Schema.Type != TypeInteger || op.Tags[0] != "api" {
+		t.Fatal("unexpected operation", op)
+	}
+	if len(op.Security) != 1 || doc.Components.SecuritySchemes["test-token"].Scheme != "bearer" {
+		t.Fatal("security must be documented", op.Security)
+	}
+
+	schema := op.Responses["200"].Content["application/json"].Schema
+	if schema.Properties["id"].Format != "int64" || schema.Properties["owner"].Type != "object" ||
+		len(schema.Required) != 2 {
+		t.Fatal("unexpected response schema", schema)
+	}
+}
@@ -0,0 +1,138 @@
+package httpx
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/omecodes/common/session"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func TestCORS(t *testing.T) {
+	handler := CORS(
+		CORSOrigins("https://app.example.com", "https://*.omecodes.com"),
+		CORSMethods(http.MethodGet, http.MethodPut),
+		CORSCredentials(true),
+		CORSMaxAge(time.Hour),
+	)(okHandler)
+
+	r := httptest.NewRequest(http.MethodOptions, "/", nil)
+	r.Header.Set("Origin", "https://api.omecodes.com")
+	r.Header.Set("Access-Control-Request-Method", http.MethodPut)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Origin") != "https://api.omecodes.com" ||
+		w.Header().Get("Access-Control-Allow-Credentials") != "true" || w.Header().Get("Access-Control-Max-Age") != "3600" {
+		t.Fatal("unexpected preflight response", w.Code, w.Header())
+	}
+
+	r = httptest.NewRequest(http.MethodOptions, "/", nil)
+	r.Header.Set("Origin", "https://app.example.com")
+	r.Header.Set("Access-Control-Request-Method", http.MethodDelete)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Fatal("preflight of a method that is not allowed must fail", w.Code)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Origin", "https://evil.com")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatal("origins that are not allowed must not get CORS headers")
+	}
+}
+
+func TestCORSCredentialsWithAllOrigins(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("credentials must not be allowed for all origins")
+		}
+	}()
+	CORS(CORSOrigins("*"), CORSCredentials(true))
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	handler := SecurityHeaders(SecurityCSP("default-src 'self'"), SecurityHSTS(time.Hour, true, false))(okHandler)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Header().Get("Strict-Transport-Security") != "" || w.Header().Get("X-Frame-Options") != "DENY" ||
+		w.Header().Get("Content-Security-Policy") != "default-src 'self'" {
+		t.Fatal("unexpected headers", w.Header())
+	}
+
+	r.TLS = &tls.ConnectionState{}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Header().Get("Strict-Transport-Security") != "max-age=3600; includeSubDomains" {
+		t.Fatal("HSTS must be sent on TLS connections", w.Header())
+	}
+}
+
+func TestCSRF(t *testing.T) {
+	manager := session.NewManager(session.NewCookieStore("session", []byte("0123456789abcdef0123456789abcdef")))
+	var token string
+	handler := CSRF(manager)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = CSRFToken(r)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if token == "" {
+		t.Fatal("a token must be generated")
+	}
+	cookies := w.Result().Cookies()
+
+	post := func(submitted string, headers map[string]string) int {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("csrf_token="+submitted))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for name, value := range headers {
+			r.Header.Set(name, value)
+		}
+		for _, cookie := range cookies {
+			r.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := post(token, nil); code != http.StatusOK {
+		t.Fatal("valid form token must be accepted", code)
+	}
+	if code := post("", map[string]string{"X-CSRF-Token": token}); code != http.StatusOK {
+		t.Fatal("valid header token must be accepted", code)
+	}
+	if code := post("forged", nil); code != http.StatusForbidden {
+		t.Fatal("invalid token must be rejected", code)
+	}
+	if code := post(token, map[string]string{"Origin": "https://evil.com"}); code != http.StatusForbidden {
+		t.Fatal("cross origin requests must be rejected", code)
+	}
+
+	cookies = nil
+	if code := post(token, nil); code != http.StatusForbidden {
+		t.Fatal("requests without cookies must be rejected", code)
+	}
+
+	// the session of the Sessions middleware is reused
+	var stored string
+	handler = Sessions(manager)(CSRF(manager)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = CSRFToken(r)
+		stored = SessionFromContext(r.Context()).GetString(csrfSessionKey)
+	})))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if stored == "" || stored != token {
+		t.Fatal("the csrf token must be stored in the request session", stored, token)
+	}
+}
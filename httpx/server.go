@@ -0,0 +1,17 @@
+package httpx
+
+import (
+	"net/http"
+
+	"github.com/omecodes/common/netx"
+)
+
+// ListenAndServe serves handler on a netx listener. The listen options, like TLS and connection limits,
+// can be shared with grpcx servers through grpcx.ListenOptions
+func ListenAndServe(address string, handler http.Handler, opts ...netx.ListenOption) error {
+	l, err := netx.Listen(address, opts...)
+	if err != nil {
+		return err
+	}
+	return http.Serve(l, handler)
+}
@@ -1,24 +1,135 @@
 package httpx
 
 import (
+	"bufio"
 	"context"
+	"fmt"
 	"github.com/gorilla/sessions"
-	"github.com/omecodes/common/utils/codec"
+	"github.com/omecodes/common/session"
+	"log"
+	"net"
 	"net/http"
+	"time"
 )
 
+const cookieSessionUserKey = "_user_id"
+
 type Cookie struct {
 	http.Cookie
 }
 
-type Session interface {
-	Set(name string, value interface{})
-	Get(name string) interface{}
-	GetString(name string) string
-	GetBool(name string) bool
-	Delete(name string)
-	Save() error
-	Encoded() ([]byte, error)
+// Session is the session API shared with grpcx. See session.Session
+type Session = session.Session
+
+// SessionFromContext returns the session loaded by the Sessions middleware, or by handlers adapted with Handle
+// and the HandleSession option
+func SessionFromContext(ctx context.Context) Session {
+	s, _ := ctx.Value(ctxSession).(Session)
+	return s
+}
+
+// ContextWithSession returns a copy of ctx that holds s
+func ContextWithSession(ctx context.Context, s Session) context.Context {
+	return context.WithValue(ctx, ctxSession, s)
+}
+
+// Sessions loads the session referenced by the request cookie from manager once per request. Handlers get it
+// with SessionFromContext. It is saved when it changed, before the response header is written
+func Sessions(manager *session.Manager) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if m, _ := r.Context().Value(ctxSessionManager).(*session.Manager); m == manager {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var token string
+			if c, err := r.Cookie(manager.CookieName()); err == nil {
+				token = c.Value
+			}
+
+			sw := &sessionWriter{ResponseWriter: w}
+			s, err := manager.Open(r.Context(), token, func(cookie *http.Cookie) error {
+				http.SetCookie(w, cookie)
+				return nil
+			})
+			if err != nil {
+				WriteLocalizedError(w, r, err)
+				return
+			}
+			sw.session = s
+
+			ctx := context.WithValue(ContextWithSession(r.Context(), s), ctxSessionManager, manager)
+			next.ServeHTTP(sw, r.WithContext(ctx))
+			sw.save()
+		})
+	}
+}
+
+// OpenSession returns the session of r in manager. It is the session of the Sessions middleware when it runs
+// with the same manager before. Otherwise the session is opened from the request cookie, and it is saved only
+// when Save is called
+func OpenSession(manager *session.Manager, w http.ResponseWriter, r *http.Request) (Session, error) {
+	if m, _ := r.Context().Value(ctxSessionManager).(*session.Manager); m == manager {
+		if s := SessionFromContext(r.Context()); s != nil {
+			return s, nil
+		}
+	}
+
+	var token string
+	if c, err := r.Cookie(manager.CookieName()); err == nil {
+		token = c.Value
+	}
+	return manager.Open(r.Context(), token, func(cookie *http.Cookie) error {
+		http.SetCookie(w, cookie)
+		return nil
+	})
+}
+
+// sessionWriter saves the session before the response header is written
+type sessionWriter struct {
+	http.ResponseWriter
+	session Session
+	started bool
+}
+
+func (sw *sessionWriter) save() {
+	if err := sw.session.Save(); err != nil {
+		log.Println("[xhttp]:\tcould not save session:", err)
+	}
+}
+
+func (sw *sessionWriter) start() {
+	if !sw.started {
+		sw.started = true
+		sw.save()
+	}
+}
+
+func (sw *sessionWriter) WriteHeader(status int) {
+	sw.start()
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *sessionWriter) Write(b []byte) (int, error) {
+	sw.start()
+	return sw.ResponseWriter.Write(b)
+}
+
+func (sw *sessionWriter) Flush() {
+	if flusher, ok := sw.ResponseWriter.(http.Flusher); ok {
+		sw.start()
+		flusher.Flush()
+	}
+}
+
+func (sw *sessionWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := sw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("httpx: response writer does not support hijacking")
+	}
+	sw.start()
+	return hijacker.Hijack()
 }
 
 func SetCookie(ctx context.Context, cookie *Cookie) {
@@ -29,11 +140,16 @@ func SetCookie(ctx context.Context, cookie *Cookie) {
 	http.SetCookie(rw.(http.ResponseWriter), &cookie.Cookie)
 }
 
+// GetSession returns the session name of store. Unlike sessions of the Sessions middleware, it is saved only when
+// Save is called. Values live in the cookie, so the session has no ID, Rotate does nothing and SetUser does not
+// rotate it: a cookie captured before login never holds the user. Such sessions cannot be revoked either.
+//
+// Deprecated: use the Sessions middleware or OpenSession with a session.Manager
 func GetSession(name string, w http.ResponseWriter, r *http.Request, store *sessions.CookieStore) Session {
 	return newSession(name, r, w, store)
 }
 
-type session struct {
+type cookieSession struct {
 	store       *sessions.CookieStore
 	httpSession *sessions.Session
 	r           *http.Request
@@ -41,7 +157,7 @@ type session struct {
 }
 
 func newSession(name string, r *http.Request, w http.ResponseWriter, store *sessions.CookieStore) Session {
-	s := new(session)
+	s := new(cookieSession)
 	s.store = store
 	s.r = r
 	s.w = w
@@ -49,11 +165,11 @@ func newSession(name string, r *http.Request, w http.ResponseWriter, store *sess
 	return s
 }
 
-func (s *session) Set(name string, value interface{}) {
+func (s *cookieSession) Set(name string, value interface{}) {
 	s.httpSession.Values[name] = value
 }
 
-func (s *session) Get(name string) interface{} {
+func (s *cookieSession) Get(name string) interface{} {
 	v, ok := s.httpSession.Values[name]
 	if !ok {
 		return nil
@@ -61,7 +177,7 @@ func (s *session) Get(name string) interface{} {
 	return v
 }
 
-func (s *session) GetString(name string) string {
+func (s *cookieSession) GetString(name string) string {
 	v, ok := s.httpSession.Values[name]
 	if !ok {
 		return ""
@@ -73,7 +189,7 @@ func (s *session) GetString(name string) string {
 	return str
 }
 
-func (s *session) GetBool(name string) bool {
+func (s *cookieSession) GetBool(name string) bool {
 	v, ok := s.httpSession.Values[name]
 	if !ok {
 		return ok
@@ -86,14 +202,57 @@ func (s *session) GetBool(name string) bool {
 	return b
 }
 
-func (s *session) Delete(key string) {
+func (s *cookieSession) Delete(key string) {
 	delete(s.httpSession.Values, key)
 }
 
-func (s *session) Save() error {
+func (s *cookieSession) Save() error {
 	return s.httpSession.Save(s.r, s.w)
 }
 
-func (s *session) Encoded() ([]byte, error) {
-	return codec.Default.Encode(s.httpSession.Values)
+func (s *cookieSession) ID() string {
+	return s.httpSession.ID
+}
+
+func (s *cookieSession) UserID() string {
+	return s.GetString(cookieSessionUserKey)
+}
+
+func (s *cookieSession) SetUser(userID string) {
+	s.Set(cookieSessionUserKey, userID)
+}
+
+func (s *cookieSession) GetInt(name string) int {
+	return session.Int(s.Get(name))
+}
+
+func (s *cookieSession) GetInt64(name string) int64 {
+	return session.Int64(s.Get(name))
+}
+
+func (s *cookieSession) GetFloat(name string) float64 {
+	return session.Float(s.Get(name))
+}
+
+func (s *cookieSession) GetTime(name string) time.Time {
+	return session.Time(s.Get(name))
+}
+
+func (s *cookieSession) AddFlash(value interface{}) {
+	s.httpSession.AddFlash(value)
+}
+
+func (s *cookieSession) Flashes() []interface{} {
+	return s.httpSession.Flashes()
+}
+
+func (s *cookieSession) Rotate() {}
+
+func (s *cookieSession) Destroy() {
+	for key := range s.httpSession.Values {
+		delete(s.httpSession.Values, key)
+	}
+	options := *s.httpSession.Options
+	options.MaxAge = -1
+	s.httpSession.Options = &options
 }
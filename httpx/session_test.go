@@ -0,0 +1,101 @@
+package httpx
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/omecodes/common/session"
+)
+
+func TestSessions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := session.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := Sessions(session.NewManager(store))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := SessionFromContext(r.Context())
+		if name := r.URL.Query().Get("name"); name != "" {
+			s.Set("name", name)
+		}
+		_, _ = w.Write([]byte(s.GetString("name")))
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?name=alice", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "session" {
+		t.Fatal("changed session must be saved before the body is written", cookies)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Body.String() != "alice" {
+		t.Fatal("session must be loaded from the cookie", w.Body.String())
+	}
+	if len(w.Result().Cookies()) != 0 {
+		t.Fatal("unchanged session must not be saved", w.Result().Cookies())
+	}
+}
+
+func TestHandleSession(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := session.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := session.NewManager(store)
+
+	var sessions []Session
+	handler := Handle(func(ctx context.Context) (interface{}, error) {
+		s := SessionFromContext(ctx)
+		sessions = append(sessions, s)
+		if s.UserID() == "" {
+			s.SetUser("alice")
+		}
+		return map[string]string{"user": s.UserID()}, nil
+	}, HandleSession(manager))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "session" {
+		t.Fatal("changed session must be saved before the response is written", cookies)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if !strings.Contains(w.Body.String(), "alice") || len(w.Result().Cookies()) != 0 {
+		t.Fatal("session must be loaded from the cookie", w.Body.String(), w.Result().Cookies())
+	}
+
+	// the session of the Sessions middleware is reused
+	var outer Session
+	Sessions(manager)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outer = SessionFromContext(r.Context())
+		handler.ServeHTTP(w, r)
+	})).ServeHTTP(httptest.NewRecorder(), r)
+	if len(sessions) != 3 || outer == nil || sessions[2] != outer {
+		t.Fatal("the session of the middleware must be reused", sessions, outer)
+	}
+}
@@ -0,0 +1,79 @@
+package httpx
+
+import (
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/omecodes/common/errors"
+)
+
+// precompressed file extensions by encoding
+var precompressedExtensions = map[string]string{
+	EncodingBrotli: ".br",
+	EncodingGzip:   ".gz",
+}
+
+// ServeFile serves filename with ServeContent. When the client accepts it, the precompressed sibling
+// filename.br or filename.gz is served instead, with the content type of filename
+func ServeFile(w http.ResponseWriter, r *http.Request, filename string) {
+	info, err := os.Stat(filename)
+	if err != nil || info.IsDir() {
+		WriteError(w, errors.NotFound)
+		return
+	}
+
+	var available []string
+	for _, encoding := range encodingPreference {
+		extension, supported := precompressedExtensions[encoding]
+		if !supported {
+			continue
+		}
+		if sibling, err := os.Stat(filename + extension); err == nil && !sibling.IsDir() {
+			available = append(available, encoding)
+		}
+	}
+
+	servedFilename := filename
+	if len(available) > 0 {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if encoding := NegotiateEncoding(r.Header.Get("Accept-Encoding"), available...); encoding != "" {
+			servedFilename = filename + precompressedExtensions[encoding]
+			w.Header().Set("Content-Encoding", encoding)
+		}
+	}
+
+	f, err := os.Open(servedFilename)
+	if err != nil {
+		w.Header().Del("Content-Encoding")
+		WriteError(w, errors.Wrap(errors.NotFound, err, ""))
+		return
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		w.Header().Del("Content-Encoding")
+		WriteError(w, err)
+		return
+	}
+
+	ServeContent(w, r, ContentInfo{Name: filepath.Base(filename), ModTime: stat.ModTime()}, f)
+}
+
+// FileServer serves the files of dir with ServeFile
+func FileServer(dir string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := path.Clean("/" + r.URL.Path)
+		if strings.HasSuffix(name, "/") {
+			name += "index.html"
+		}
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if info, err := os.Stat(filename); err == nil && info.IsDir() {
+			filename = filepath.Join(filename, "index.html")
+		}
+		ServeFile(w, r, filename)
+	})
+}
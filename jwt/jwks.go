@@ -0,0 +1,193 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// JWK is a JSON Web Key
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set document
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK exports the public part of the key
+func (k *Key) JWK() (*JWK, error) {
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		return &JWK{
+			Kty: "RSA",
+			Kid: k.ID,
+			Alg: k.Algorithm,
+			Use: "sig",
+			N:   encoding.EncodeToString(pub.N.Bytes()),
+			E:   encoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, nil
+
+	case *ecdsa.PublicKey:
+		x, y := make([]byte, 32), make([]byte, 32)
+		xBytes, yBytes := pub.X.Bytes(), pub.Y.Bytes()
+		copy(x[32-len(xBytes):], xBytes)
+		copy(y[32-len(yBytes):], yBytes)
+		return &JWK{
+			Kty: "EC",
+			Kid: k.ID,
+			Alg: k.Algorithm,
+			Use: "sig",
+			Crv: "P-256",
+			X:   encoding.EncodeToString(x),
+			Y:   encoding.EncodeToString(y),
+		}, nil
+
+	default:
+		return nil, ErrKeyNotSupported
+	}
+}
+
+// Key creates a verification key from the JWK
+func (j *JWK) Key() (*Key, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := encoding.DecodeString(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := encoding.DecodeString(j.E)
+		if err != nil {
+			return nil, err
+		}
+		return NewPublicKey(j.Kid, &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		})
+
+	case "EC":
+		if j.Crv != "P-256" {
+			return nil, ErrKeyNotSupported
+		}
+		x, err := encoding.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := encoding.DecodeString(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		return NewPublicKey(j.Kid, &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		})
+
+	default:
+		return nil, ErrKeyNotSupported
+	}
+}
+
+// NewRemoteKeySet creates a key provider that consumes the JWKS document served at url.
+// The document is cached for ttl and fetched again when an unknown key ID is requested
+func NewRemoteKeySet(url string, client *http.Client, ttl time.Duration) *RemoteKeySet {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &RemoteKeySet{
+		url:    url,
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+// RemoteKeySet is a KeyProvider backed by a remote JWKS document
+type RemoteKeySet struct {
+	sync.Mutex
+	url       string
+	client    *http.Client
+	ttl       time.Duration
+	keys      *KeySet
+	fetchedAt time.Time
+}
+
+// Key returns the key registered with kid in the remote JWKS document
+func (r *RemoteKeySet) Key(kid string) (*Key, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	var cached *Key
+	if r.keys != nil {
+		k, err := r.keys.Key(kid)
+		if err == nil && time.Since(r.fetchedAt) < r.ttl {
+			return k, nil
+		}
+
+		// avoids hammering the JWKS endpoint with tokens signed by unknown keys
+		if err != nil && time.Since(r.fetchedAt) < time.Second*10 {
+			return nil, err
+		}
+		cached = k
+	}
+
+	err := r.fetch(context.Background())
+	if err != nil {
+		if cached != nil {
+			return cached, nil
+		}
+		return nil, err
+	}
+	return r.keys.Key(kid)
+}
+
+func (r *RemoteKeySet) fetch(ctx context.Context) error {
+	req, err := http.NewRequest(http.MethodGet, r.url, nil)
+	if err != nil {
+		return err
+	}
+
+	rsp, err := r.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		return fmt.Errorf("jwt: could not fetch JWKS document: %s", rsp.Status)
+	}
+
+	var set JWKS
+	err = json.NewDecoder(rsp.Body).Decode(&set)
+	if err != nil {
+		return err
+	}
+
+	keys := NewKeySet()
+	for _, jwk := range set.Keys {
+		k, err := jwk.Key()
+		if err != nil {
+			continue
+		}
+		keys.Add(k)
+	}
+
+	r.keys = keys
+	r.fetchedAt = time.Now()
+	return nil
+}
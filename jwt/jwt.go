@@ -0,0 +1,234 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/omecodes/common/errors"
+)
+
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+var (
+	ErrMalformed       = errors.Create(errors.Unauthorized, "jwt: malformed token")
+	ErrAlgorithm       = errors.Create(errors.Unauthorized, "jwt: unsupported algorithm")
+	ErrUnknownKey      = errors.Create(errors.Unauthorized, "jwt: unknown key")
+	ErrSignature       = errors.Create(errors.Unauthorized, "jwt: invalid signature")
+	ErrExpired         = errors.Create(errors.Unauthorized, "jwt: token is expired")
+	ErrNotValidYet     = errors.Create(errors.Unauthorized, "jwt: token is not valid yet")
+	ErrAudience        = errors.Create(errors.Unauthorized, "jwt: invalid audience")
+	ErrIssuer          = errors.Create(errors.Unauthorized, "jwt: invalid issuer")
+	ErrCannotSign      = errors.Create(errors.Internal, "jwt: key cannot sign")
+	ErrNoSigningKey    = errors.Create(errors.Internal, "jwt: no signing key")
+	ErrKeyNotSupported = errors.Create(errors.NotSupported, "jwt: key type not supported")
+)
+
+var encoding = base64.RawURLEncoding
+
+// Header is the JOSE header of a token
+type Header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// Audience holds the "aud" claim which can be encoded either as a single string or as an array
+type Audience []string
+
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// Contains tells whether aud is part of the audience
+func (a Audience) Contains(aud string) bool {
+	for _, item := range a {
+		if item == aud {
+			return true
+		}
+	}
+	return false
+}
+
+// Claims holds registered claims. Private claims are kept in Extra
+type Claims struct {
+	Issuer    string                 `json:"iss,omitempty"`
+	Subject   string                 `json:"sub,omitempty"`
+	Audience  Audience               `json:"aud,omitempty"`
+	ExpiresAt int64                  `json:"exp,omitempty"`
+	NotBefore int64                  `json:"nbf,omitempty"`
+	IssuedAt  int64                  `json:"iat,omitempty"`
+	ID        string                 `json:"jti,omitempty"`
+	Extra     map[string]interface{} `json:"-"`
+}
+
+type registeredClaims Claims
+
+var registeredNames = []string{"iss", "sub", "aud", "exp", "nbf", "iat", "jti"}
+
+func (c *Claims) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal((*registeredClaims)(c))
+	if err != nil || len(c.Extra) == 0 {
+		return data, err
+	}
+
+	all := map[string]interface{}{}
+	for name, value := range c.Extra {
+		all[name] = value
+	}
+
+	registered := map[string]interface{}{}
+	err = json.Unmarshal(data, &registered)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range registered {
+		all[name] = value
+	}
+	return json.Marshal(all)
+}
+
+func (c *Claims) UnmarshalJSON(data []byte) error {
+	err := json.Unmarshal(data, (*registeredClaims)(c))
+	if err != nil {
+		return err
+	}
+
+	all := map[string]interface{}{}
+	err = json.Unmarshal(data, &all)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range registeredNames {
+		delete(all, name)
+	}
+	if len(all) > 0 {
+		c.Extra = all
+	}
+	return nil
+}
+
+// Get returns the private claim registered under name
+func (c *Claims) Get(name string) interface{} {
+	if c.Extra == nil {
+		return nil
+	}
+	return c.Extra[name]
+}
+
+// Set sets a private claim
+func (c *Claims) Set(name string, value interface{}) {
+	if c.Extra == nil {
+		c.Extra = map[string]interface{}{}
+	}
+	c.Extra[name] = value
+}
+
+// Expiry returns the expiry time. Zero time is returned if the claim is not set
+func (c *Claims) Expiry() time.Time {
+	if c.ExpiresAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(c.ExpiresAt, 0)
+}
+
+// Token is a decoded JWT
+type Token struct {
+	Header    Header
+	Claims    *Claims
+	Raw       string
+	signature []byte
+	signed    string
+}
+
+// Parse decodes raw without verifying its signature nor its claims
+func Parse(raw string) (*Token, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	t := &Token{
+		Raw:    raw,
+		Claims: new(Claims),
+		signed: parts[0] + "." + parts[1],
+	}
+
+	headerBytes, err := encoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	err = json.Unmarshal(headerBytes, &t.Header)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	claimsBytes, err := encoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	err = json.Unmarshal(claimsBytes, t.Claims)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	t.signature, err = encoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	return t, nil
+}
+
+// Sign encodes claims and signs them with key
+func Sign(key *Key, claims *Claims) (string, error) {
+	if !key.CanSign() {
+		return "", ErrCannotSign
+	}
+
+	header := Header{
+		Alg: key.Algorithm,
+		Typ: "JWT",
+		Kid: key.ID,
+	}
+
+	headerBytes, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+
+	claimsBytes, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signed := encoding.EncodeToString(headerBytes) + "." + encoding.EncodeToString(claimsBytes)
+	signature, err := key.sign([]byte(signed))
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + encoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,138 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testKeys(t *testing.T) []*Key {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rk, err := NewPrivateKey("rsa", rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek, err := NewPrivateKey("ec", ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return []*Key{NewHMACKey("hmac", []byte("secret")), rk, ek}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	for _, k := range testKeys(t) {
+		keys := NewKeySet(k)
+		claims := &Claims{
+			Issuer:    "ome",
+			Subject:   "user",
+			Audience:  Audience{"api"},
+			ExpiresAt: time.Now().Add(time.Minute).Unix(),
+		}
+		claims.Set("role", "admin")
+
+		raw, err := keys.Sign(claims)
+		if err != nil {
+			t.Fatal(k.Algorithm, err)
+		}
+
+		v := NewVerifier(keys, WithIssuer("ome"), WithAudience("api"))
+		token, err := v.Verify(raw)
+		if err != nil {
+			t.Fatal(k.Algorithm, err)
+		}
+
+		if token.Header.Kid != k.ID || token.Claims.Subject != "user" || token.Claims.Get("role") != "admin" {
+			t.Fatal(k.Algorithm, "unexpected token content")
+		}
+
+		_, err = NewVerifier(keys, WithAudience("other")).Verify(raw)
+		if err != ErrAudience {
+			t.Fatal(k.Algorithm, "expected audience error, got", err)
+		}
+	}
+}
+
+func TestExpiredToken(t *testing.T) {
+	keys := NewKeySet(NewHMACKey("hmac", []byte("secret")))
+	raw, err := keys.Sign(&Claims{ExpiresAt: time.Now().Add(-time.Minute).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewVerifier(keys).Verify(raw)
+	if err != ErrExpired {
+		t.Fatal("expected expired error, got", err)
+	}
+
+	_, err = NewVerifier(keys, WithLeeway(time.Hour)).Verify(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRotationAndJWKS(t *testing.T) {
+	keys := testKeys(t)
+	set := NewKeySet(keys[1])
+
+	oldToken, err := set.Sign(&Claims{Subject: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = set.Rotate(keys[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newToken, err := set.Sign(&Claims{Subject: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(set)
+	defer server.Close()
+
+	v := NewVerifier(NewRemoteKeySet(server.URL, nil, time.Minute))
+	for _, raw := range []string{oldToken, newToken} {
+		_, err = v.Verify(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := json.Marshal(NewKeySet(keys[0]).JWKS())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"keys":[]}` {
+		t.Fatal("HMAC keys must not be exported")
+	}
+}
+
+func TestAlgorithmSubstitution(t *testing.T) {
+	keys := testKeys(t)
+	forged := NewHMACKey("rsa", []byte("secret"))
+	raw, err := Sign(forged, &Claims{Subject: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewVerifier(NewKeySet(keys[1])).Verify(raw)
+	if err != ErrAlgorithm {
+		t.Fatal("expected algorithm error, got", err)
+	}
+}
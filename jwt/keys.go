@@ -0,0 +1,173 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"math/big"
+
+	"github.com/omecodes/common/utils/jcon"
+	crypto2 "github.com/omecodes/libome/crypt"
+)
+
+// Key is a signing and/or verification key identified by its ID (kid)
+type Key struct {
+	ID        string
+	Algorithm string
+	secret    []byte
+	private   crypto.PrivateKey
+	public    crypto.PublicKey
+}
+
+// NewHMACKey creates a HS256 key from a shared secret
+func NewHMACKey(id string, secret []byte) *Key {
+	return &Key{
+		ID:        id,
+		Algorithm: HS256,
+		secret:    secret,
+	}
+}
+
+// NewPrivateKey creates a signing key. RSA keys are used with RS256 and P-256 ECDSA keys with ES256
+func NewPrivateKey(id string, private crypto.PrivateKey) (*Key, error) {
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		return &Key{ID: id, Algorithm: RS256, private: k, public: &k.PublicKey}, nil
+
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return nil, ErrKeyNotSupported
+		}
+		return &Key{ID: id, Algorithm: ES256, private: k, public: &k.PublicKey}, nil
+
+	default:
+		return nil, ErrKeyNotSupported
+	}
+}
+
+// NewPublicKey creates a verification only key
+func NewPublicKey(id string, public crypto.PublicKey) (*Key, error) {
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		return &Key{ID: id, Algorithm: RS256, public: k}, nil
+
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return nil, ErrKeyNotSupported
+		}
+		return &Key{ID: id, Algorithm: ES256, public: k}, nil
+
+	default:
+		return nil, ErrKeyNotSupported
+	}
+}
+
+// LoadPrivateKey loads a PEM encoded private key from keyFilename
+func LoadPrivateKey(id string, keyFilename string, password []byte) (*Key, error) {
+	private, err := crypto2.LoadPrivateKey(password, keyFilename)
+	if err != nil {
+		return nil, err
+	}
+	return NewPrivateKey(id, private)
+}
+
+// LoadPublicKey loads the public key of the PEM encoded certificate stored in certFilename
+func LoadPublicKey(id string, certFilename string) (*Key, error) {
+	cert, err := crypto2.LoadCertificate(certFilename)
+	if err != nil {
+		return nil, err
+	}
+	return NewPublicKey(id, cert.PublicKey)
+}
+
+// KeysFromSecrets creates HS256 keys from the "secrets" app config. Each secret name is used as key ID
+func KeysFromSecrets(secrets jcon.Map) []*Key {
+	var keys []*Key
+	for name := range secrets {
+		secret, ok := secrets.GetString(name)
+		if !ok || secret == "" {
+			continue
+		}
+		keys = append(keys, NewHMACKey(name, []byte(secret)))
+	}
+	return keys
+}
+
+// CanSign tells whether the key holds private material
+func (k *Key) CanSign() bool {
+	return k != nil && (k.secret != nil || k.private != nil)
+}
+
+// Public returns the public key. It is nil for HMAC keys
+func (k *Key) Public() crypto.PublicKey {
+	return k.public
+}
+
+func (k *Key) sign(input []byte) ([]byte, error) {
+	switch k.Algorithm {
+	case HS256:
+		mac := hmac.New(sha256.New, k.secret)
+		_, _ = mac.Write(input)
+		return mac.Sum(nil), nil
+
+	case RS256:
+		hash := sha256.Sum256(input)
+		return rsa.SignPKCS1v15(rand.Reader, k.private.(*rsa.PrivateKey), crypto.SHA256, hash[:])
+
+	case ES256:
+		hash := sha256.Sum256(input)
+		r, s, err := ecdsa.Sign(rand.Reader, k.private.(*ecdsa.PrivateKey), hash[:])
+		if err != nil {
+			return nil, err
+		}
+		signature := make([]byte, 64)
+		rBytes, sBytes := r.Bytes(), s.Bytes()
+		copy(signature[32-len(rBytes):32], rBytes)
+		copy(signature[64-len(sBytes):], sBytes)
+		return signature, nil
+
+	default:
+		return nil, ErrAlgorithm
+	}
+}
+
+func (k *Key) verify(input []byte, signature []byte) error {
+	switch k.Algorithm {
+	case HS256:
+		if k.secret == nil {
+			return ErrSignature
+		}
+		mac := hmac.New(sha256.New, k.secret)
+		_, _ = mac.Write(input)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrSignature
+		}
+		return nil
+
+	case RS256:
+		hash := sha256.Sum256(input)
+		if rsa.VerifyPKCS1v15(k.public.(*rsa.PublicKey), crypto.SHA256, hash[:], signature) != nil {
+			return ErrSignature
+		}
+		return nil
+
+	case ES256:
+		if len(signature) != 64 {
+			return ErrSignature
+		}
+		hash := sha256.Sum256(input)
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(k.public.(*ecdsa.PublicKey), hash[:], r, s) {
+			return ErrSignature
+		}
+		return nil
+
+	default:
+		return ErrAlgorithm
+	}
+}
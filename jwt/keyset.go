@@ -0,0 +1,138 @@
+package jwt
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// KeyProvider resolves verification keys by ID
+type KeyProvider interface {
+	Key(kid string) (*Key, error)
+}
+
+// NewKeySet creates a key set. The first key that can sign becomes the active signing key
+func NewKeySet(keys ...*Key) *KeySet {
+	s := &KeySet{keys: map[string]*Key{}}
+	for _, k := range keys {
+		s.Add(k)
+	}
+	return s
+}
+
+// KeySet holds signing and verification keys. Rotating the active key keeps
+// the previous ones available for verification until they are removed
+type KeySet struct {
+	sync.RWMutex
+	keys   map[string]*Key
+	order  []string
+	active string
+}
+
+// Add registers k for verification. It becomes the signing key if none was set
+func (s *KeySet) Add(k *Key) {
+	s.Lock()
+	defer s.Unlock()
+	s.add(k)
+	if s.active == "" && k.CanSign() {
+		s.active = k.ID
+	}
+}
+
+// Rotate registers k and makes it the active signing key
+func (s *KeySet) Rotate(k *Key) error {
+	if !k.CanSign() {
+		return ErrCannotSign
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	s.add(k)
+	s.active = k.ID
+	return nil
+}
+
+// Remove unregisters the key identified by kid
+func (s *KeySet) Remove(kid string) {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.keys, kid)
+	for i, id := range s.order {
+		if id == kid {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+	if s.active == kid {
+		s.active = ""
+	}
+}
+
+func (s *KeySet) add(k *Key) {
+	if _, exists := s.keys[k.ID]; !exists {
+		s.order = append(s.order, k.ID)
+	}
+	s.keys[k.ID] = k
+}
+
+// Key returns the key registered with kid
+func (s *KeySet) Key(kid string) (*Key, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	k, ok := s.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return k, nil
+}
+
+// SigningKey returns the active signing key
+func (s *KeySet) SigningKey() (*Key, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	if s.active == "" {
+		return nil, ErrNoSigningKey
+	}
+	return s.keys[s.active], nil
+}
+
+// Sign signs claims with the active signing key
+func (s *KeySet) Sign(claims *Claims) (string, error) {
+	k, err := s.SigningKey()
+	if err != nil {
+		return "", err
+	}
+	return Sign(k, claims)
+}
+
+// JWKS returns the JSON Web Key Set document of the public keys. HMAC keys are never exported
+func (s *KeySet) JWKS() *JWKS {
+	s.RLock()
+	defer s.RUnlock()
+
+	set := &JWKS{Keys: []JWK{}}
+	for _, id := range s.order {
+		jwk, err := s.keys[id].JWK()
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, *jwk)
+	}
+	return set
+}
+
+// ServeHTTP serves the JWKS document
+func (s *KeySet) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, err := json.Marshal(s.JWKS())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/jwk-set+json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,73 @@
+package jwt
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TokenSource provides tokens to attach to outgoing requests
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// FetchFunc obtains a new token along with its expiry time
+type FetchFunc func(ctx context.Context) (string, time.Time, error)
+
+// StaticSource returns a source that always provides t
+func StaticSource(t string) TokenSource {
+	return staticSource(t)
+}
+
+type staticSource string
+
+func (s staticSource) Token(ctx context.Context) (string, error) {
+	return string(s), nil
+}
+
+// RefreshingSource returns a source that caches the token obtained with fetch
+// and fetches a new one when it is about to expire within margin
+func RefreshingSource(fetch FetchFunc, margin time.Duration) TokenSource {
+	return &refreshingSource{fetch: fetch, margin: margin}
+}
+
+// IssuingSource returns a source that mints tokens signed with the active key of keys.
+// claims is called for each new token. "iat" and "exp" are set from ttl
+func IssuingSource(keys *KeySet, claims func() *Claims, ttl time.Duration) TokenSource {
+	fetch := func(ctx context.Context) (string, time.Time, error) {
+		c := claims()
+		now := time.Now()
+		expiry := now.Add(ttl)
+		c.IssuedAt = now.Unix()
+		c.ExpiresAt = expiry.Unix()
+
+		t, err := keys.Sign(c)
+		return t, expiry, err
+	}
+	return RefreshingSource(fetch, ttl/10)
+}
+
+type refreshingSource struct {
+	sync.Mutex
+	fetch  FetchFunc
+	margin time.Duration
+	token  string
+	expiry time.Time
+}
+
+func (s *refreshingSource) Token(ctx context.Context) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.token != "" && (s.expiry.IsZero() || time.Now().Add(s.margin).Before(s.expiry)) {
+		return s.token, nil
+	}
+
+	t, expiry, err := s.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	s.token = t
+	s.expiry = expiry
+	return t, nil
+}
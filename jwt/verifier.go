@@ -0,0 +1,147 @@
+package jwt
+
+import (
+	"context"
+	"time"
+)
+
+type verifyOptions struct {
+	issuer     string
+	audience   string
+	leeway     time.Duration
+	algorithms []string
+	now        func() time.Time
+}
+
+// VerifyOption enriches verification options
+type VerifyOption func(*verifyOptions)
+
+// WithIssuer requires the "iss" claim to be equal to issuer
+func WithIssuer(issuer string) VerifyOption {
+	return func(opts *verifyOptions) {
+		opts.issuer = issuer
+	}
+}
+
+// WithAudience requires the "aud" claim to contain audience
+func WithAudience(audience string) VerifyOption {
+	return func(opts *verifyOptions) {
+		opts.audience = audience
+	}
+}
+
+// WithLeeway tolerates clock skew when checking "exp" and "nbf"
+func WithLeeway(leeway time.Duration) VerifyOption {
+	return func(opts *verifyOptions) {
+		opts.leeway = leeway
+	}
+}
+
+// WithAlgorithms restricts accepted signing algorithms
+func WithAlgorithms(algorithms ...string) VerifyOption {
+	return func(opts *verifyOptions) {
+		opts.algorithms = algorithms
+	}
+}
+
+// NewVerifier creates a token verifier that resolves keys with keys
+func NewVerifier(keys KeyProvider, opts ...VerifyOption) *Verifier {
+	v := &Verifier{keys: keys}
+	v.options.now = time.Now
+	for _, opt := range opts {
+		opt(&v.options)
+	}
+	return v
+}
+
+// Verifier checks token signatures and claims
+type Verifier struct {
+	keys    KeyProvider
+	options verifyOptions
+}
+
+// Verify parses raw, checks its signature against the key identified by the "kid" header and validates its claims
+func (v *Verifier) Verify(raw string) (*Token, error) {
+	t, err := Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if !v.algorithmAllowed(t.Header.Alg) {
+		return nil, ErrAlgorithm
+	}
+
+	k, err := v.keys.Key(t.Header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	// prevents algorithm substitution, e.g. HS256 tokens signed with a RSA public key
+	if k.Algorithm != t.Header.Alg {
+		return nil, ErrAlgorithm
+	}
+
+	err = k.verify([]byte(t.signed), t.signature)
+	if err != nil {
+		return nil, err
+	}
+
+	err = v.validate(t.Claims)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (v *Verifier) algorithmAllowed(alg string) bool {
+	if len(v.options.algorithms) == 0 {
+		return alg == HS256 || alg == RS256 || alg == ES256
+	}
+	for _, a := range v.options.algorithms {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *Verifier) validate(c *Claims) error {
+	now := v.options.now()
+
+	if c.ExpiresAt != 0 && now.After(time.Unix(c.ExpiresAt, 0).Add(v.options.leeway)) {
+		return ErrExpired
+	}
+
+	if c.NotBefore != 0 && now.Add(v.options.leeway).Before(time.Unix(c.NotBefore, 0)) {
+		return ErrNotValidYet
+	}
+
+	if v.options.issuer != "" && c.Issuer != v.options.issuer {
+		return ErrIssuer
+	}
+
+	if v.options.audience != "" && !c.Audience.Contains(v.options.audience) {
+		return ErrAudience
+	}
+	return nil
+}
+
+type ctxToken struct{}
+
+// ContextWithToken returns a new context that holds the verified token t
+func ContextWithToken(parent context.Context, t *Token) context.Context {
+	return context.WithValue(parent, ctxToken{}, t)
+}
+
+// TokenFromContext returns the verified token stored in ctx
+func TokenFromContext(ctx context.Context) *Token {
+	o := ctx.Value(ctxToken{})
+	if o == nil {
+		return nil
+	}
+	t, ok := o.(*Token)
+	if !ok {
+		return nil
+	}
+	return t
+}
@@ -0,0 +1,264 @@
+package netx
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/omecodes/common/utils/ratelimit"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	rejectMaxConnections = "max_connections"
+	rejectMaxPerIP       = "max_connections_per_ip"
+	rejectRate           = "accept_rate"
+)
+
+var (
+	rejectedConnections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "netx",
+		Name:      "rejected_connections_total",
+		Help:      "Number of connections closed by listeners limits.",
+	}, []string{"address", "reason"})
+
+	openConnections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "netx",
+		Name:      "open_connections",
+		Help:      "Number of connections accepted by limited listeners that are still open.",
+	}, []string{"address"})
+)
+
+func init() {
+	prometheus.MustRegister(rejectedConnections, openConnections)
+}
+
+// MaxConnections closes new connections while max connections are open
+func MaxConnections(max int) ListenOption {
+	return func(opts *ListenOptions) {
+		opts.MaxConnections = max
+	}
+}
+
+// MaxConnectionsPerIP closes new connections from a peer IP while it has max connections open.
+// Limits apply to the direct peer, which is the proxy when PROXY protocol is used
+func MaxConnectionsPerIP(max int) ListenOption {
+	return func(opts *ListenOptions) {
+		opts.MaxConnectionsPerIP = max
+	}
+}
+
+// AcceptRate closes new connections accepted above perSecond, allowing bursts of burst connections
+func AcceptRate(perSecond float64, burst int) ListenOption {
+	return func(opts *ListenOptions) {
+		opts.AcceptRate = perSecond
+		opts.AcceptBurst = burst
+	}
+}
+
+// KeepAlive sets the TCP keep-alive period. Keep-alives are disabled if period is negative
+func KeepAlive(period time.Duration) ListenOption {
+	return func(opts *ListenOptions) {
+		opts.KeepAlive = period
+	}
+}
+
+// ReadTimeout fails each read that takes longer than timeout
+func ReadTimeout(timeout time.Duration) ListenOption {
+	return func(opts *ListenOptions) {
+		opts.ReadTimeout = timeout
+	}
+}
+
+// IdleTimeout fails reads and writes that make no progress during timeout, which ends idle connections
+func IdleTimeout(timeout time.Duration) ListenOption {
+	return func(opts *ListenOptions) {
+		opts.IdleTimeout = timeout
+	}
+}
+
+func (opts *ListenOptions) limited() bool {
+	return opts.MaxConnections > 0 || opts.MaxConnectionsPerIP > 0 || opts.AcceptRate > 0 ||
+		opts.ReadTimeout > 0 || opts.IdleTimeout > 0
+}
+
+func newLimitListener(l net.Listener, opts *ListenOptions) net.Listener {
+	ll := &limitListener{
+		Listener:    l,
+		address:     l.Addr().String(),
+		max:         opts.MaxConnections,
+		maxPerIP:    opts.MaxConnectionsPerIP,
+		readTimeout: opts.ReadTimeout,
+		idleTimeout: opts.IdleTimeout,
+		perIP:       map[string]int{},
+	}
+	if opts.AcceptRate > 0 {
+		ll.rate = ratelimit.NewBucket(opts.AcceptRate, opts.AcceptBurst)
+	}
+	return ll
+}
+
+type limitListener struct {
+	net.Listener
+	sync.Mutex
+	address     string
+	max         int
+	maxPerIP    int
+	rate        *ratelimit.Bucket
+	readTimeout time.Duration
+	idleTimeout time.Duration
+	count       int
+	perIP       map[string]int
+}
+
+func (l *limitListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		ip := peerIP(conn.RemoteAddr())
+		if reason := l.acquire(ip); reason != "" {
+			rejectedConnections.WithLabelValues(l.address, reason).Inc()
+			_ = conn.Close()
+			continue
+		}
+
+		openConnections.WithLabelValues(l.address).Inc()
+		return &limitConn{
+			Conn:        conn,
+			listener:    l,
+			ip:          ip,
+			readTimeout: l.readTimeout,
+			idleTimeout: l.idleTimeout,
+		}, nil
+	}
+}
+
+func (l *limitListener) acquire(ip string) string {
+	if l.rate != nil && !l.rate.Allow() {
+		return rejectRate
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.max > 0 && l.count >= l.max {
+		return rejectMaxConnections
+	}
+
+	if l.maxPerIP > 0 && ip != "" && l.perIP[ip] >= l.maxPerIP {
+		return rejectMaxPerIP
+	}
+
+	l.count++
+	if ip != "" {
+		l.perIP[ip]++
+	}
+	return ""
+}
+
+func (l *limitListener) release(ip string) {
+	openConnections.WithLabelValues(l.address).Dec()
+
+	l.Lock()
+	defer l.Unlock()
+
+	l.count--
+	if ip == "" {
+		return
+	}
+
+	l.perIP[ip]--
+	if l.perIP[ip] <= 0 {
+		delete(l.perIP, ip)
+	}
+}
+
+func peerIP(addr net.Addr) string {
+	if tcpAddr, ok := addr.(*net.TCPAddr); ok {
+		return tcpAddr.IP.String()
+	}
+	return ""
+}
+
+// limitConn releases its slot on close and applies read and idle timeouts.
+// Deadlines set by users of the connection are kept when they are earlier
+type limitConn struct {
+	net.Conn
+	sync.Mutex
+	listener      *limitListener
+	ip            string
+	readTimeout   time.Duration
+	idleTimeout   time.Duration
+	readDeadline  time.Time
+	writeDeadline time.Time
+	closeOnce     sync.Once
+}
+
+func earliest(deadline time.Time, timeout time.Duration) time.Time {
+	if timeout <= 0 {
+		return deadline
+	}
+
+	t := time.Now().Add(timeout)
+	if deadline.IsZero() || t.Before(deadline) {
+		return t
+	}
+	return deadline
+}
+
+func (c *limitConn) Read(b []byte) (int, error) {
+	if c.readTimeout > 0 || c.idleTimeout > 0 {
+		c.Lock()
+		deadline := earliest(earliest(c.readDeadline, c.readTimeout), c.idleTimeout)
+		err := c.Conn.SetReadDeadline(deadline)
+		c.Unlock()
+		if err != nil {
+			return 0, err
+		}
+	}
+	return c.Conn.Read(b)
+}
+
+func (c *limitConn) Write(b []byte) (int, error) {
+	if c.idleTimeout > 0 {
+		c.Lock()
+		err := c.Conn.SetWriteDeadline(earliest(c.writeDeadline, c.idleTimeout))
+		c.Unlock()
+		if err != nil {
+			return 0, err
+		}
+	}
+	return c.Conn.Write(b)
+}
+
+func (c *limitConn) SetDeadline(t time.Time) error {
+	c.Lock()
+	defer c.Unlock()
+	c.readDeadline = t
+	c.writeDeadline = t
+	return c.Conn.SetDeadline(t)
+}
+
+func (c *limitConn) SetReadDeadline(t time.Time) error {
+	c.Lock()
+	defer c.Unlock()
+	c.readDeadline = t
+	return c.Conn.SetReadDeadline(t)
+}
+
+func (c *limitConn) SetWriteDeadline(t time.Time) error {
+	c.Lock()
+	defer c.Unlock()
+	c.writeDeadline = t
+	return c.Conn.SetWriteDeadline(t)
+}
+
+func (c *limitConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.listener.release(c.ip)
+	})
+	return c.Conn.Close()
+}
@@ -0,0 +1,86 @@
+package netx
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestMaxConnections(t *testing.T) {
+	l, err := Listen("127.0.0.1:", MaxConnections(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	accepted := make(chan net.Conn, 2)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	first, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	serverConn := <-accepted
+
+	second, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+
+	_ = second.SetReadDeadline(time.Now().Add(time.Second * 2))
+	if _, err := second.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatal("connections above the limit must be closed", err)
+	}
+
+	_ = serverConn.Close()
+	third, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer third.Close()
+
+	select {
+	case conn := <-accepted:
+		_ = conn.Close()
+	case <-time.After(time.Second * 2):
+		t.Fatal("closing a connection must release its slot")
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	l, err := Listen("127.0.0.1:", IdleTimeout(time.Millisecond*100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go func() {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err == nil {
+			time.Sleep(time.Second)
+			_ = conn.Close()
+		}
+	}()
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	_, err = conn.Read(make([]byte, 1))
+	if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+		t.Fatal("idle reads must time out", err)
+	}
+}
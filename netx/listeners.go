@@ -0,0 +1,142 @@
+package netx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	schemeUnix    = "unix://"
+	schemeSystemd = "systemd://"
+
+	// systemd passes inherited sockets starting at this file descriptor
+	listenFdsStart = 3
+)
+
+// ErrNoInheritedListener is returned when no socket matching a systemd:// address was passed by systemd
+var ErrNoInheritedListener = errors.New("netx: no inherited listener")
+
+// SocketMode sets the permissions of unix socket files
+func SocketMode(mode os.FileMode) ListenOption {
+	return func(opts *ListenOptions) {
+		opts.SocketMode = mode
+	}
+}
+
+// listen creates the base listener of address:
+// "unix:///path" listens to a unix socket, "systemd://" takes the next socket passed by systemd
+// and "systemd://name" the socket named name. Other addresses are TCP addresses
+func listen(address string, opts *ListenOptions) (net.Listener, error) {
+	switch {
+	case strings.HasPrefix(address, schemeUnix):
+		return listenUnix(strings.TrimPrefix(address, schemeUnix), opts.SocketMode)
+
+	case strings.HasPrefix(address, schemeSystemd):
+		return inheritedListener(strings.TrimPrefix(address, schemeSystemd))
+
+	default:
+		if address == "" {
+			address = ":"
+		}
+		lc := net.ListenConfig{KeepAlive: opts.KeepAlive}
+		return lc.Listen(context.Background(), "tcp", address)
+	}
+}
+
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	// removes the socket file left by a previous process
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		_ = os.Remove(path)
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode != 0 {
+		err = os.Chmod(path, mode)
+		if err != nil {
+			_ = l.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+type inherited struct {
+	name     string
+	listener net.Listener
+	taken    bool
+}
+
+var (
+	inheritedOnce      sync.Once
+	inheritedMutex     sync.Mutex
+	inheritedListeners []*inherited
+)
+
+func loadInheritedListeners() {
+	defer func() {
+		_ = os.Unsetenv("LISTEN_PID")
+		_ = os.Unsetenv("LISTEN_FDS")
+		_ = os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	for i := 0; i < count; i++ {
+		name := fmt.Sprintf("LISTEN_FD_%d", listenFdsStart+i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		f := os.NewFile(uintptr(listenFdsStart+i), name)
+		l, err := net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			continue
+		}
+		inheritedListeners = append(inheritedListeners, &inherited{name: name, listener: l})
+	}
+}
+
+func inheritedListener(name string) (net.Listener, error) {
+	inheritedOnce.Do(loadInheritedListeners)
+
+	inheritedMutex.Lock()
+	defer inheritedMutex.Unlock()
+
+	for _, i := range inheritedListeners {
+		if i.taken || (name != "" && i.name != name) {
+			continue
+		}
+		i.taken = true
+		return i.listener, nil
+	}
+	return nil, ErrNoInheritedListener
+}
+
+// DialAddress returns the address clients use to dial l. Unix socket addresses are prefixed with "unix://"
+func DialAddress(l net.Listener) string {
+	addr := l.Addr()
+	if addr.Network() == "unix" {
+		return schemeUnix + addr.String()
+	}
+	return addr.String()
+}
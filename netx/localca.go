@@ -0,0 +1,322 @@
+package netx
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/omecodes/common/futils"
+	crypto2 "github.com/omecodes/libome/crypt"
+)
+
+const (
+	caCertFilename = "ca.crt"
+	caKeyFilename  = "ca.key"
+	leavesDirname  = "leaves"
+
+	defaultCAValidity   = time.Hour * 24 * 365 * 10
+	defaultLeafValidity = time.Hour * 24 * 30
+)
+
+// ErrInvalidCertificateName is returned when a certificate name cannot be used as a file name
+var ErrInvalidCertificateName = errors.New("netx: invalid certificate name")
+
+var leafNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// LocalCAOption enriches local CA options
+type LocalCAOption func(ca *LocalCA)
+
+// LeafValidity sets the validity duration of issued certificates
+func LeafValidity(d time.Duration) LocalCAOption {
+	return func(ca *LocalCA) {
+		ca.leafValidity = d
+	}
+}
+
+// Organization sets the organization of the CA and of issued certificates
+func Organization(name string) LocalCAOption {
+	return func(ca *LocalCA) {
+		ca.organization = name
+	}
+}
+
+// NewLocalCA loads the CA persisted in dir, or generates and persists a new one.
+// Issued certificates are persisted in the leaves sub directory and renewed when a third of their validity is left
+func NewLocalCA(dir string, opts ...LocalCAOption) (*LocalCA, error) {
+	ca := &LocalCA{
+		dir:          dir,
+		organization: "Ome",
+		leafValidity: defaultLeafValidity,
+		leaves:       map[string]*tls.Certificate{},
+	}
+	for _, opt := range opts {
+		opt(ca)
+	}
+
+	err := os.MkdirAll(filepath.Join(dir, leavesDirname), 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ca.loadOrCreate()
+	if err != nil {
+		return nil, err
+	}
+	return ca, nil
+}
+
+// LocalCA is a certificate authority persisted on disk, used to secure dev boxes and tests without manual steps
+type LocalCA struct {
+	sync.Mutex
+	dir          string
+	organization string
+	leafValidity time.Duration
+	cert         *x509.Certificate
+	key          crypto.PrivateKey
+	leaves       map[string]*tls.Certificate
+}
+
+func (ca *LocalCA) loadOrCreate() error {
+	certFilename := filepath.Join(ca.dir, caCertFilename)
+	keyFilename := filepath.Join(ca.dir, caKeyFilename)
+
+	if futils.FileExists(certFilename) && futils.FileExists(keyFilename) {
+		cert, err := crypto2.LoadCertificate(certFilename)
+		if err != nil {
+			return err
+		}
+
+		key, err := crypto2.LoadPrivateKey(nil, keyFilename)
+		if err != nil {
+			return err
+		}
+
+		if time.Now().Before(cert.NotAfter) {
+			ca.cert = cert
+			ca.key = key
+			return nil
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	cert, err := crypto2.GenerateCACertificate(&crypto2.CertificateTemplate{
+		Organization:     ca.organization,
+		Name:             ca.organization + " local CA",
+		Expiry:           defaultCAValidity,
+		PublicKey:        key.Public(),
+		SignerPrivateKey: key,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = storeCertificate(cert, certFilename)
+	if err != nil {
+		return err
+	}
+
+	err = crypto2.StorePrivateKey(key, nil, keyFilename)
+	if err != nil {
+		return err
+	}
+
+	ca.cert = cert
+	ca.key = key
+	return nil
+}
+
+// Certificate returns the CA certificate
+func (ca *LocalCA) Certificate() *x509.Certificate {
+	return ca.cert
+}
+
+// CertificateFilename returns the path of the PEM encoded CA certificate
+func (ca *LocalCA) CertificateFilename() string {
+	return filepath.Join(ca.dir, caCertFilename)
+}
+
+// Pool returns a pool that contains the CA certificate
+func (ca *LocalCA) Pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+// Issue returns a valid certificate for name. hosts are DNS names or IP addresses added as SANs.
+// name is made of letters, digits, '.', '_' and '-'. A persisted certificate is reused until it must be
+// renewed or does not cover hosts
+func (ca *LocalCA) Issue(name string, hosts ...string) (*tls.Certificate, error) {
+	if !leafNamePattern.MatchString(name) {
+		return nil, ErrInvalidCertificateName
+	}
+
+	ca.Lock()
+	defer ca.Unlock()
+
+	cacheKey := name + "\x00" + strings.Join(hosts, ",")
+	if leaf, ok := ca.leaves[cacheKey]; ok && !ca.needsRenewal(leaf.Leaf) {
+		return leaf, nil
+	}
+
+	certFilename := filepath.Join(ca.dir, leavesDirname, name+".crt")
+	keyFilename := filepath.Join(ca.dir, leavesDirname, name+".key")
+
+	if futils.FileExists(certFilename) && futils.FileExists(keyFilename) {
+		leaf, err := ca.load(certFilename, keyFilename)
+		if err == nil && !ca.needsRenewal(leaf.Leaf) && coversHosts(leaf.Leaf, hosts) {
+			ca.leaves[cacheKey] = leaf
+			return leaf, nil
+		}
+	}
+
+	leaf, err := ca.generate(name, hosts, certFilename, keyFilename)
+	if err != nil {
+		return nil, err
+	}
+	ca.leaves[cacheKey] = leaf
+	return leaf, nil
+}
+
+func (ca *LocalCA) needsRenewal(cert *x509.Certificate) bool {
+	lifetime := cert.NotAfter.Sub(cert.NotBefore)
+	return time.Now().Add(lifetime / 3).After(cert.NotAfter)
+}
+
+func (ca *LocalCA) load(certFilename, keyFilename string) (*tls.Certificate, error) {
+	cert, err := crypto2.LoadCertificate(certFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := crypto2.LoadPrivateKey(nil, keyFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cert.CheckSignatureFrom(ca.cert)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{Certificate: [][]byte{cert.Raw}, PrivateKey: key, Leaf: cert}, nil
+}
+
+func (ca *LocalCA) generate(name string, hosts []string, certFilename, keyFilename string) (*tls.Certificate, error) {
+	if len(hosts) == 0 {
+		hosts = []string{"localhost", "127.0.0.1", "::1"}
+	}
+
+	var (
+		domains []string
+		ips     []net.IP
+	)
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			ips = append(ips, ip)
+		} else {
+			domains = append(domains, host)
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	generated, err := crypto2.GenerateServiceCertificate(&crypto2.CertificateTemplate{
+		Organization:      ca.organization,
+		Name:              name,
+		Domains:           domains,
+		IPs:               ips,
+		Expiry:            ca.leafValidity,
+		PublicKey:         key.Public(),
+		SignerPrivateKey:  ca.key,
+		SignerCertificate: ca.cert,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := x509.ParseCertificate(generated.Raw)
+	if err != nil {
+		return nil, err
+	}
+
+	err = storeCertificate(cert, certFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	err = crypto2.StorePrivateKey(key, nil, keyFilename)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{Certificate: [][]byte{cert.Raw}, PrivateKey: key, Leaf: cert}, nil
+}
+
+// ServerTLS returns a tls config that serves the certificate issued for name and hosts, renewed before it expires
+func (ca *LocalCA) ServerTLS(name string, hosts ...string) *tls.Config {
+	return &tls.Config{
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return ca.Issue(name, hosts...)
+		},
+	}
+}
+
+// ClientTLS returns a tls config that trusts the CA. It presents a certificate issued for "client"
+// when the server requests one
+func (ca *LocalCA) ClientTLS() *tls.Config {
+	return &tls.Config{
+		RootCAs: ca.Pool(),
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return ca.Issue("client")
+		},
+	}
+}
+
+// LocalCertificates secures the listener with a certificate issued by ca for name and hosts.
+// Internal clients trust ca and, when client authentication is required, are verified against it
+func LocalCertificates(ca *LocalCA, name string, hosts ...string) ListenOption {
+	return func(opts *ListenOptions) {
+		opts.Secure = true
+		opts.TLS = ca.ServerTLS(name, hosts...)
+		opts.CertFilename = ""
+		opts.KeyFilename = ""
+		opts.LocalCA = ca
+		if opts.ClientCAs == nil && opts.ClientCAsFilename == "" {
+			opts.ClientCAs = ca.Pool()
+		}
+	}
+}
+
+func coversHosts(cert *x509.Certificate, hosts []string) bool {
+	for _, host := range hosts {
+		if cert.VerifyHostname(strings.Trim(host, "[]")) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func storeCertificate(cert *x509.Certificate, filename string) error {
+	data, err := crypto2.PEMEncodeCertificate(cert)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0644)
+}
@@ -0,0 +1,133 @@
+package netx
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestLocalCA(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netx-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca, err := NewLocalCA(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := Listen("127.0.0.1:", LocalCertificates(ca, "server", "localhost", "127.0.0.1"), ClientAuth(tls.RequireAndVerifyClientCert))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	var lopts ListenOptions
+	LocalCertificates(ca, "server")(&lopts)
+	clientConfig, err := lopts.ClientTLS()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	principal, err := handshake(t, l, clientConfig)
+	if principal == nil || principal.CommonName != "client" {
+		t.Fatal("handshake with local CA certificates failed:", err)
+	}
+
+	reloaded, err := NewLocalCA(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reloaded.Certificate().Equal(ca.Certificate()) {
+		t.Fatal("persisted CA must be reused")
+	}
+}
+
+func TestLocalCARenewal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netx-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca, err := NewLocalCA(dir, LeafValidity(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := ca.Issue("server")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	same, err := ca.Issue("server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same.Leaf.SerialNumber.Cmp(first.Leaf.SerialNumber) != 0 {
+		t.Fatal("valid certificate must be reused")
+	}
+
+	ca.leafValidity = time.Second
+	ca.leaves = map[string]*tls.Certificate{}
+	short, err := ca.Issue("short")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(time.Second)
+	renewed, err := ca.Issue("short")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewed.Leaf.SerialNumber.Cmp(short.Leaf.SerialNumber) == 0 {
+		t.Fatal("certificate close to expiry must be renewed")
+	}
+}
+
+func TestLocalCAIssue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netx-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca, err := NewLocalCA(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"", "../server", "a/b", ".hidden"} {
+		if _, err = ca.Issue(name); err != ErrInvalidCertificateName {
+			t.Fatal("invalid name must be rejected", name, err)
+		}
+	}
+
+	// leaves do not overwrite the CA files
+	if _, err = ca.Issue("ca"); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := NewLocalCA(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reloaded.Certificate().Equal(ca.Certificate()) {
+		t.Fatal("issued certificate must not replace the CA")
+	}
+
+	local, err := ca.Issue("server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ca.Issue("server", "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Leaf.VerifyHostname("example.com") != nil || local.Leaf.VerifyHostname("localhost") != nil {
+		t.Fatal("issued certificates must cover the requested hosts")
+	}
+}
@@ -0,0 +1,245 @@
+package netx
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"time"
+
+	crypto2 "github.com/omecodes/libome/crypt"
+)
+
+var (
+	ErrCertificateRevoked   = errors.New("netx: client certificate is revoked")
+	ErrClientNameNotAllowed = errors.New("netx: client certificate name is not allowed")
+	ErrCRLExpired           = errors.New("netx: certificate revocation list is expired")
+)
+
+// revocationList is a CRL with its revoked serials. Its signature is checked against the issuer of
+// the verified chain before it is used
+type revocationList struct {
+	issuer  string
+	list    *pkix.CertificateList
+	revoked map[string]bool
+}
+
+func newRevocationList(filename string) (*revocationList, error) {
+	list, err := loadCRL(filename)
+	if err != nil {
+		return nil, err
+	}
+	if list.HasExpired(time.Now()) {
+		return nil, errors.New("netx: certificate revocation list " + filename + " is expired")
+	}
+
+	rl := &revocationList{
+		issuer:  list.TBSCertList.Issuer.String(),
+		list:    list,
+		revoked: map[string]bool{},
+	}
+	for _, rc := range list.TBSCertList.RevokedCertificates {
+		rl.revoked[serial(rc.SerialNumber)] = true
+	}
+	return rl, nil
+}
+
+// check tells whether cert, issued by issuer, is revoked by the list. Lists of other issuers are ignored
+func (rl *revocationList) check(cert, issuer *x509.Certificate) error {
+	if rl.issuer != issuer.Subject.ToRDNSequence().String() {
+		return nil
+	}
+	if err := issuer.CheckCRLSignature(rl.list); err != nil {
+		return err
+	}
+	if rl.list.HasExpired(time.Now()) {
+		return ErrCRLExpired
+	}
+	if rl.revoked[serial(cert.SerialNumber)] {
+		return ErrCertificateRevoked
+	}
+	return nil
+}
+
+func (opts *ListenOptions) applyClientAuth(tc *tls.Config) error {
+	if opts.ClientAuth != tls.NoClientCert {
+		tc.ClientAuth = opts.ClientAuth
+	}
+
+	if opts.ClientCAs != nil {
+		tc.ClientCAs = opts.ClientCAs
+	}
+
+	if opts.ClientCAsFilename != "" {
+		pool, err := LoadCertPool(opts.ClientCAsFilename)
+		if err != nil {
+			return err
+		}
+		tc.ClientCAs = pool
+	}
+
+	if len(opts.CRLFilenames) == 0 && len(opts.AllowedClientNames) == 0 {
+		return nil
+	}
+
+	var lists []*revocationList
+	for _, filename := range opts.CRLFilenames {
+		rl, err := newRevocationList(filename)
+		if err != nil {
+			return err
+		}
+		lists = append(lists, rl)
+	}
+
+	allowed := map[string]bool{}
+	for _, name := range opts.AllowedClientNames {
+		allowed[name] = true
+	}
+
+	tc.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		// each certificate is checked against the lists of its issuer, the next one in the chain
+		for _, chain := range verifiedChains {
+			for i := 0; i < len(chain)-1; i++ {
+				for _, rl := range lists {
+					if err := rl.check(chain[i], chain[i+1]); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		if len(allowed) == 0 || len(verifiedChains) == 0 {
+			return nil
+		}
+
+		for _, name := range certificateNames(verifiedChains[0][0]) {
+			if allowed[name] {
+				return nil
+			}
+		}
+		return ErrClientNameNotAllowed
+	}
+	return nil
+}
+
+// ClientTLS builds a tls config for internal clients, like the gRPC gateway, that dial the listener.
+// The server certificate is verified against the local CA, CAFilename, or against itself when it is self-signed
+// and not reloaded.
+// It is also presented as client certificate when client authentication is enabled
+func (opts *ListenOptions) ClientTLS() (*tls.Config, error) {
+	if opts.LocalCA != nil {
+		return opts.LocalCA.ClientTLS(), nil
+	}
+
+	if opts.Reloader != nil {
+		return opts.reloaderClientTLS()
+	}
+
+	tc := &tls.Config{}
+
+	var serverCert *tls.Certificate
+	if opts.CertFilename != "" && opts.KeyFilename != "" {
+		cert, err := crypto2.LoadCertificate(opts.CertFilename)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := crypto2.LoadPrivateKey(opts.KeyPassword, opts.KeyFilename)
+		if err != nil {
+			return nil, err
+		}
+		serverCert = &tls.Certificate{Certificate: [][]byte{cert.Raw}, PrivateKey: key, Leaf: cert}
+
+	} else if opts.TLS != nil && len(opts.TLS.Certificates) > 0 {
+		serverCert = &opts.TLS.Certificates[0]
+		tc.RootCAs = opts.TLS.RootCAs
+	}
+
+	if opts.CAFilename != "" {
+		pool, err := LoadCertPool(opts.CAFilename)
+		if err != nil {
+			return nil, err
+		}
+		tc.RootCAs = pool
+
+	} else if tc.RootCAs == nil && serverCert != nil {
+		pool := x509.NewCertPool()
+		for _, raw := range serverCert.Certificate {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return nil, err
+			}
+			pool.AddCert(cert)
+		}
+		tc.RootCAs = pool
+	}
+
+	if opts.ClientAuth != tls.NoClientCert && serverCert != nil {
+		tc.Certificates = []tls.Certificate{*serverCert}
+	}
+	return tc, nil
+}
+
+// LoadCertPool loads all the PEM encoded certificates of filename in a pool
+func LoadCertPool(filename string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New("netx: no certificate found in " + filename)
+	}
+	return pool, nil
+}
+
+func loadCRL(filename string) (*pkix.CertificateList, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	}
+	return x509.ParseDERCRL(data)
+}
+
+func serial(n *big.Int) string {
+	if n == nil {
+		return ""
+	}
+	return n.String()
+}
+
+func certificateNames(cert *x509.Certificate) []string {
+	names := []string{cert.Subject.CommonName}
+	names = append(names, cert.DNSNames...)
+	names = append(names, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	return names
+}
+
+// reloaderClientTLS verifies the server certificate against CAFilename, or the system roots when it is not set.
+// The certificate is not pinned since it can be rotated at any time. It also presents the current certificate
+// when client authentication is enabled
+func (opts *ListenOptions) reloaderClientTLS() (*tls.Config, error) {
+	tc := &tls.Config{}
+	if opts.CAFilename != "" {
+		pool, err := LoadCertPool(opts.CAFilename)
+		if err != nil {
+			return nil, err
+		}
+		tc.RootCAs = pool
+	}
+	if opts.ClientAuth != tls.NoClientCert {
+		tc.GetClientCertificate = opts.Reloader.GetClientCertificate
+	}
+	return tc, nil
+}
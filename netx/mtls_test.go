@@ -0,0 +1,253 @@
+package netx
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	crypto2 "github.com/omecodes/libome/crypt"
+)
+
+type testPKI struct {
+	dir       string
+	caCert    *x509.Certificate
+	caKey     *ecdsa.PrivateKey
+	caFile    string
+	certFile  string
+	keyFile   string
+	clientTLS tls.Certificate
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	dir, err := ioutil.TempDir("", "netx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &testPKI{dir: dir}
+	p.caKey, err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.caCert, err = crypto2.GenerateCACertificate(&crypto2.CertificateTemplate{
+		Organization:     "Ome",
+		Name:             "test CA",
+		Expiry:           time.Hour,
+		PublicKey:        p.caKey.Public(),
+		SignerPrivateKey: p.caKey,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.caFile = filepath.Join(dir, "ca.crt")
+	err = crypto2.StoreCertificate(p.caCert, p.caFile, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serverCert, serverKey := p.issue(t, "server")
+	p.certFile = filepath.Join(dir, "server.crt")
+	p.keyFile = filepath.Join(dir, "server.key")
+	err = crypto2.StoreCertificate(serverCert, p.certFile, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = crypto2.StorePrivateKey(serverKey, nil, p.keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientCert, clientKey := p.issue(t, "client")
+	p.clientTLS = tls.Certificate{Certificate: [][]byte{clientCert.Raw}, PrivateKey: clientKey}
+	return p
+}
+
+func (p *testPKI) issue(t *testing.T, name string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := crypto2.GenerateServiceCertificate(&crypto2.CertificateTemplate{
+		Organization:      "Ome",
+		Name:              name,
+		Domains:           []string{name},
+		IPs:               []net.IP{net.ParseIP("127.0.0.1")},
+		Expiry:            time.Hour,
+		PublicKey:         key.Public(),
+		SignerPrivateKey:  p.caKey,
+		SignerCertificate: p.caCert,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func handshake(t *testing.T, l net.Listener, clientConfig *tls.Config) (*Principal, error) {
+	result := make(chan *Principal, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			result <- nil
+			return
+		}
+		defer conn.Close()
+
+		tc := conn.(*tls.Conn)
+		if tc.Handshake() != nil {
+			result <- nil
+			return
+		}
+		state := tc.ConnectionState()
+		result <- PrincipalFromConnectionState(&state)
+	}()
+
+	conn, err := tls.Dial("tcp", l.Addr().String(), clientConfig)
+	if err == nil {
+		// forces the client to wait for the server verdict on its certificate
+		_, err = conn.Read(make([]byte, 1))
+		_ = conn.Close()
+	}
+	return <-result, err
+}
+
+func TestMutualTLS(t *testing.T) {
+	pki := newTestPKI(t)
+	defer os.RemoveAll(pki.dir)
+
+	l, err := Listen("127.0.0.1:", Secure(pki.certFile, pki.keyFile), MutualTLS(pki.caFile), CA(pki.caFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(pki.caCert)
+
+	principal, _ := handshake(t, l, &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{pki.clientTLS}})
+	if principal == nil || principal.CommonName != "client" {
+		t.Fatal("expected client principal")
+	}
+
+	principal, _ = handshake(t, l, &tls.Config{RootCAs: roots})
+	if principal != nil {
+		t.Fatal("connection without client certificate must be rejected")
+	}
+
+	var lopts ListenOptions
+	for _, opt := range []ListenOption{Secure(pki.certFile, pki.keyFile), MutualTLS(pki.caFile), CA(pki.caFile)} {
+		opt(&lopts)
+	}
+	internal, err := lopts.ClientTLS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	principal, _ = handshake(t, l, internal)
+	if principal == nil || principal.CommonName != "server" {
+		t.Fatal("internal client must authenticate with the server certificate")
+	}
+}
+
+func TestAllowedClientNames(t *testing.T) {
+	pki := newTestPKI(t)
+	defer os.RemoveAll(pki.dir)
+
+	l, err := Listen("127.0.0.1:", Secure(pki.certFile, pki.keyFile), MutualTLS(pki.caFile), AllowedClientNames("other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(pki.caCert)
+
+	principal, _ := handshake(t, l, &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{pki.clientTLS}})
+	if principal != nil {
+		t.Fatal("client name must not be allowed")
+	}
+}
+
+func TestCRL(t *testing.T) {
+	pki := newTestPKI(t)
+	defer os.RemoveAll(pki.dir)
+
+	client, err := x509.ParseCertificate(pki.clientTLS.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storeCRL := func(name string, signer *testPKI, nextUpdate time.Time) string {
+		der, err := signer.caCert.CreateCRL(rand.Reader, signer.caKey, []pkix.RevokedCertificate{
+			{SerialNumber: client.SerialNumber, RevocationTime: time.Now()},
+		}, time.Now().Add(-time.Hour), nextUpdate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		filename := filepath.Join(pki.dir, name)
+		if err = ioutil.WriteFile(filename, der, 0600); err != nil {
+			t.Fatal(err)
+		}
+		return filename
+	}
+
+	other := &testPKI{}
+	other.caKey, err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.caCert, err = crypto2.GenerateCACertificate(&crypto2.CertificateTemplate{
+		Organization:     "Ome",
+		Name:             "other CA",
+		Expiry:           time.Hour,
+		PublicKey:        other.caKey.Public(),
+		SignerPrivateKey: other.caKey,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(pki.caCert)
+	clientConfig := &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{pki.clientTLS}}
+
+	// the serial revoked by another authority does not apply
+	l, err := Listen("127.0.0.1:", Secure(pki.certFile, pki.keyFile), MutualTLS(pki.caFile),
+		CRL(storeCRL("other.crl", other, time.Now().Add(time.Hour))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	principal, _ := handshake(t, l, clientConfig)
+	_ = l.Close()
+	if principal == nil {
+		t.Fatal("revocation by another issuer must be ignored")
+	}
+
+	l, err = Listen("127.0.0.1:", Secure(pki.certFile, pki.keyFile), MutualTLS(pki.caFile),
+		CRL(storeCRL("ca.crl", pki, time.Now().Add(time.Hour))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	principal, _ = handshake(t, l, clientConfig)
+	_ = l.Close()
+	if principal != nil {
+		t.Fatal("revoked client certificate must be rejected")
+	}
+
+	_, err = Listen("127.0.0.1:", Secure(pki.certFile, pki.keyFile), MutualTLS(pki.caFile),
+		CRL(storeCRL("stale.crl", pki, time.Now().Add(-time.Minute))))
+	if err == nil {
+		t.Fatal("expired revocation list must be rejected")
+	}
+}
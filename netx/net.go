@@ -5,6 +5,8 @@ import (
 	"crypto/x509"
 	crypto2 "github.com/omecodes/libome/crypt"
 	"net"
+	"os"
+	"time"
 )
 
 type ListenOptions struct {
@@ -14,6 +16,37 @@ type ListenOptions struct {
 	TLS          *tls.Config
 	Secure       bool
 	KeyPassword  []byte
+
+	// CAFilename is the certificate of the authority that signed the server certificate.
+	// Internal clients like the gRPC gateway use it to verify the server
+	CAFilename string
+
+	// LocalCA is set when certificates are issued by a local CA
+	LocalCA *LocalCA
+
+	// Reloader is set when the certificate can be reloaded while listening
+	Reloader *CertificateReloader
+
+	ClientAuth         tls.ClientAuthType
+	ClientCAsFilename  string
+	ClientCAs          *x509.CertPool
+	CRLFilenames       []string
+	AllowedClientNames []string
+
+	// SocketMode is applied to unix socket files
+	SocketMode os.FileMode
+
+	// ProxyProtocol enables PROXY protocol headers parsing for peers of ProxyTrusted
+	ProxyProtocol bool
+	ProxyTrusted  []string
+
+	MaxConnections      int
+	MaxConnectionsPerIP int
+	AcceptRate          float64
+	AcceptBurst         int
+	KeepAlive           time.Duration
+	ReadTimeout         time.Duration
+	IdleTimeout         time.Duration
 }
 
 // ListenOption enriches listen options object
@@ -33,6 +66,8 @@ func TLS(tc *tls.Config) ListenOption {
 		opts.TLS = tc
 		opts.CertFilename = ""
 		opts.KeyFilename = ""
+		opts.LocalCA = nil
+		opts.Reloader = nil
 	}
 }
 
@@ -43,6 +78,8 @@ func Secure(certFilename, keyFilename string) ListenOption {
 		opts.TLS = nil
 		opts.CertFilename = certFilename
 		opts.KeyFilename = keyFilename
+		opts.LocalCA = nil
+		opts.Reloader = nil
 	}
 }
 
@@ -53,30 +90,81 @@ func KeyPassword(password []byte) ListenOption {
 	}
 }
 
-// Listen listen to tcp connections
-func Listen(address string, opts ...ListenOption) (net.Listener, error) {
-	var lopts ListenOptions
-	for _, opt := range opts {
-		opt(&lopts)
+// CA specify the certificate of the authority that signed the server certificate
+func CA(caFilename string) ListenOption {
+	return func(opts *ListenOptions) {
+		opts.CAFilename = caFilename
 	}
+}
 
-	if address == "" {
-		address = ":"
+// MutualTLS requires clients to present a certificate signed by one of the CAs of the PEM bundle caBundleFilename
+func MutualTLS(caBundleFilename string) ListenOption {
+	return func(opts *ListenOptions) {
+		opts.ClientAuth = tls.RequireAndVerifyClientCert
+		opts.ClientCAsFilename = caBundleFilename
 	}
+}
 
-	if lopts.CertFilename != "" && lopts.KeyFilename != "" {
+// ClientAuth sets the client certificate policy
+func ClientAuth(clientAuth tls.ClientAuthType) ListenOption {
+	return func(opts *ListenOptions) {
+		opts.ClientAuth = clientAuth
+	}
+}
 
-		cert, err := crypto2.LoadCertificate(lopts.CertFilename)
+// ClientCAs sets the pool of authorities client certificates are verified against
+func ClientCAs(pool *x509.CertPool) ListenOption {
+	return func(opts *ListenOptions) {
+		opts.ClientCAs = pool
+	}
+}
+
+// CRL rejects client certificates revoked by one of the PEM or DER encoded revocation lists
+func CRL(filenames ...string) ListenOption {
+	return func(opts *ListenOptions) {
+		opts.CRLFilenames = append(opts.CRLFilenames, filenames...)
+	}
+}
+
+// AllowedClientNames only accepts client certificates whose common name or one of
+// whose DNS, email or URI SANs matches one of names
+func AllowedClientNames(names ...string) ListenOption {
+	return func(opts *ListenOptions) {
+		opts.AllowedClientNames = append(opts.AllowedClientNames, names...)
+	}
+}
+
+// ServerTLS builds the tls config used to accept connections. It returns nil if TLS is not configured
+func (opts *ListenOptions) ServerTLS() (*tls.Config, error) {
+	var tc *tls.Config
+
+	if opts.Reloader != nil {
+		tc = &tls.Config{GetCertificate: opts.Reloader.GetCertificate}
+		if opts.Trust && opts.ClientCAs == nil && opts.ClientCAsFilename == "" {
+			// the trusted certificate follows reloads
+			base := tc
+			tc.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+				c := base.Clone()
+				c.GetConfigForClient = nil
+				pool := x509.NewCertPool()
+				pool.AddCert(opts.Reloader.Certificate().Leaf)
+				c.ClientCAs = pool
+				return c, nil
+			}
+		}
+
+	} else if opts.CertFilename != "" && opts.KeyFilename != "" {
+		cert, err := crypto2.LoadCertificate(opts.CertFilename)
 		if err != nil {
 			return nil, err
 		}
 
-		key, err := crypto2.LoadPrivateKey(lopts.KeyPassword, lopts.KeyFilename)
+		key, err := crypto2.LoadPrivateKey(opts.KeyPassword, opts.KeyFilename)
 		if err != nil {
 			return nil, err
 		}
 
-		tc := &tls.Config{
+		tc = &tls.Config{
 			Certificates: []tls.Certificate{
 				{
 					Certificate: [][]byte{cert.Raw},
@@ -85,17 +173,54 @@ func Listen(address string, opts ...ListenOption) (net.Listener, error) {
 			},
 		}
 
-		if lopts.Trust {
+		if opts.Trust {
 			pool := x509.NewCertPool()
 			pool.AddCert(cert)
 			tc.ClientCAs = pool
 		}
 
-		return tls.Listen("tcp", address, tc)
+	} else if opts.TLS != nil {
+		tc = opts.TLS.Clone()
 
-	} else if lopts.TLS != nil {
-		return tls.Listen("tcp", address, lopts.TLS)
 	} else {
-		return net.Listen("tcp", address)
+		return nil, nil
+	}
+
+	return tc, opts.applyClientAuth(tc)
+}
+
+// Listen listen to connections on address. See listen for the supported address schemes
+func Listen(address string, opts ...ListenOption) (net.Listener, error) {
+	var lopts ListenOptions
+	for _, opt := range opts {
+		opt(&lopts)
+	}
+
+	tc, err := lopts.ServerTLS()
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := listen(address, &lopts)
+	if err != nil {
+		return nil, err
+	}
+
+	if lopts.limited() {
+		l = newLimitListener(l, &lopts)
+	}
+
+	if lopts.ProxyProtocol {
+		pl, err := newProxyListener(l, lopts.ProxyTrusted)
+		if err != nil {
+			_ = l.Close()
+			return nil, err
+		}
+		l = pl
+	}
+
+	if tc != nil {
+		return tls.NewListener(l, tc), nil
 	}
+	return l, nil
 }
@@ -0,0 +1,61 @@
+package netx
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+)
+
+// Principal is the identity proven by a verified client certificate
+type Principal struct {
+	CommonName   string
+	Organization []string
+	SerialNumber string
+	DNSNames     []string
+	Emails       []string
+	URIs         []string
+	Certificate  *x509.Certificate
+}
+
+// Names returns the common name followed by all the SANs of the certificate
+func (p *Principal) Names() []string {
+	return certificateNames(p.Certificate)
+}
+
+// PrincipalFromCertificate creates a principal from cert
+func PrincipalFromCertificate(cert *x509.Certificate) *Principal {
+	p := &Principal{
+		CommonName:   cert.Subject.CommonName,
+		Organization: cert.Subject.Organization,
+		SerialNumber: serial(cert.SerialNumber),
+		DNSNames:     cert.DNSNames,
+		Emails:       cert.EmailAddresses,
+		Certificate:  cert,
+	}
+	for _, uri := range cert.URIs {
+		p.URIs = append(p.URIs, uri.String())
+	}
+	return p
+}
+
+// PrincipalFromConnectionState returns the principal of the verified client certificate of state.
+// It returns nil if the client did not present a verified certificate
+func PrincipalFromConnectionState(state *tls.ConnectionState) *Principal {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return PrincipalFromCertificate(state.VerifiedChains[0][0])
+}
+
+type ctxPrincipal struct{}
+
+// ContextWithPrincipal returns a new context that holds p
+func ContextWithPrincipal(parent context.Context, p *Principal) context.Context {
+	return context.WithValue(parent, ctxPrincipal{}, p)
+}
+
+// PrincipalFromContext returns the principal stored in ctx
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(ctxPrincipal{}).(*Principal)
+	return p
+}
@@ -0,0 +1,238 @@
+package netx
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrProxyHeader = errors.New("netx: invalid PROXY protocol header")
+
+	proxyV1Prefix    = []byte("PROXY ")
+	proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+const proxyHeaderTimeout = time.Second * 5
+
+// ProxyProtocol parses PROXY protocol v1 and v2 headers so that the address of the
+// real client is returned by connections RemoteAddr. Headers are only expected from peers
+// whose address is in one of the trusted CIDRs, or from unix socket peers. No TCP peer is
+// trusted if none is passed
+func ProxyProtocol(trusted ...string) ListenOption {
+	return func(opts *ListenOptions) {
+		opts.ProxyProtocol = true
+		opts.ProxyTrusted = append(opts.ProxyTrusted, trusted...)
+	}
+}
+
+func newProxyListener(l net.Listener, trusted []string) (net.Listener, error) {
+	pl := &proxyListener{Listener: l}
+	for _, cidr := range trusted {
+		if !strings.Contains(cidr, "/") {
+			if strings.Contains(cidr, ":") {
+				cidr += "/128"
+			} else {
+				cidr += "/32"
+			}
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		pl.trusted = append(pl.trusted, network)
+	}
+	return pl, nil
+}
+
+type proxyListener struct {
+	net.Listener
+	trusted []*net.IPNet
+}
+
+func (l *proxyListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	if !l.isTrusted(conn.RemoteAddr()) {
+		return conn, nil
+	}
+	return &proxyConn{Conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+func (l *proxyListener) isTrusted(addr net.Addr) bool {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		// unix socket peers are local
+		return true
+	}
+
+	for _, network := range l.trusted {
+		if network.Contains(tcpAddr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// proxyConn reads the PROXY header on first use, which happens in the connection
+// serving goroutine so that a slow peer does not block the accept loop
+type proxyConn struct {
+	net.Conn
+	reader     *bufio.Reader
+	once       sync.Once
+	err        error
+	remoteAddr net.Addr
+	localAddr  net.Addr
+}
+
+func (c *proxyConn) init() {
+	c.once.Do(func() {
+		_ = c.Conn.SetReadDeadline(time.Now().Add(proxyHeaderTimeout))
+		c.remoteAddr, c.localAddr, c.err = readProxyHeader(c.reader)
+		_ = c.Conn.SetReadDeadline(time.Time{})
+	})
+}
+
+func (c *proxyConn) Read(b []byte) (int, error) {
+	c.init()
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.reader.Read(b)
+}
+
+func (c *proxyConn) RemoteAddr() net.Addr {
+	c.init()
+	if c.remoteAddr != nil {
+		return c.remoteAddr
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *proxyConn) LocalAddr() net.Addr {
+	c.init()
+	if c.localAddr != nil {
+		return c.localAddr
+	}
+	return c.Conn.LocalAddr()
+}
+
+// readProxyHeader reads a v1 or v2 header. Nil addresses are returned for LOCAL and UNKNOWN headers
+func readProxyHeader(r *bufio.Reader) (net.Addr, net.Addr, error) {
+	// the shortest header, "PROXY UNKNOWN\r\n", is 15 bytes long and the peer may wait for a response
+	// after it, so no more than the bytes telling the versions apart are peeked first
+	prefix, err := r.Peek(len(proxyV1Prefix))
+	if err != nil {
+		return nil, nil, ErrProxyHeader
+	}
+
+	if bytes.Equal(prefix, proxyV1Prefix) {
+		return readProxyHeaderV1(r)
+	}
+
+	if !bytes.HasPrefix(proxyV2Signature, prefix) {
+		return nil, nil, ErrProxyHeader
+	}
+
+	prefix, err = r.Peek(len(proxyV2Signature))
+	if err != nil || !bytes.Equal(prefix, proxyV2Signature) {
+		return nil, nil, ErrProxyHeader
+	}
+	return readProxyHeaderV2(r)
+}
+
+func readProxyHeaderV1(r *bufio.Reader) (net.Addr, net.Addr, error) {
+	// a v1 header is at most 107 bytes long
+	var line []byte
+	for len(line) < 107 {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, nil, ErrProxyHeader
+		}
+		line = append(line, b)
+		if bytes.HasSuffix(line, []byte("\r\n")) {
+			break
+		}
+	}
+
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, nil, ErrProxyHeader
+	}
+
+	fields := strings.Fields(string(line[:len(line)-2]))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil, nil
+	}
+
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, nil, ErrProxyHeader
+	}
+
+	srcIP, dstIP := net.ParseIP(fields[2]), net.ParseIP(fields[3])
+	srcPort, err1 := strconv.Atoi(fields[4])
+	dstPort, err2 := strconv.Atoi(fields[5])
+	if srcIP == nil || dstIP == nil || err1 != nil || err2 != nil {
+		return nil, nil, ErrProxyHeader
+	}
+
+	return &net.TCPAddr{IP: srcIP, Port: srcPort}, &net.TCPAddr{IP: dstIP, Port: dstPort}, nil
+}
+
+func readProxyHeaderV2(r *bufio.Reader) (net.Addr, net.Addr, error) {
+	header := make([]byte, 16)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return nil, nil, ErrProxyHeader
+	}
+
+	if header[12]>>4 != 2 {
+		return nil, nil, ErrProxyHeader
+	}
+	command := header[12] & 0x0F
+	family := header[13]
+	length := int(binary.BigEndian.Uint16(header[14:16]))
+
+	payload := make([]byte, length)
+	_, err = io.ReadFull(r, payload)
+	if err != nil {
+		return nil, nil, ErrProxyHeader
+	}
+
+	// LOCAL command: health checks sent by the proxy itself
+	if command == 0 {
+		return nil, nil, nil
+	}
+	if command != 1 {
+		return nil, nil, ErrProxyHeader
+	}
+
+	switch family >> 4 {
+	case 1:
+		if len(payload) < 12 {
+			return nil, nil, ErrProxyHeader
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:4]), Port: int(binary.BigEndian.Uint16(payload[8:10]))},
+			&net.TCPAddr{IP: net.IP(payload[4:8]), Port: int(binary.BigEndian.Uint16(payload[10:12]))}, nil
+
+	case 2:
+		if len(payload) < 36 {
+			return nil, nil, ErrProxyHeader
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:16]), Port: int(binary.BigEndian.Uint16(payload[32:34]))},
+			&net.TCPAddr{IP: net.IP(payload[16:32]), Port: int(binary.BigEndian.Uint16(payload[34:36]))}, nil
+
+	default:
+		// unix and unspecified families carry no usable address
+		return nil, nil, nil
+	}
+}
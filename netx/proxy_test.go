@@ -0,0 +1,150 @@
+package netx
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadProxyHeaderV1(t *testing.T) {
+	r := bufio.NewReader(bytes.NewBufferString("PROXY TCP4 192.0.2.10 192.0.2.1 56324 443\r\nGET / HTTP/1.1\r\n"))
+	remote, local, err := readProxyHeader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if remote.String() != "192.0.2.10:56324" || local.String() != "192.0.2.1:443" {
+		t.Fatal("unexpected addresses", remote, local)
+	}
+
+	rest, _ := r.ReadString('\n')
+	if rest != "GET / HTTP/1.1\r\n" {
+		t.Fatal("payload must follow the header", rest)
+	}
+}
+
+func TestReadProxyHeaderV2(t *testing.T) {
+	var header bytes.Buffer
+	header.Write(proxyV2Signature)
+	header.Write([]byte{0x21, 0x11, 0, 12})
+	header.Write(net.ParseIP("198.51.100.7").To4())
+	header.Write(net.ParseIP("198.51.100.1").To4())
+	_ = binary.Write(&header, binary.BigEndian, uint16(40000))
+	_ = binary.Write(&header, binary.BigEndian, uint16(8080))
+
+	remote, _, err := readProxyHeader(bufio.NewReader(&header))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if remote.String() != "198.51.100.7:40000" {
+		t.Fatal("unexpected remote address", remote)
+	}
+}
+
+func TestReadProxyHeaderInvalid(t *testing.T) {
+	_, _, err := readProxyHeader(bufio.NewReader(bytes.NewBufferString("GET / HTTP/1.1\r\nHost: a\r\n")))
+	if err != ErrProxyHeader {
+		t.Fatal("requests without header must be rejected")
+	}
+}
+
+func TestUnixProxyListener(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.sock")
+	l, err := Listen("unix://"+path, SocketMode(0660), ProxyProtocol())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0660 {
+		t.Fatal("unexpected socket permissions", info.Mode().Perm())
+	}
+
+	if DialAddress(l) != "unix://"+path {
+		t.Fatal("unexpected dial address", DialAddress(l))
+	}
+
+	go func() {
+		conn, err := net.Dial("unix", path)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Write([]byte("PROXY TCP6 2001:db8::1 2001:db8::2 1234 80\r\nhello"))
+	}()
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if conn.RemoteAddr().String() != "[2001:db8::1]:1234" {
+		t.Fatal("unexpected remote address", conn.RemoteAddr())
+	}
+
+	data, err := ioutil.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello" {
+		t.Fatal("unexpected payload", string(data))
+	}
+}
+
+func TestNoInheritedListener(t *testing.T) {
+	_, err := Listen("systemd://missing")
+	if err != ErrNoInheritedListener {
+		t.Fatal("expected ErrNoInheritedListener", err)
+	}
+}
+
+func TestReadProxyHeaderUnknown(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	// the peer waits for a response after the shortest possible header
+	go func() {
+		_, _ = client.Write([]byte("PROXY UNKNOWN\r\n"))
+	}()
+
+	remote, local, err := readProxyHeader(bufio.NewReader(server))
+	if err != nil || remote != nil || local != nil {
+		t.Fatal("UNKNOWN header must be accepted without addresses", remote, local, err)
+	}
+}
+
+func TestProxyTrustedNetworks(t *testing.T) {
+	l, err := newProxyListener(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.(*proxyListener).isTrusted(&net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 4000}) {
+		t.Fatal("no TCP peer must be trusted without trusted networks")
+	}
+
+	l, err = newProxyListener(nil, []string{"192.0.2.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !l.(*proxyListener).isTrusted(&net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 4000}) {
+		t.Fatal("peers of trusted networks must be trusted")
+	}
+}
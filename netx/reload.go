@@ -0,0 +1,232 @@
+package netx
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	crypto2 "github.com/omecodes/libome/crypt"
+)
+
+// ErrKeyMismatch is returned when the private key does not match the certificate
+var ErrKeyMismatch = errors.New("netx: private key does not match the certificate")
+
+// NewCertificateReloader loads the certificate and key files. The loaded pair is served to new
+// handshakes until Reload succeeds; established connections are not affected by reloads
+func NewCertificateReloader(certFilename, keyFilename string, keyPassword []byte) (*CertificateReloader, error) {
+	r := &CertificateReloader{
+		certFilename: certFilename,
+		keyFilename:  keyFilename,
+		keyPassword:  keyPassword,
+		errors:       make(chan error, 4),
+		stop:         make(chan struct{}),
+	}
+
+	err := r.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// CertificateReloader serves a certificate that can be replaced while listeners are running
+type CertificateReloader struct {
+	sync.RWMutex
+	certFilename string
+	keyFilename  string
+	keyPassword  []byte
+	cert         *tls.Certificate
+	certStat     fileStat
+	keyStat      fileStat
+	errors       chan error
+	stop         chan struct{}
+	stopOnce     sync.Once
+}
+
+type fileStat struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(filename string) (fileStat, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return fileStat{}, err
+	}
+	return fileStat{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// Reload loads the certificate and key files again. The current certificate is kept when they cannot be loaded
+// or do not form a pair
+func (r *CertificateReloader) Reload() error {
+	certStat, err := statFile(r.certFilename)
+	if err != nil {
+		return err
+	}
+
+	keyStat, err := statFile(r.keyFilename)
+	if err != nil {
+		return err
+	}
+
+	cert, err := crypto2.LoadCertificate(r.certFilename)
+	if err != nil {
+		return err
+	}
+
+	key, err := crypto2.LoadPrivateKey(r.keyPassword, r.keyFilename)
+	if err != nil {
+		return err
+	}
+
+	// the files may be replaced one after the other, the current pair is kept until they match
+	err = checkKeyPair(cert, key)
+	if err != nil {
+		return err
+	}
+
+	pair := &tls.Certificate{Certificate: [][]byte{cert.Raw}, PrivateKey: key, Leaf: cert}
+
+	r.Lock()
+	defer r.Unlock()
+	r.cert = pair
+	r.certStat = certStat
+	r.keyStat = keyStat
+	return nil
+}
+
+func checkKeyPair(cert *x509.Certificate, key crypto.PrivateKey) error {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return ErrKeyMismatch
+	}
+
+	expected, err := x509.MarshalPKIXPublicKey(cert.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	actual, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(expected, actual) {
+		return ErrKeyMismatch
+	}
+	return nil
+}
+
+func (r *CertificateReloader) changed() bool {
+	certStat, err := statFile(r.certFilename)
+	if err != nil {
+		return false
+	}
+
+	keyStat, err := statFile(r.keyFilename)
+	if err != nil {
+		return false
+	}
+
+	r.RLock()
+	defer r.RUnlock()
+	return certStat != r.certStat || keyStat != r.keyStat
+}
+
+func (r *CertificateReloader) reload() {
+	err := r.Reload()
+	if err != nil {
+		select {
+		case r.errors <- err:
+		default:
+		}
+	}
+}
+
+// Watch checks the files every interval and reloads them when they changed
+func (r *CertificateReloader) Watch(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.stop:
+				return
+
+			case <-ticker.C:
+				if r.changed() {
+					r.reload()
+				}
+			}
+		}
+	}()
+}
+
+// ReloadOn reloads the files each time one of signals is received
+func (r *CertificateReloader) ReloadOn(signals ...os.Signal) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, signals...)
+
+	go func() {
+		defer signal.Stop(c)
+		for {
+			select {
+			case <-r.stop:
+				return
+
+			case <-c:
+				r.reload()
+			}
+		}
+	}()
+}
+
+// Errors returns the channel reload errors are sent to. Errors are dropped when it is full
+func (r *CertificateReloader) Errors() <-chan error {
+	return r.errors
+}
+
+// Close stops watching files and signals
+func (r *CertificateReloader) Close() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+}
+
+// Certificate returns the current certificate
+func (r *CertificateReloader) Certificate() *tls.Certificate {
+	r.RLock()
+	defer r.RUnlock()
+	return r.cert
+}
+
+// GetCertificate can be used as tls.Config GetCertificate
+func (r *CertificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+// GetClientCertificate can be used as tls.Config GetClientCertificate
+func (r *CertificateReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+// ReloadingCertificate serves the certificate of r. Internal clients verify it against the CA option,
+// or against the system roots when it is not set
+func ReloadingCertificate(r *CertificateReloader) ListenOption {
+	return func(opts *ListenOptions) {
+		opts.Secure = true
+		opts.TLS = nil
+		opts.CertFilename = r.certFilename
+		opts.KeyFilename = r.keyFilename
+		opts.KeyPassword = r.keyPassword
+		opts.LocalCA = nil
+		opts.Reloader = r
+	}
+}
@@ -0,0 +1,112 @@
+package netx
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"testing"
+
+	crypto2 "github.com/omecodes/libome/crypt"
+)
+
+func TestCertificateReload(t *testing.T) {
+	pki := newTestPKI(t)
+	defer os.RemoveAll(pki.dir)
+
+	reloader, err := NewCertificateReloader(pki.certFile, pki.keyFile, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reloader.Close()
+
+	l, err := Listen("127.0.0.1:", ReloadingCertificate(reloader))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(pki.caCert)
+
+	serverName := func() string {
+		go func() {
+			conn, err := l.Accept()
+			if err == nil {
+				_ = conn.(*tls.Conn).Handshake()
+				_ = conn.Close()
+			}
+		}()
+
+		conn, err := tls.Dial("tcp", l.Addr().String(), &tls.Config{RootCAs: roots})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+	}
+
+	if name := serverName(); name != "server" {
+		t.Fatal("unexpected server certificate", name)
+	}
+
+	cert, key := pki.issue(t, "rotated")
+	err = crypto2.StoreCertificate(cert, pki.certFile, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = crypto2.StorePrivateKey(key, nil, pki.keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reloader.changed() {
+		t.Fatal("file changes must be detected")
+	}
+
+	err = reloader.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name := serverName(); name != "rotated" {
+		t.Fatal("reloaded certificate must be served", name)
+	}
+
+	// a key that does not match the certificate is not loaded
+	_, otherKey := pki.issue(t, "other")
+	err = crypto2.StorePrivateKey(otherKey, nil, pki.keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = reloader.Reload(); err != ErrKeyMismatch {
+		t.Fatal("mismatching key must be rejected", err)
+	}
+	if name := serverName(); name != "rotated" {
+		t.Fatal("current certificate must be kept", name)
+	}
+
+	// internal clients verify the rotated certificate against the CA
+	var lopts ListenOptions
+	for _, opt := range []ListenOption{ReloadingCertificate(reloader), CA(pki.caFile)} {
+		opt(&lopts)
+	}
+	internal, err := lopts.ClientTLS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if internal.InsecureSkipVerify || internal.RootCAs == nil {
+		t.Fatal("internal client must verify the server certificate")
+	}
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			_ = conn.(*tls.Conn).Handshake()
+			_ = conn.Close()
+		}
+	}()
+	conn, err := tls.Dial("tcp", l.Addr().String(), internal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.Close()
+}
@@ -0,0 +1,182 @@
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	grantTypeAuthorizationCode = "authorization_code"
+	grantTypeClientCredentials = "client_credentials"
+	grantTypeRefreshToken      = "refresh_token"
+)
+
+// Token is the token endpoint response
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresIn    int64     `json:"expires_in,omitempty"`
+	Scope        string    `json:"scope,omitempty"`
+	IDToken      string    `json:"id_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+}
+
+// Expired tells whether the access token expires within margin
+func (t *Token) Expired(margin time.Duration) bool {
+	if t.Expiry.IsZero() {
+		return false
+	}
+	return time.Now().Add(margin).After(t.Expiry)
+}
+
+// Error is an error returned by the token endpoint
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	Status      int    `json:"-"`
+}
+
+func (e *Error) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("oauth2: %s: %s", e.Code, e.Description)
+	}
+	return "oauth2: " + e.Code
+}
+
+// NewClient creates a client for the provider described by cfg
+func NewClient(cfg *Config, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{config: cfg, http: httpClient}
+}
+
+// ClientFor creates a client for the provider registered under name in the app configs
+func ClientFor(ctx context.Context, name string) (*Client, error) {
+	cfg, err := ProviderConfig(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(cfg, nil), nil
+}
+
+// Client runs OAuth2 flows against a single provider
+type Client struct {
+	config *Config
+	http   *http.Client
+}
+
+// Config returns the provider config
+func (c *Client) Config() *Config {
+	return c.config
+}
+
+// AuthorizeURL builds the URL the user agent is redirected to in order to start an authorization code flow
+func (c *Client) AuthorizeURL(redirectURI, state, codeChallenge string) string {
+	values := url.Values{}
+	values.Set("response_type", "code")
+	values.Set("client_id", c.config.ClientID)
+	values.Set("redirect_uri", redirectURI)
+	values.Set("state", state)
+	if len(c.config.Scopes) > 0 {
+		values.Set("scope", strings.Join(c.config.Scopes, " "))
+	}
+	if codeChallenge != "" {
+		values.Set("code_challenge", codeChallenge)
+		values.Set("code_challenge_method", "S256")
+	}
+
+	authorizeURL := c.config.AuthorizeURL()
+	if strings.Contains(authorizeURL, "?") {
+		return authorizeURL + "&" + values.Encode()
+	}
+	return authorizeURL + "?" + values.Encode()
+}
+
+// Exchange trades an authorization code for a token
+func (c *Client) Exchange(ctx context.Context, code, redirectURI, codeVerifier string) (*Token, error) {
+	values := url.Values{}
+	values.Set("grant_type", grantTypeAuthorizationCode)
+	values.Set("code", code)
+	values.Set("redirect_uri", redirectURI)
+	if codeVerifier != "" {
+		values.Set("code_verifier", codeVerifier)
+	}
+	return c.requestToken(ctx, values)
+}
+
+// ClientCredentials gets a token for the client itself, typically for service to service calls
+func (c *Client) ClientCredentials(ctx context.Context, scopes ...string) (*Token, error) {
+	values := url.Values{}
+	values.Set("grant_type", grantTypeClientCredentials)
+	if len(scopes) == 0 {
+		scopes = c.config.Scopes
+	}
+	if len(scopes) > 0 {
+		values.Set("scope", strings.Join(scopes, " "))
+	}
+	return c.requestToken(ctx, values)
+}
+
+// Refresh gets a new token using refreshToken
+func (c *Client) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	values := url.Values{}
+	values.Set("grant_type", grantTypeRefreshToken)
+	values.Set("refresh_token", refreshToken)
+	return c.requestToken(ctx, values)
+}
+
+func (c *Client) requestToken(ctx context.Context, values url.Values) (*Token, error) {
+	values.Set("client_id", c.config.ClientID)
+
+	req, err := http.NewRequest(http.MethodPost, c.config.TokenURL(), strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.config.Secret != "" {
+		req.SetBasicAuth(url.QueryEscape(c.config.ClientID), url.QueryEscape(c.config.Secret))
+	}
+
+	rsp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+
+	body, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if rsp.StatusCode != http.StatusOK {
+		e := &Error{Status: rsp.StatusCode}
+		if json.Unmarshal(body, e) != nil || e.Code == "" {
+			e.Code = "server_error"
+			e.Description = rsp.Status
+		}
+		return nil, e
+	}
+
+	t := new(Token)
+	err = json.Unmarshal(body, t)
+	if err != nil {
+		return nil, err
+	}
+	if t.AccessToken == "" {
+		return nil, &Error{Code: "server_error", Description: "missing access token", Status: rsp.StatusCode}
+	}
+	if t.ExpiresIn > 0 {
+		t.Expiry = time.Now().Add(time.Duration(t.ExpiresIn) * time.Second)
+	}
+	return t, nil
+}
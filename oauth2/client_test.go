@@ -0,0 +1,71 @@
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func tokenServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, secret, ok := r.BasicAuth()
+		if !ok || user != "client" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(&Error{Code: "invalid_client"})
+			return
+		}
+
+		_ = r.ParseForm()
+		switch r.PostForm.Get("grant_type") {
+		case grantTypeClientCredentials:
+			_ = json.NewEncoder(w).Encode(&Token{AccessToken: "service", ExpiresIn: 3600})
+
+		case grantTypeAuthorizationCode:
+			if CodeChallenge(r.PostForm.Get("code_verifier")) != r.PostForm.Get("code") {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(&Error{Code: "invalid_grant"})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(&Token{AccessToken: "user", RefreshToken: "refresh", ExpiresIn: 1})
+
+		case grantTypeRefreshToken:
+			_ = json.NewEncoder(w).Encode(&Token{AccessToken: "refreshed", ExpiresIn: 3600})
+		}
+	}))
+}
+
+func TestClientFlows(t *testing.T) {
+	server := tokenServer(t)
+	defer server.Close()
+
+	client := NewClient(&Config{ServerURL: server.URL, ClientID: "client", Secret: "secret"}, nil)
+	ctx := context.Background()
+
+	token, err := client.ClientCredentialsSource().Token(ctx)
+	if err != nil || token != "service" {
+		t.Fatal("client credentials flow failed:", err)
+	}
+
+	verifier, err := NewCodeVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the test server expects the challenge as code to check the verifier is sent
+	_, err = client.Exchange(ctx, CodeChallenge("wrong"), "http://localhost/callback", verifier)
+	if e, ok := err.(*Error); !ok || e.Code != "invalid_grant" {
+		t.Fatal("expected invalid_grant error, got", err)
+	}
+
+	userToken, err := client.Exchange(ctx, CodeChallenge(verifier), "http://localhost/callback", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err = client.TokenSource(userToken).Token(ctx)
+	if err != nil || token != "refreshed" {
+		t.Fatal("token refresh failed:", err)
+	}
+}
@@ -0,0 +1,91 @@
+package oauth2
+
+import (
+	"context"
+	"strings"
+
+	"github.com/omecodes/common/env/app"
+	"github.com/omecodes/common/errors"
+	"github.com/omecodes/common/utils/jcon"
+)
+
+// ProviderOme is the name under which the Ome authority config is resolved
+const ProviderOme = "ome"
+
+const (
+	defaultAuthorizeEndpoint = "/authorize"
+	defaultTokenEndpoint     = "/token"
+)
+
+// Config holds an OAuth2 client registration
+type Config struct {
+	Name              string
+	ServerURL         string
+	ClientID          string
+	Secret            string
+	AuthorizeEndpoint string
+	TokenEndpoint     string
+	CallbackURL       string
+	Scopes            []string
+}
+
+// AuthorizeURL returns the absolute authorization endpoint URL
+func (c *Config) AuthorizeURL() string {
+	return endpointURL(c.ServerURL, c.AuthorizeEndpoint, defaultAuthorizeEndpoint)
+}
+
+// TokenURL returns the absolute token endpoint URL
+func (c *Config) TokenURL() string {
+	return endpointURL(c.ServerURL, c.TokenEndpoint, defaultTokenEndpoint)
+}
+
+func endpointURL(server, endpoint, defaultEndpoint string) string {
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+	if strings.HasPrefix(endpoint, "http://") || strings.HasPrefix(endpoint, "https://") {
+		return endpoint
+	}
+	if !strings.HasPrefix(endpoint, "/") {
+		endpoint = "/" + endpoint
+	}
+	return strings.TrimSuffix(server, "/") + endpoint
+}
+
+// ProviderConfig loads the config registered for the provider called name from the app stored in ctx.
+// "ome" resolves the Ome authority config, other names resolve entries of the oauth2-providers config
+func ProviderConfig(ctx context.Context, name string) (*Config, error) {
+	var values jcon.Map
+	if name == ProviderOme {
+		ome := app.ConfigFromContext(ctx, app.ConfigOme)
+		if ome != nil {
+			values = ome.GetConf("oauth2")
+		}
+	} else {
+		provider := app.Oauth2ProviderConfig(ctx, name)
+		if provider != nil {
+			values = provider.GetConf("config")
+		}
+	}
+
+	if values == nil {
+		return nil, errors.NotFound
+	}
+	return configFromMap(name, values), nil
+}
+
+func configFromMap(name string, values jcon.Map) *Config {
+	cfg := &Config{Name: name}
+	cfg.ServerURL, _ = values.GetString("server_url")
+	cfg.ClientID, _ = values.GetString("client_id")
+	cfg.Secret, _ = values.GetString("secret")
+	cfg.AuthorizeEndpoint, _ = values.GetString("authorize_endpoint")
+	cfg.TokenEndpoint, _ = values.GetString("token_endpoint")
+	cfg.CallbackURL, _ = values.GetString("callback_url")
+
+	scope, _ := values.GetString("scope")
+	if scope != "" {
+		cfg.Scopes = strings.Fields(scope)
+	}
+	return cfg
+}
@@ -0,0 +1,267 @@
+package oauth2
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/omecodes/common/errors"
+	"github.com/omecodes/common/httpx"
+	"github.com/omecodes/common/session"
+	"github.com/omecodes/common/utils/log"
+)
+
+const (
+	sessionKeyState    = "oauth2_state"
+	sessionKeyVerifier = "oauth2_verifier"
+	sessionKeyRedirect = "oauth2_redirect_uri"
+
+	// SessionKeyToken is the session key under which the default success handler stores the JSON encoded token
+	SessionKeyToken = "oauth2_token"
+)
+
+// SuccessFunc is called once the authorization code has been exchanged for a token
+type SuccessFunc func(w http.ResponseWriter, r *http.Request, session httpx.Session, t *Token)
+
+type handlerOptions struct {
+	prefix      string
+	externalURL string
+	client      *Client
+	onSuccess   SuccessFunc
+}
+
+// HandlerOption enriches login handler options
+type HandlerOption func(*handlerOptions)
+
+// WithPrefix sets the path the login and callback routes are mounted under. Defaults to "/oauth2/{provider}"
+func WithPrefix(prefix string) HandlerOption {
+	return func(opts *handlerOptions) {
+		opts.prefix = prefix
+	}
+}
+
+// WithExternalURL sets the scheme and host the service is reached with, e.g. "https://example.com". It is used to
+// build the redirect URI when the provider config has no callback URL. Request headers are never used for that
+func WithExternalURL(url string) HandlerOption {
+	return func(opts *handlerOptions) {
+		opts.externalURL = url
+	}
+}
+
+// WithClient uses client instead of resolving the provider config from the request context
+func WithClient(client *Client) HandlerOption {
+	return func(opts *handlerOptions) {
+		opts.client = client
+	}
+}
+
+// OnSuccess sets the func called at the end of a successful flow
+func OnSuccess(f SuccessFunc) HandlerOption {
+	return func(opts *handlerOptions) {
+		opts.onSuccess = f
+	}
+}
+
+// NewLoginHandler creates handlers for the authorization code + PKCE flow of the provider called name.
+// The flow state is kept in the sessions of manager. See httpx.OpenSession for how they are shared with the
+// httpx.Sessions middleware. Unless WithClient is passed, the provider config is loaded from the app stored in the
+// request context
+func NewLoginHandler(provider string, manager *session.Manager, opts ...HandlerOption) *LoginHandler {
+	h := &LoginHandler{
+		provider: provider,
+		manager:  manager,
+	}
+	h.options.prefix = "/oauth2/" + provider
+	for _, opt := range opts {
+		opt(&h.options)
+	}
+	h.prefix = strings.TrimSuffix(h.options.prefix, "/")
+	if h.options.onSuccess == nil {
+		h.options.onSuccess = storeTokenAndRedirect
+	}
+	return h
+}
+
+// LoginHandler serves the login and callback endpoints of an authorization code flow
+type LoginHandler struct {
+	provider string
+	manager  *session.Manager
+	options  handlerOptions
+	prefix   string
+}
+
+// Routes returns the login and callback routes mounted under the handler prefix
+func (h *LoginHandler) Routes() []httpx.Route {
+	return []httpx.Route{
+		{
+			Name:        h.provider + "-oauth2-login",
+			Method:      []string{http.MethodGet},
+			Pattern:     h.prefix + "/login",
+			HandlerFunc: h.Login,
+		},
+		{
+			Name:        h.provider + "-oauth2-callback",
+			Method:      []string{http.MethodGet},
+			Pattern:     h.prefix + "/callback",
+			HandlerFunc: h.Callback,
+		},
+	}
+}
+
+func (h *LoginHandler) client(r *http.Request) (*Client, error) {
+	if h.options.client != nil {
+		return h.options.client, nil
+	}
+	return ClientFor(r.Context(), h.provider)
+}
+
+func (h *LoginHandler) redirectURI(cfg *Config) (string, error) {
+	if cfg.CallbackURL != "" {
+		return cfg.CallbackURL, nil
+	}
+	if h.options.externalURL == "" {
+		return "", errors.Create(errors.Internal, "no callback url configured for oauth2 provider %q", h.provider)
+	}
+	return strings.TrimSuffix(h.options.externalURL, "/") + h.prefix + "/callback", nil
+}
+
+// Login redirects the user agent to the provider authorization endpoint
+func (h *LoginHandler) Login(w http.ResponseWriter, r *http.Request) {
+	client, err := h.client(r)
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
+
+	state, err := randomString(16)
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
+
+	verifier, err := NewCodeVerifier()
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
+
+	redirectURI, err := h.redirectURI(client.Config())
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
+
+	session, err := httpx.OpenSession(h.manager, w, r)
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
+	session.Set(sessionKeyState, state)
+	session.Set(sessionKeyVerifier, verifier)
+	session.Set(sessionKeyRedirect, redirectURI)
+	err = session.Save()
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
+
+	httpx.Redirect(w, &httpx.RedirectURL{
+		URL:  client.AuthorizeURL(redirectURI, state, CodeChallenge(verifier)),
+		Code: http.StatusFound,
+	})
+}
+
+// Callback checks the returned state and exchanges the authorization code for a token
+func (h *LoginHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	session, err := httpx.OpenSession(h.manager, w, r)
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
+	state := session.GetString(sessionKeyState)
+	verifier := session.GetString(sessionKeyVerifier)
+	redirectURI := session.GetString(sessionKeyRedirect)
+
+	session.Delete(sessionKeyState)
+	session.Delete(sessionKeyVerifier)
+	session.Delete(sessionKeyRedirect)
+
+	// the flow state is consumed whatever the outcome, so that a replayed callback cannot find it
+	err = session.Save()
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
+
+	if e := query.Get("error"); e != "" {
+		log.Error("[oauth2] authorization failed",
+			log.Field("provider", h.provider),
+			log.Field("error", e),
+			log.Field("description", query.Get("error_description")),
+		)
+		httpx.WriteError(w, errors.Forbidden)
+		return
+	}
+
+	if state == "" || query.Get("state") != state {
+		httpx.WriteError(w, errors.BadInput)
+		return
+	}
+
+	code := query.Get("code")
+	if code == "" {
+		httpx.WriteError(w, errors.BadInput)
+		return
+	}
+
+	client, err := h.client(r)
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
+
+	t, err := client.Exchange(r.Context(), code, redirectURI, verifier)
+	if err != nil {
+		log.Error("[oauth2] code exchange failed", log.Field("provider", h.provider), log.Err(err))
+		httpx.WriteError(w, errors.Unauthorized)
+		return
+	}
+
+	h.options.onSuccess(w, r, session, t)
+}
+
+func storeTokenAndRedirect(w http.ResponseWriter, r *http.Request, session httpx.Session, t *Token) {
+	encoded, err := json.Marshal(t)
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
+
+	// the session gets the privileges of the token
+	session.Rotate()
+	session.Set(SessionKeyToken, string(encoded))
+	err = session.Save()
+	if err != nil {
+		httpx.WriteError(w, err)
+		return
+	}
+
+	httpx.Redirect(w, &httpx.RedirectURL{URL: "/", Code: http.StatusFound})
+}
+
+// TokenFromSession returns the token stored by the default success handler
+func TokenFromSession(session httpx.Session) (*Token, error) {
+	encoded := session.GetString(SessionKeyToken)
+	if encoded == "" {
+		return nil, errors.NotFound
+	}
+
+	t := new(Token)
+	err := json.Unmarshal([]byte(encoded), t)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -0,0 +1,92 @@
+package oauth2
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/omecodes/common/session"
+)
+
+func TestLoginHandlerCallback(t *testing.T) {
+	server := tokenServer(t)
+	defer server.Close()
+
+	client := NewClient(&Config{ServerURL: server.URL, ClientID: "client", Secret: "secret"}, nil)
+	dir, err := ioutil.TempDir("", "oauth2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := session.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewLoginHandler("test", session.NewManager(store), WithClient(client), WithExternalURL("https://example.com"))
+
+	routes := h.Routes()
+	if routes[1].Pattern != "/oauth2/test/callback" {
+		t.Fatal("unexpected callback route", routes[1].Pattern)
+	}
+
+	login := func() (string, []*http.Cookie) {
+		w := httptest.NewRecorder()
+		h.Login(w, httptest.NewRequest(http.MethodGet, "/oauth2/test/login", nil))
+		if w.Code != http.StatusFound {
+			t.Fatal("login must redirect", w.Code)
+		}
+
+		location, err := url.Parse(w.Header().Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if location.Query().Get("redirect_uri") != "https://example.com/oauth2/test/callback" {
+			t.Fatal("unexpected redirect uri", location.Query().Get("redirect_uri"))
+		}
+		return location.Query().Get("state"), w.Result().Cookies()
+	}
+
+	callback := func(query string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/oauth2/test/callback?"+query, nil)
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		h.Callback(w, r)
+		return w
+	}
+
+	state, cookies := login()
+	w := callback("code=code&state=wrong", cookies)
+	if w.Code != http.StatusBadRequest {
+		t.Fatal("mismatching state must be rejected", w.Code)
+	}
+	if len(w.Result().Cookies()) == 0 {
+		t.Fatal("failed callback must save the session")
+	}
+
+	// the failed callback consumed the state, the session cookie is replayed as it was after login
+	w = callback("code=code&state="+state, cookies)
+	if w.Code != http.StatusBadRequest {
+		t.Fatal("replayed state must be rejected", w.Code)
+	}
+
+	// the state is consumed as well when the provider reports an error
+	state, cookies = login()
+	w = callback("error=access_denied&state="+state, cookies)
+	if w.Code != http.StatusForbidden {
+		t.Fatal("authorization error must be forbidden", w.Code)
+	}
+	if len(w.Result().Cookies()) == 0 {
+		t.Fatal("failed callback must save the session")
+	}
+
+	w = callback("code=code&state="+state, cookies)
+	if w.Code != http.StatusBadRequest {
+		t.Fatal("state of a denied authorization must be rejected", w.Code)
+	}
+}
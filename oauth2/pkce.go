@@ -0,0 +1,27 @@
+package oauth2
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// NewCodeVerifier generates a PKCE code verifier
+func NewCodeVerifier() (string, error) {
+	return randomString(32)
+}
+
+// CodeChallenge computes the S256 challenge of verifier
+func CodeChallenge(verifier string) string {
+	hash := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+func randomString(size int) (string, error) {
+	buff := make([]byte, size)
+	_, err := rand.Read(buff)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buff), nil
+}
@@ -0,0 +1,78 @@
+package oauth2
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/omecodes/common/grpcx"
+	"github.com/omecodes/common/jwt"
+	"google.golang.org/grpc/credentials"
+)
+
+const expiryMargin = time.Second * 30
+
+// ClientCredentialsSource returns a token source that runs the client credentials flow
+// and runs it again when the access token is about to expire
+func (c *Client) ClientCredentialsSource(scopes ...string) jwt.TokenSource {
+	return jwt.RefreshingSource(func(ctx context.Context) (string, time.Time, error) {
+		t, err := c.ClientCredentials(ctx, scopes...)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		return t.AccessToken, t.Expiry, nil
+	}, expiryMargin)
+}
+
+// TokenSource returns a token source that starts with t and refreshes it with its refresh token
+func (c *Client) TokenSource(t *Token) *RefreshingSource {
+	return &RefreshingSource{client: c, token: t}
+}
+
+// RefreshingSource provides the access token of a user token and refreshes it when it expires
+type RefreshingSource struct {
+	sync.Mutex
+	client *Client
+	token  *Token
+}
+
+// Token returns a valid access token
+func (s *RefreshingSource) Token(ctx context.Context) (string, error) {
+	t, err := s.Current(ctx)
+	if err != nil {
+		return "", err
+	}
+	return t.AccessToken, nil
+}
+
+// Current returns the current token, refreshed if needed
+func (s *RefreshingSource) Current(ctx context.Context) (*Token, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if !s.token.Expired(expiryMargin) || s.token.RefreshToken == "" {
+		return s.token, nil
+	}
+
+	t, err := s.client.Refresh(ctx, s.token.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// refresh tokens are not always rotated
+	if t.RefreshToken == "" {
+		t.RefreshToken = s.token.RefreshToken
+	}
+	s.token = t
+	return t, nil
+}
+
+// GRPCCredentials returns per-RPC credentials that authenticate gRPC calls with
+// tokens obtained with the client credentials flow
+func GRPCCredentials(ctx context.Context, provider string, scopes ...string) (credentials.PerRPCCredentials, error) {
+	client, err := ClientFor(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+	return grpcx.NewGRPCClientJwtSource(client.ClientCredentialsSource(scopes...)), nil
+}
@@ -0,0 +1,162 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/omecodes/common/errors"
+)
+
+var (
+	boltSessions = []byte("sessions")
+	boltUsers    = []byte("session_users")
+)
+
+// BoltStore keeps records in a Bolt database. Sessions are indexed by user
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore creates a Bolt store in db
+func NewBoltStore(db *bolt.DB) (*BoltStore, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(boltSessions); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(boltUsers)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &BoltStore{db: db}, nil
+}
+
+func userKey(userID, id string) []byte {
+	return []byte(userID + "\x00" + id)
+}
+
+func (s *BoltStore) Load(ctx context.Context, token string) (*Record, error) {
+	var data []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(boltSessions).Get([]byte(token)); v != nil {
+			data = append([]byte{}, v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, errors.NotFound
+	}
+	return DecodeRecord(data)
+}
+
+func (s *BoltStore) Save(ctx context.Context, r *Record) (string, error) {
+	return s.save(r, false)
+}
+
+func (s *BoltStore) Update(ctx context.Context, r *Record) (string, error) {
+	return s.save(r, true)
+}
+
+func (s *BoltStore) save(r *Record, update bool) (string, error) {
+	data, err := r.Encode()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		sessions, users := tx.Bucket(boltSessions), tx.Bucket(boltUsers)
+
+		previous := sessions.Get([]byte(r.ID))
+		if previous == nil && update {
+			return errors.NotFound
+		}
+		if previous != nil {
+			if old, err := DecodeRecord(previous); err == nil && old.UserID != "" && old.UserID != r.UserID {
+				if err = users.Delete(userKey(old.UserID, r.ID)); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := sessions.Put([]byte(r.ID), data); err != nil {
+			return err
+		}
+		if r.UserID != "" {
+			return users.Put(userKey(r.UserID, r.ID), []byte{})
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return r.ID, nil
+}
+
+func (s *BoltStore) Delete(ctx context.Context, r *Record) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return s.delete(tx, []byte(r.ID))
+	})
+}
+
+func (s *BoltStore) delete(tx *bolt.Tx, id []byte) error {
+	sessions := tx.Bucket(boltSessions)
+	if data := sessions.Get(id); data != nil {
+		if r, err := DecodeRecord(data); err == nil && r.UserID != "" {
+			if err = tx.Bucket(boltUsers).Delete(userKey(r.UserID, r.ID)); err != nil {
+				return err
+			}
+		}
+	}
+	return sessions.Delete(id)
+}
+
+func (s *BoltStore) DeleteUser(ctx context.Context, userID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		prefix := []byte(userID + "\x00")
+
+		var ids [][]byte
+		c := tx.Bucket(boltUsers).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			ids = append(ids, append([]byte{}, k[len(prefix):]...))
+		}
+
+		for _, id := range ids {
+			if err := s.delete(tx, id); err != nil {
+				return err
+			}
+			if err := tx.Bucket(boltUsers).Delete(append(append([]byte{}, prefix...), id...)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BoltStore) DeleteExpired(ctx context.Context, now time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		var expired [][]byte
+		err := tx.Bucket(boltSessions).ForEach(func(k, v []byte) error {
+			if r, err := DecodeRecord(v); err != nil || r.Expired(now) {
+				expired = append(expired, append([]byte{}, k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, id := range expired {
+			if err = s.delete(tx, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,58 @@
+package session
+
+import (
+	"context"
+
+	"github.com/gorilla/securecookie"
+
+	"github.com/omecodes/common/errors"
+)
+
+// CookieStore keeps records in the cookie itself, signed and encrypted with the securecookie codecs.
+// Records are limited by the cookie size and cannot be revoked on server side
+type CookieStore struct {
+	name   string
+	codecs []securecookie.Codec
+}
+
+// NewCookieStore creates a cookie store. name is the cookie name records are bound to. keyPairs are pairs of
+// authentication and encryption keys, as in securecookie.CodecsFromPairs
+func NewCookieStore(name string, keyPairs ...[]byte) *CookieStore {
+	codecs := securecookie.CodecsFromPairs(keyPairs...)
+	for _, c := range codecs {
+		if sc, ok := c.(*securecookie.SecureCookie); ok {
+			// expiry is checked by the manager
+			sc.MaxAge(0)
+		}
+	}
+	return &CookieStore{name: name, codecs: codecs}
+}
+
+func (s *CookieStore) Load(ctx context.Context, token string) (*Record, error) {
+	var data []byte
+	if err := securecookie.DecodeMulti(s.name, token, &data, s.codecs...); err != nil {
+		return nil, errors.Wrap(errors.NotFound, err, "invalid session cookie")
+	}
+	return DecodeRecord(data)
+}
+
+func (s *CookieStore) Save(ctx context.Context, r *Record) (string, error) {
+	data, err := r.Encode()
+	if err != nil {
+		return "", err
+	}
+	return securecookie.EncodeMulti(s.name, data, s.codecs...)
+}
+
+// Update is Save, since records held by clients cannot be known to be deleted
+func (s *CookieStore) Update(ctx context.Context, r *Record) (string, error) {
+	return s.Save(ctx, r)
+}
+
+func (s *CookieStore) Delete(ctx context.Context, r *Record) error {
+	return nil
+}
+
+func (s *CookieStore) DeleteUser(ctx context.Context, userID string) error {
+	return errors.Create(errors.NotSupported, "cookie sessions cannot be revoked")
+}
@@ -0,0 +1,143 @@
+package session
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/omecodes/common/errors"
+)
+
+// FileStore keeps each record in a file of a directory, like filepath.Join(app.CacheDir(), "sessions")
+type FileStore struct {
+	sync.RWMutex
+	dir string
+}
+
+// NewFileStore creates a file store in dir. The directory is created if it does not exist
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) filename(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+func (s *FileStore) Load(ctx context.Context, token string) (*Record, error) {
+	if !validID(token) {
+		return nil, errors.NotFound
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	data, err := ioutil.ReadFile(s.filename(token))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.NotFound
+		}
+		return nil, err
+	}
+	return DecodeRecord(data)
+}
+
+func (s *FileStore) Save(ctx context.Context, r *Record) (string, error) {
+	return s.save(r, false)
+}
+
+func (s *FileStore) Update(ctx context.Context, r *Record) (string, error) {
+	return s.save(r, true)
+}
+
+func (s *FileStore) save(r *Record, update bool) (string, error) {
+	if !validID(r.ID) {
+		return "", errors.Create(errors.BadInput, "invalid session id")
+	}
+
+	data, err := r.Encode()
+	if err != nil {
+		return "", err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if update {
+		if _, err = os.Stat(s.filename(r.ID)); os.IsNotExist(err) {
+			return "", errors.NotFound
+		}
+	}
+
+	// written to a temporary file first so that readers never see partial records
+	tmp := s.filename(r.ID) + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return "", err
+	}
+	if err = os.Rename(tmp, s.filename(r.ID)); err != nil {
+		_ = os.Remove(tmp)
+		return "", err
+	}
+	return r.ID, nil
+}
+
+func (s *FileStore) Delete(ctx context.Context, r *Record) error {
+	if !validID(r.ID) {
+		return nil
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	err := os.Remove(s.filename(r.ID))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) DeleteUser(ctx context.Context, userID string) error {
+	return s.deleteWhere(func(r *Record) bool {
+		return r.UserID == userID
+	})
+}
+
+func (s *FileStore) DeleteExpired(ctx context.Context, now time.Time) error {
+	return s.deleteWhere(func(r *Record) bool {
+		return r.Expired(now)
+	})
+}
+
+func (s *FileStore) deleteWhere(match func(r *Record) bool) error {
+	s.Lock()
+	defer s.Unlock()
+
+	entries, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !validID(entry.Name()) {
+			continue
+		}
+
+		filename := s.filename(entry.Name())
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			continue
+		}
+		r, err := DecodeRecord(data)
+		if err != nil || match(r) {
+			if err = os.Remove(filename); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
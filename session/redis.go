@@ -0,0 +1,142 @@
+package session
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/omecodes/common/errors"
+)
+
+// RedisStore keeps records in Redis with a TTL matching their expiry. The IDs of the sessions of a user
+// are kept in a set to revoke them
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisStore creates a Redis store. Keys are prefixed with prefix
+func NewRedisStore(client redis.UniversalClient, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (s *RedisStore) key(id string) string {
+	return s.prefix + "session:" + id
+}
+
+func (s *RedisStore) userKey(userID string) string {
+	return s.prefix + "session_user:" + userID
+}
+
+func (s *RedisStore) Load(ctx context.Context, token string) (*Record, error) {
+	data, err := s.client.Get(ctx, s.key(token)).Bytes()
+	if err == redis.Nil {
+		return nil, errors.NotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return DecodeRecord(data)
+}
+
+func (s *RedisStore) Save(ctx context.Context, r *Record) (string, error) {
+	return s.save(ctx, r, false)
+}
+
+func (s *RedisStore) Update(ctx context.Context, r *Record) (string, error) {
+	return s.save(ctx, r, true)
+}
+
+// save writes r in a transaction watching its key, so that a record deleted concurrently is not recreated
+// by an update
+func (s *RedisStore) save(ctx context.Context, r *Record, update bool) (string, error) {
+	data, err := r.Encode()
+	if err != nil {
+		return "", err
+	}
+
+	var ttl time.Duration
+	if !r.ExpiresAt.IsZero() {
+		ttl = time.Until(r.ExpiresAt)
+		if ttl <= 0 {
+			return r.ID, s.Delete(ctx, r)
+		}
+	}
+
+	err = s.client.Watch(ctx, func(tx *redis.Tx) error {
+		var previous *Record
+		stored, err := tx.Get(ctx, s.key(r.ID)).Bytes()
+		if err == redis.Nil {
+			if update {
+				return errors.NotFound
+			}
+		} else if err != nil {
+			return err
+		} else if previous, err = DecodeRecord(stored); err != nil {
+			previous = nil
+		}
+
+		// the user set lives as long as its longest session
+		var userTTL time.Duration
+		if r.UserID != "" {
+			if userTTL, err = tx.TTL(ctx, s.userKey(r.UserID)).Result(); err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if previous != nil && previous.UserID != "" && previous.UserID != r.UserID {
+				pipe.SRem(ctx, s.userKey(previous.UserID), r.ID)
+			}
+			pipe.Set(ctx, s.key(r.ID), data, ttl)
+			if r.UserID != "" {
+				exists := userTTL != -2
+				pipe.SAdd(ctx, s.userKey(r.UserID), r.ID)
+				if ttl == 0 {
+					pipe.Persist(ctx, s.userKey(r.UserID))
+				} else if !exists || userTTL > 0 && userTTL < ttl {
+					pipe.Expire(ctx, s.userKey(r.UserID), ttl)
+				}
+			}
+			return nil
+		})
+		return err
+	}, s.key(r.ID))
+	if err != nil {
+		return "", err
+	}
+	return r.ID, nil
+}
+
+func (s *RedisStore) Delete(ctx context.Context, r *Record) error {
+	previous, err := s.Load(ctx, r.ID)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, s.key(r.ID))
+		if previous.UserID != "" {
+			pipe.SRem(ctx, s.userKey(previous.UserID), r.ID)
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *RedisStore) DeleteUser(ctx context.Context, userID string) error {
+	ids, err := s.client.SMembers(ctx, s.userKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := []string{s.userKey(userID)}
+	for _, id := range ids {
+		keys = append(keys, s.key(id))
+	}
+	return s.client.Del(ctx, keys...).Err()
+}
@@ -0,0 +1,300 @@
+// Package session stores server-side sessions referenced by cookies. A Manager loads and saves session records
+// in a Store and enforces idle and absolute expiry. Stores that keep records on server side can revoke all the
+// sessions of a user. Manager.Open returns the Session API shared by the httpx middleware and the grpcx interceptors
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/omecodes/common/errors"
+	"github.com/omecodes/common/utils/codec"
+)
+
+// Record is the stored state of a session
+type Record struct {
+	ID         string
+	UserID     string
+	Values     map[string]interface{}
+	CreatedAt  time.Time
+	AccessedAt time.Time
+	ExpiresAt  time.Time
+}
+
+// Expired tells if r is expired at now
+func (r *Record) Expired(now time.Time) bool {
+	return !r.ExpiresAt.IsZero() && !now.Before(r.ExpiresAt)
+}
+
+// Encode encodes r with the default codec. Values must hold types registered with gob
+func (r *Record) Encode() ([]byte, error) {
+	return codec.Default.Encode(r)
+}
+
+// DecodeRecord decodes a record encoded with Record.Encode
+func DecodeRecord(data []byte) (*Record, error) {
+	r := new(Record)
+	if err := codec.Default.Decode(data, r); err != nil {
+		return nil, err
+	}
+	if r.Values == nil {
+		r.Values = map[string]interface{}{}
+	}
+	return r, nil
+}
+
+// Store keeps session records
+type Store interface {
+	// Load returns the record referenced by the cookie value token, or errors.NotFound
+	Load(ctx context.Context, token string) (*Record, error)
+
+	// Save stores r, replacing the record of the same ID, and returns the cookie value that references it
+	Save(ctx context.Context, r *Record) (string, error)
+
+	// Update replaces the stored record of r and returns the cookie value that references it. It fails with
+	// errors.NotFound when the record no longer exists, like when the sessions of its user were revoked
+	Update(ctx context.Context, r *Record) (string, error)
+
+	// Delete deletes r
+	Delete(ctx context.Context, r *Record) error
+
+	// DeleteUser deletes all the sessions of the user userID. Stores that do not keep records
+	// on server side return errors.NotSupported
+	DeleteUser(ctx context.Context, userID string) error
+}
+
+// Cleaner is implemented by stores that need expired records to be removed periodically
+type Cleaner interface {
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
+
+// NewID generates a random session ID
+func NewID() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// validID tells if id can have been generated by NewID. It protects stores that use IDs as file names or keys
+func validID(id string) bool {
+	if len(id) != 43 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+type options struct {
+	cookieName      string
+	cookie          http.Cookie
+	idleTimeout     time.Duration
+	absoluteTimeout time.Duration
+}
+
+// Option configures a Manager
+type Option func(opts *options)
+
+// CookieName sets the name of the session cookie. Default is "session"
+func CookieName(name string) Option {
+	return func(opts *options) {
+		opts.cookieName = name
+	}
+}
+
+// Cookie sets the attributes of the session cookie. Name, Value, Expires and MaxAge are ignored.
+// Default is an HttpOnly, Lax cookie with path "/"
+func Cookie(attributes http.Cookie) Option {
+	return func(opts *options) {
+		opts.cookie = attributes
+	}
+}
+
+// IdleTimeout sets how long a session lives without being saved. Default is 30 minutes, 0 disables it
+func IdleTimeout(timeout time.Duration) Option {
+	return func(opts *options) {
+		opts.idleTimeout = timeout
+	}
+}
+
+// AbsoluteTimeout sets how long a session lives after its creation. Default is 24 hours, 0 disables it
+func AbsoluteTimeout(timeout time.Duration) Option {
+	return func(opts *options) {
+		opts.absoluteTimeout = timeout
+	}
+}
+
+// Manager loads and saves sessions in a store
+type Manager struct {
+	store   Store
+	options options
+}
+
+// NewManager creates a manager that keeps sessions in store
+func NewManager(store Store, opts ...Option) *Manager {
+	m := &Manager{
+		store: store,
+		options: options{
+			cookieName:      "session",
+			cookie:          http.Cookie{Path: "/", HttpOnly: true, SameSite: http.SameSiteLaxMode},
+			idleTimeout:     30 * time.Minute,
+			absoluteTimeout: 24 * time.Hour,
+		},
+	}
+	for _, opt := range opts {
+		opt(&m.options)
+	}
+	return m
+}
+
+// Store returns the store of m
+func (m *Manager) Store() Store {
+	return m.store
+}
+
+// CookieName returns the name of the session cookie
+func (m *Manager) CookieName() string {
+	return m.options.cookieName
+}
+
+// New creates an empty session record
+func (m *Manager) New() *Record {
+	now := time.Now()
+	return &Record{
+		ID:         NewID(),
+		Values:     map[string]interface{}{},
+		CreatedAt:  now,
+		AccessedAt: now,
+	}
+}
+
+// Load returns the session referenced by the cookie value token. A new session is returned when token is empty,
+// unknown or references an expired session
+func (m *Manager) Load(ctx context.Context, token string) (*Record, error) {
+	r, _, err := m.load(ctx, token)
+	return r, err
+}
+
+// load is Load that also tells if the record was found in the store
+func (m *Manager) load(ctx context.Context, token string) (*Record, bool, error) {
+	if token == "" {
+		return m.New(), false, nil
+	}
+
+	r, err := m.store.Load(ctx, token)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return m.New(), false, nil
+		}
+		return nil, false, err
+	}
+
+	if r.Expired(time.Now()) {
+		_ = m.store.Delete(ctx, r)
+		return m.New(), false, nil
+	}
+	return r, true, nil
+}
+
+// LoadRequest returns the session referenced by the cookie of r
+func (m *Manager) LoadRequest(r *http.Request) (*Record, error) {
+	var token string
+	if c, err := r.Cookie(m.options.cookieName); err == nil {
+		token = c.Value
+	}
+	return m.Load(r.Context(), token)
+}
+
+// Save saves r, extending its idle expiry, and returns the cookie to send
+func (m *Manager) Save(ctx context.Context, r *Record) (*http.Cookie, error) {
+	return m.save(ctx, r, m.store.Save)
+}
+
+// Update is Save for records loaded from the store. It fails with errors.NotFound when the record was deleted
+// in the meantime, so that revoked sessions are not recreated
+func (m *Manager) Update(ctx context.Context, r *Record) (*http.Cookie, error) {
+	return m.save(ctx, r, m.store.Update)
+}
+
+func (m *Manager) save(ctx context.Context, r *Record, store func(context.Context, *Record) (string, error)) (*http.Cookie, error) {
+	now := time.Now()
+	r.AccessedAt = now
+	r.ExpiresAt = time.Time{}
+	if m.options.idleTimeout > 0 {
+		r.ExpiresAt = now.Add(m.options.idleTimeout)
+	}
+	if m.options.absoluteTimeout > 0 {
+		absolute := r.CreatedAt.Add(m.options.absoluteTimeout)
+		if r.ExpiresAt.IsZero() || absolute.Before(r.ExpiresAt) {
+			r.ExpiresAt = absolute
+		}
+	}
+
+	token, err := store(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	cookie := m.options.cookie
+	cookie.Name = m.options.cookieName
+	cookie.Value = token
+	if !r.ExpiresAt.IsZero() {
+		cookie.Expires = r.ExpiresAt
+		cookie.MaxAge = int(r.ExpiresAt.Sub(now).Seconds())
+		if cookie.MaxAge <= 0 {
+			cookie.MaxAge = -1
+		}
+	}
+	return &cookie, nil
+}
+
+// Destroy deletes r and returns the cookie that clears it on client side
+func (m *Manager) Destroy(ctx context.Context, r *Record) (*http.Cookie, error) {
+	if err := m.store.Delete(ctx, r); err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	return m.clearCookie(), nil
+}
+
+// clearCookie returns the cookie that clears the session cookie
+func (m *Manager) clearCookie() *http.Cookie {
+	cookie := m.options.cookie
+	cookie.Name = m.options.cookieName
+	cookie.Value = ""
+	cookie.MaxAge = -1
+	cookie.Expires = time.Unix(1, 0)
+	return &cookie
+}
+
+// Rotate gives r a new ID and deletes the record stored under the old one. It must be called when the privileges
+// of the session change, like on login, to prevent session fixation. r must be saved afterwards
+func (m *Manager) Rotate(ctx context.Context, r *Record) error {
+	old := *r
+	r.ID = NewID()
+	if err := m.store.Delete(ctx, &old); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// RevokeUser deletes all the sessions of userID, logging the user out everywhere
+func (m *Manager) RevokeUser(ctx context.Context, userID string) error {
+	return m.store.DeleteUser(ctx, userID)
+}
+
+// DeleteExpired removes the expired records of stores that implement Cleaner. Other stores expire records by
+// themselves, like Redis, or do not keep them, like cookies
+func (m *Manager) DeleteExpired(ctx context.Context) error {
+	if c, ok := m.store.(Cleaner); ok {
+		return c.DeleteExpired(ctx, time.Now())
+	}
+	return nil
+}
@@ -0,0 +1,348 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/go-redis/redis/v8"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/omecodes/common/errors"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// testStore checks the behavior shared by all the stores that keep records on server side
+func testStore(t *testing.T, store Store) {
+	ctx := context.Background()
+	m := NewManager(store)
+
+	r := m.New()
+	r.UserID = "alice"
+	r.Values["name"] = "Alice"
+	cookie, err := m.Save(ctx, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cookie.Name != "session" || cookie.Value == "" || !cookie.HttpOnly || cookie.MaxAge <= 0 {
+		t.Fatal("unexpected cookie", cookie)
+	}
+
+	loaded, err := m.Load(ctx, cookie.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.ID != r.ID || loaded.UserID != "alice" || loaded.Values["name"] != "Alice" {
+		t.Fatal("unexpected record", loaded)
+	}
+
+	// rotation invalidates the previous ID
+	previous := cookie.Value
+	if err = m.Rotate(ctx, loaded); err != nil {
+		t.Fatal(err)
+	}
+	if cookie, err = m.Save(ctx, loaded); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.Load(ctx, previous); !errors.IsNotFound(err) {
+		t.Fatal("rotated session must be deleted", err)
+	}
+
+	other := m.New()
+	other.UserID = "alice"
+	if _, err = m.Save(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+	bob := m.New()
+	bob.UserID = "bob"
+	bobCookie, err := m.Save(ctx, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob.Values["name"] = "Bob"
+	if _, err = m.Save(ctx, bob); err != nil {
+		t.Fatal("saving a stored record must replace it", err)
+	}
+	if _, err = m.Update(ctx, bob); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.RevokeUser(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{cookie.Value, other.ID} {
+		if _, err = store.Load(ctx, id); !errors.IsNotFound(err) {
+			t.Fatal("revoked session must be deleted", err)
+		}
+	}
+	if loaded, err = store.Load(ctx, bobCookie.Value); err != nil || loaded.Values["name"] != "Bob" {
+		t.Fatal("sessions of other users must be kept", err)
+	}
+	if _, err = m.Update(ctx, other); !errors.IsNotFound(err) {
+		t.Fatal("revoked session must not be recreated by an update", err)
+	}
+	if _, err = store.Load(ctx, other.ID); !errors.IsNotFound(err) {
+		t.Fatal("revoked session must stay deleted", err)
+	}
+
+	if _, err = m.Destroy(ctx, bob); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.Load(ctx, bobCookie.Value); !errors.IsNotFound(err) {
+		t.Fatal("destroyed session must be deleted", err)
+	}
+
+	if c, ok := store.(Cleaner); ok {
+		expired := m.New()
+		expired.ExpiresAt = time.Now().Add(-time.Minute)
+		if _, err = store.Save(ctx, expired); err != nil {
+			t.Fatal(err)
+		}
+		if err = c.DeleteExpired(ctx, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = store.Load(ctx, expired.ID); !errors.IsNotFound(err) {
+			t.Fatal("expired session must be deleted", err)
+		}
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, store)
+
+	if _, err = store.Load(context.Background(), "../../etc/passwd"); !errors.IsNotFound(err) {
+		t.Fatal("invalid IDs must not be read", err)
+	}
+}
+
+func TestBoltStore(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	db, err := bolt.Open(filepath.Join(dir, "sessions.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	store, err := NewBoltStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, store)
+}
+
+func TestSQLStore(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "sessions.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	store, err := NewSQLStore(db, "sqlite3", "sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, store)
+
+	// the table and its index already exist
+	if _, err = NewSQLStore(db, "sqlite3", "sessions"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRedisStore(t *testing.T) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR is not set")
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	defer client.Close()
+	testStore(t, NewRedisStore(client, "test:"+NewID()+":"))
+}
+
+func TestCookieStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewCookieStore("session", []byte("0123456789abcdef0123456789abcdef"))
+	m := NewManager(store)
+
+	r := m.New()
+	r.Values["name"] = "Alice"
+	cookie, err := m.Save(ctx, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := m.Load(ctx, cookie.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.ID != r.ID || loaded.Values["name"] != "Alice" {
+		t.Fatal("unexpected record", loaded)
+	}
+
+	// tampered cookies start a new session
+	loaded, err = m.Load(ctx, cookie.Value+"x")
+	if err != nil || loaded.ID == r.ID {
+		t.Fatal("tampered cookie must not be accepted", err)
+	}
+
+	if err = m.RevokeUser(ctx, "alice"); errors.Code(err) != errors.NotSupported {
+		t.Fatal("cookie sessions cannot be revoked", err)
+	}
+}
+
+func TestManagerExpiry(t *testing.T) {
+	ctx := context.Background()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(store, IdleTimeout(time.Hour), AbsoluteTimeout(2*time.Hour))
+
+	r := m.New()
+	r.CreatedAt = time.Now().Add(-90 * time.Minute)
+	if _, err = m.Save(ctx, r); err != nil {
+		t.Fatal(err)
+	}
+	if until := time.Until(r.ExpiresAt); until > 31*time.Minute || until < 29*time.Minute {
+		t.Fatal("absolute timeout must bound the idle expiry", until)
+	}
+
+	r.CreatedAt = time.Now().Add(-3 * time.Hour)
+	if _, err = m.Save(ctx, r); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := m.Load(ctx, r.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.ID == r.ID {
+		t.Fatal("expired session must be replaced by a new one")
+	}
+	if _, err = store.Load(ctx, r.ID); !errors.IsNotFound(err) {
+		t.Fatal("expired session must be deleted", err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	ctx := context.Background()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(store)
+
+	var cookies []*http.Cookie
+	setCookie := func(cookie *http.Cookie) error {
+		cookies = append(cookies, cookie)
+		return nil
+	}
+
+	s, err := m.Open(ctx, "", setCookie)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Save(); err != nil || len(cookies) != 0 {
+		t.Fatal("unchanged new sessions must not be saved", err, cookies)
+	}
+
+	s.Set("count", 3)
+	s.AddFlash("welcome")
+	if err = s.Save(); err != nil || len(cookies) != 1 {
+		t.Fatal("changed session must be saved", err, cookies)
+	}
+	token := cookies[0].Value
+
+	s, err = m.Open(ctx, token, setCookie)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.GetInt("count") != 3 || s.GetString("count") != "" {
+		t.Fatal("unexpected typed values", s.Get("count"))
+	}
+	if flashes := s.Flashes(); len(flashes) != 1 || flashes[0] != "welcome" || len(s.Flashes()) != 0 {
+		t.Fatal("flashes must be read once", flashes)
+	}
+
+	// login rotates the ID and deletes the previous record
+	s.SetUser("alice")
+	if err = s.Save(); err != nil || len(cookies) != 2 || cookies[1].Value == token {
+		t.Fatal("rotated session must be saved under a new ID", err, cookies)
+	}
+	if _, err = store.Load(ctx, token); !errors.IsNotFound(err) {
+		t.Fatal("previous ID must be deleted", err)
+	}
+	token = cookies[1].Value
+
+	s, err = m.Open(ctx, token, setCookie)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.UserID() != "alice" {
+		t.Fatal("unexpected user", s.UserID())
+	}
+	s.Destroy()
+	if err = s.Save(); err != nil || len(cookies) != 3 || cookies[2].MaxAge != -1 {
+		t.Fatal("destroyed session cookie must be cleared", err, cookies)
+	}
+	if _, err = store.Load(ctx, token); !errors.IsNotFound(err) {
+		t.Fatal("destroyed session must be deleted", err)
+	}
+
+	// sessions revoked while a request uses them are not recreated
+	s, err = m.Open(ctx, "", setCookie)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetUser("bob")
+	if err = s.Save(); err != nil || len(cookies) != 4 {
+		t.Fatal(err, cookies)
+	}
+	token = cookies[3].Value
+
+	s, err = m.Open(ctx, token, setCookie)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.RevokeUser(ctx, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	s.Set("count", 4)
+	if err = s.Save(); err != nil || len(cookies) != 5 || cookies[4].MaxAge != -1 {
+		t.Fatal("revoked session cookie must be cleared", err, cookies)
+	}
+	if _, err = store.Load(ctx, token); !errors.IsNotFound(err) {
+		t.Fatal("revoked session must not be recreated", err)
+	}
+}
@@ -0,0 +1,153 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/omecodes/common/errors"
+)
+
+// SQLStore keeps records in a SQL table with the id, user_id, data and expires_at columns
+type SQLStore struct {
+	db       *sql.DB
+	table    string
+	postgres bool
+	mysql    bool
+}
+
+// NewSQLStore creates a SQL store that keeps records in table, which is created if it does not exist.
+// dialect is one of "mysql", "sqlite3" and "postgres"
+func NewSQLStore(db *sql.DB, dialect string, table string) (*SQLStore, error) {
+	s := &SQLStore{db: db, table: table, postgres: dialect == "postgres", mysql: dialect == "mysql"}
+
+	dataType := "BLOB"
+	if s.postgres {
+		dataType = "BYTEA"
+	}
+	_, err := db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		id VARCHAR(64) NOT NULL PRIMARY KEY,
+		user_id VARCHAR(255) NOT NULL,
+		data %s NOT NULL,
+		expires_at BIGINT NOT NULL
+	)`, table, dataType))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(fmt.Sprintf("CREATE INDEX %s_user_id ON %s (user_id)", table, table))
+	if err != nil && !strings.Contains(strings.ToLower(err.Error()), "exist") && !strings.Contains(err.Error(), "Duplicate") {
+		return nil, err
+	}
+	return s, nil
+}
+
+// query replaces the ? placeholders of q for postgres
+func (s *SQLStore) query(q string) string {
+	q = strings.Replace(q, "$table", s.table, -1)
+	if !s.postgres {
+		return q
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, c := range q {
+		if c == '?' {
+			n++
+			b.WriteString(fmt.Sprintf("$%d", n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func (s *SQLStore) Load(ctx context.Context, token string) (*Record, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx, s.query("SELECT data FROM $table WHERE id=?"), token).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return DecodeRecord(data)
+}
+
+func (s *SQLStore) Save(ctx context.Context, r *Record) (string, error) {
+	data, expiresAt, err := s.row(r)
+	if err != nil {
+		return "", err
+	}
+
+	upsert := "INSERT INTO $table (id, user_id, data, expires_at) VALUES (?, ?, ?, ?) " +
+		"ON CONFLICT (id) DO UPDATE SET user_id=excluded.user_id, data=excluded.data, expires_at=excluded.expires_at"
+	if s.mysql {
+		upsert = "INSERT INTO $table (id, user_id, data, expires_at) VALUES (?, ?, ?, ?) " +
+			"ON DUPLICATE KEY UPDATE user_id=VALUES(user_id), data=VALUES(data), expires_at=VALUES(expires_at)"
+	}
+	if _, err = s.db.ExecContext(ctx, s.query(upsert), r.ID, r.UserID, data, expiresAt); err != nil {
+		return "", err
+	}
+	return r.ID, nil
+}
+
+func (s *SQLStore) Update(ctx context.Context, r *Record) (string, error) {
+	data, expiresAt, err := s.row(r)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := s.db.ExecContext(ctx, s.query("UPDATE $table SET user_id=?, data=?, expires_at=? WHERE id=?"),
+		r.UserID, data, expiresAt, r.ID)
+	if err != nil {
+		return "", err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return "", err
+	}
+	if updated == 0 {
+		// MySQL does not count rows whose values did not change
+		var found int
+		err = s.db.QueryRowContext(ctx, s.query("SELECT 1 FROM $table WHERE id=?"), r.ID).Scan(&found)
+		if err == sql.ErrNoRows {
+			return "", errors.NotFound
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return r.ID, nil
+}
+
+// row returns the data and expires_at column values of r
+func (s *SQLStore) row(r *Record) ([]byte, int64, error) {
+	data, err := r.Encode()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var expiresAt int64
+	if !r.ExpiresAt.IsZero() {
+		expiresAt = r.ExpiresAt.Unix()
+	}
+	return data, expiresAt, nil
+}
+
+func (s *SQLStore) Delete(ctx context.Context, r *Record) error {
+	_, err := s.db.ExecContext(ctx, s.query("DELETE FROM $table WHERE id=?"), r.ID)
+	return err
+}
+
+func (s *SQLStore) DeleteUser(ctx context.Context, userID string) error {
+	_, err := s.db.ExecContext(ctx, s.query("DELETE FROM $table WHERE user_id=?"), userID)
+	return err
+}
+
+func (s *SQLStore) DeleteExpired(ctx context.Context, now time.Time) error {
+	_, err := s.db.ExecContext(ctx, s.query("DELETE FROM $table WHERE expires_at > 0 AND expires_at <= ?"), now.Unix())
+	return err
+}
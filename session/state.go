@@ -0,0 +1,249 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/omecodes/common/errors"
+)
+
+const flashesKey = "_flashes"
+
+// Session is the session of a request, as produced by the httpx.Sessions middleware and the grpcx session
+// interceptors. Changes are saved automatically when the request completes
+type Session interface {
+	// ID returns the session ID
+	ID() string
+
+	// UserID returns the ID of the user the session belongs to
+	UserID() string
+
+	// SetUser binds the session to userID. The session ID is rotated since its privileges change
+	SetUser(userID string)
+
+	Get(name string) interface{}
+	GetString(name string) string
+	GetBool(name string) bool
+	GetInt(name string) int
+	GetInt64(name string) int64
+	GetFloat(name string) float64
+	GetTime(name string) time.Time
+
+	Set(name string, value interface{})
+	Delete(name string)
+
+	// AddFlash adds a message that is kept until it is read with Flashes
+	AddFlash(value interface{})
+
+	// Flashes returns and removes the flash messages
+	Flashes() []interface{}
+
+	// Rotate gives the session a new ID, to be called when its privileges change
+	Rotate()
+
+	// Destroy deletes the session. Values set afterwards are saved in a new session
+	Destroy()
+
+	// Save saves the session now instead of waiting for the request to complete
+	Save() error
+}
+
+// CookieSetter sends a session cookie to the client
+type CookieSetter func(cookie *http.Cookie) error
+
+// Open returns the session referenced by the cookie value token. The session is saved when Save is called if it
+// changed, and the cookie to send is passed to setCookie
+func (m *Manager) Open(ctx context.Context, token string, setCookie CookieSetter) (Session, error) {
+	r, found, err := m.load(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return &state{
+		ctx:       ctx,
+		manager:   m,
+		setCookie: setCookie,
+		record:    r,
+		stored:    found,
+		hadCookie: token != "",
+	}, nil
+}
+
+// state is the Session implementation of the Manager
+type state struct {
+	sync.Mutex
+	ctx       context.Context
+	manager   *Manager
+	setCookie CookieSetter
+	record    *Record
+
+	// stored tells if record is in the store under its current ID
+	stored    bool
+	hadCookie bool
+	modified  bool
+	discarded []*Record
+}
+
+func (s *state) ID() string {
+	s.Lock()
+	defer s.Unlock()
+	return s.record.ID
+}
+
+func (s *state) UserID() string {
+	s.Lock()
+	defer s.Unlock()
+	return s.record.UserID
+}
+
+func (s *state) SetUser(userID string) {
+	s.Lock()
+	defer s.Unlock()
+	s.rotate()
+	s.record.UserID = userID
+}
+
+func (s *state) Get(name string) interface{} {
+	s.Lock()
+	defer s.Unlock()
+	return s.record.Values[name]
+}
+
+func (s *state) GetString(name string) string {
+	return String(s.Get(name))
+}
+
+func (s *state) GetBool(name string) bool {
+	return Bool(s.Get(name))
+}
+
+func (s *state) GetInt(name string) int {
+	return Int(s.Get(name))
+}
+
+func (s *state) GetInt64(name string) int64 {
+	return Int64(s.Get(name))
+}
+
+func (s *state) GetFloat(name string) float64 {
+	return Float(s.Get(name))
+}
+
+func (s *state) GetTime(name string) time.Time {
+	return Time(s.Get(name))
+}
+
+func (s *state) Set(name string, value interface{}) {
+	s.Lock()
+	defer s.Unlock()
+	s.record.Values[name] = value
+	s.modified = true
+}
+
+func (s *state) Delete(name string) {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.record.Values[name]; ok {
+		delete(s.record.Values, name)
+		s.modified = true
+	}
+}
+
+func (s *state) AddFlash(value interface{}) {
+	s.Lock()
+	defer s.Unlock()
+	flashes, _ := s.record.Values[flashesKey].([]interface{})
+	s.record.Values[flashesKey] = append(flashes, value)
+	s.modified = true
+}
+
+func (s *state) Flashes() []interface{} {
+	s.Lock()
+	defer s.Unlock()
+	flashes, ok := s.record.Values[flashesKey].([]interface{})
+	if ok {
+		delete(s.record.Values, flashesKey)
+		s.modified = true
+	}
+	return flashes
+}
+
+func (s *state) Rotate() {
+	s.Lock()
+	defer s.Unlock()
+	s.rotate()
+}
+
+func (s *state) rotate() {
+	if s.stored {
+		old := *s.record
+		s.discarded = append(s.discarded, &old)
+		s.stored = false
+	}
+	s.record.ID = NewID()
+	s.modified = true
+}
+
+func (s *state) Destroy() {
+	s.Lock()
+	defer s.Unlock()
+	if s.stored {
+		s.discarded = append(s.discarded, s.record)
+	}
+	s.record = s.manager.New()
+	s.stored = false
+	s.modified = false
+}
+
+// Save deletes the discarded records and saves the current one if it changed or if its idle expiry must be
+// extended. Records loaded from the store are only updated: when they were deleted in the meantime, like by
+// Manager.RevokeUser, the session is considered revoked and its cookie is cleared instead.
+// A cookie clearing the session is also sent when the session was destroyed and not recreated
+func (s *state) Save() error {
+	s.Lock()
+	defer s.Unlock()
+
+	for len(s.discarded) > 0 {
+		if err := s.manager.store.Delete(s.ctx, s.discarded[0]); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		s.discarded = s.discarded[1:]
+	}
+
+	if s.modified || s.stored && s.idle() {
+		save := s.manager.Save
+		if s.stored {
+			save = s.manager.Update
+		}
+
+		cookie, err := save(s.ctx, s.record)
+		if errors.IsNotFound(err) {
+			s.record = s.manager.New()
+			s.stored = false
+			s.modified = false
+			s.hadCookie = false
+			return s.setCookie(s.manager.clearCookie())
+		}
+		if err != nil {
+			return err
+		}
+		s.stored = true
+		s.hadCookie = true
+		s.modified = false
+		return s.setCookie(cookie)
+	}
+
+	if !s.stored && s.hadCookie {
+		s.hadCookie = false
+		return s.setCookie(s.manager.clearCookie())
+	}
+	return nil
+}
+
+// idle tells if a quarter of the idle timeout elapsed since the record was saved. Saving it again extends its
+// expiry without writing the store on every request
+func (s *state) idle() bool {
+	timeout := s.manager.options.idleTimeout
+	return timeout > 0 && time.Since(s.record.AccessedAt) > timeout/4
+}
@@ -0,0 +1,75 @@
+package session
+
+import (
+	"encoding/gob"
+	"time"
+)
+
+func init() {
+	// registered since they are stored in Values as interface values
+	gob.Register([]interface{}{})
+	gob.Register(time.Time{})
+}
+
+// String returns v if it is a string, or ""
+func String(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+// Bool returns v if it is a bool, or false
+func Bool(v interface{}) bool {
+	b, _ := v.(bool)
+	return b
+}
+
+// Int64 converts v to an int64 if it is an integer, or returns 0
+func Int64(v interface{}) int64 {
+	switch n := v.(type) {
+	case int:
+		return int64(n)
+	case int8:
+		return int64(n)
+	case int16:
+		return int64(n)
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	case uint:
+		return int64(n)
+	case uint8:
+		return int64(n)
+	case uint16:
+		return int64(n)
+	case uint32:
+		return int64(n)
+	case uint64:
+		return int64(n)
+	default:
+		return 0
+	}
+}
+
+// Int converts v to an int if it is an integer, or returns 0
+func Int(v interface{}) int {
+	return int(Int64(v))
+}
+
+// Float converts v to a float64 if it is a number, or returns 0
+func Float(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case float32:
+		return float64(n)
+	default:
+		return float64(Int64(v))
+	}
+}
+
+// Time returns v if it is a time.Time, or the zero time
+func Time(v interface{}) time.Time {
+	t, _ := v.(time.Time)
+	return t
+}
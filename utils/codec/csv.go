@@ -0,0 +1,311 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Csv encodes slices of structs, of maps and of string slices. The first record holds the column names,
+// taken from csv or json field tags, except for string slices that are written as they are
+var Csv Codec = &csvc{}
+
+type csvc struct{}
+
+func (c *csvc) Supports(o interface{}) bool {
+	v := indirect(reflect.ValueOf(o))
+	if !v.IsValid() || (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Type().Elem().Kind() == reflect.Uint8 {
+		return false
+	}
+
+	switch elem := indirectType(v.Type().Elem()); elem.Kind() {
+	case reflect.Struct, reflect.Map:
+		return elem.Kind() != reflect.Map || elem.Key().Kind() == reflect.String
+	case reflect.Slice:
+		return elem.Elem().Kind() == reflect.String
+	default:
+		return false
+	}
+}
+
+func (c *csvc) Encode(o interface{}) ([]byte, error) {
+	buff := bytes.NewBuffer(nil)
+	err := c.EncodeTo(buff, o)
+	return buff.Bytes(), err
+}
+
+func (c *csvc) EncodeTo(w io.Writer, o interface{}) error {
+	if !c.Supports(o) {
+		return ErrNotSupported
+	}
+
+	v := indirect(reflect.ValueOf(o))
+	elem := indirectType(v.Type().Elem())
+	writer := csv.NewWriter(w)
+
+	var header []string
+	switch elem.Kind() {
+	case reflect.Struct:
+		for _, f := range csvFields(elem) {
+			header = append(header, f.name)
+		}
+
+	case reflect.Map:
+		keys := map[string]bool{}
+		for i := 0; i < v.Len(); i++ {
+			item := indirect(v.Index(i))
+			if !item.IsValid() {
+				continue
+			}
+			for _, key := range item.MapKeys() {
+				keys[key.String()] = true
+			}
+		}
+		for key := range keys {
+			header = append(header, key)
+		}
+		sort.Strings(header)
+	}
+
+	if header != nil {
+		if err := writer.Write(header); err != nil {
+			return err
+		}
+	}
+
+	fields := csvFields(elem)
+	for i := 0; i < v.Len(); i++ {
+		item := indirect(v.Index(i))
+
+		var record []string
+		switch elem.Kind() {
+		case reflect.Struct:
+			for _, f := range fields {
+				if item.IsValid() {
+					record = append(record, csvString(item.FieldByIndex(f.index)))
+				} else {
+					record = append(record, "")
+				}
+			}
+
+		case reflect.Map:
+			for _, key := range header {
+				var value reflect.Value
+				if item.IsValid() {
+					value = item.MapIndex(reflect.ValueOf(key).Convert(elem.Key()))
+				}
+				record = append(record, csvString(value))
+			}
+
+		default:
+			if item.IsValid() {
+				for j := 0; j < item.Len(); j++ {
+					record = append(record, item.Index(j).String())
+				}
+			}
+		}
+
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func (c *csvc) Decode(data []byte, o interface{}) error {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return err
+	}
+
+	if rows, ok := o.(*[][]string); ok {
+		*rows = records
+		return nil
+	}
+
+	v := reflect.ValueOf(o)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice || indirectType(v.Elem().Type().Elem()).Kind() != reflect.Struct {
+		return ErrNotSupported
+	}
+
+	slice := v.Elem()
+	itemType := slice.Type().Elem()
+	structType := indirectType(itemType)
+
+	if len(records) == 0 {
+		slice.Set(reflect.MakeSlice(slice.Type(), 0, 0))
+		return nil
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[name] = i
+	}
+
+	result := reflect.MakeSlice(slice.Type(), 0, len(records)-1)
+	for _, record := range records[1:] {
+		item := reflect.New(structType).Elem()
+		for _, f := range csvFields(structType) {
+			col, found := columns[f.name]
+			if !found || col >= len(record) {
+				continue
+			}
+			err = setCsvString(item.FieldByIndex(f.index), record[col])
+			if err != nil {
+				return fmt.Errorf("codec: column %s: %s", f.name, err)
+			}
+		}
+
+		if itemType.Kind() == reflect.Ptr {
+			result = reflect.Append(result, item.Addr())
+		} else {
+			result = reflect.Append(result, item)
+		}
+	}
+	slice.Set(result)
+	return nil
+}
+
+type csvField struct {
+	name  string
+	index []int
+}
+
+func csvFields(t reflect.Type) []csvField {
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fields []csvField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name := f.Name
+		tag := f.Tag.Get("csv")
+		if tag == "" {
+			tag = f.Tag.Get("json")
+		}
+		if tag != "" {
+			tag = strings.Split(tag, ",")[0]
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		fields = append(fields, csvField{name: name, index: f.Index})
+	}
+	return fields
+}
+
+func csvString(v reflect.Value) string {
+	v = indirect(v)
+	if !v.IsValid() {
+		return ""
+	}
+
+	switch value := v.Interface().(type) {
+	case time.Time:
+		return value.Format(time.RFC3339)
+	case fmt.Stringer:
+		return value.String()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+func setCsvString(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Ptr {
+		if s == "" {
+			return nil
+		}
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+
+	if _, ok := v.Interface().(time.Time); ok {
+		if s == "" {
+			return nil
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return ErrNotSupported
+	}
+	return nil
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,50 @@
+package codec
+
+import (
+	"testing"
+)
+
+type csvRow struct {
+	Name    string  `csv:"name"`
+	Age     int     `json:"age"`
+	Score   float64 `csv:"score"`
+	Ignored string  `csv:"-"`
+}
+
+func TestCsvRoundTrip(t *testing.T) {
+	rows := []*csvRow{{Name: "ann", Age: 31, Score: 4.5, Ignored: "x"}, {Name: "bob", Age: 27}}
+
+	data, err := Csv.Encode(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "name,age,score\nann,31,4.5\nbob,27,0\n" {
+		t.Fatal("unexpected encoding", string(data))
+	}
+
+	var decoded []csvRow
+	err = Csv.Decode(data, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(decoded) != 2 || decoded[0].Name != "ann" || decoded[0].Age != 31 || decoded[0].Score != 4.5 {
+		t.Fatal("unexpected decoded rows", decoded)
+	}
+}
+
+func TestCsvMaps(t *testing.T) {
+	data, err := Csv.Encode([]map[string]interface{}{{"b": 2, "a": "x"}, {"c": true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "a,b,c\nx,2,\n,,true\n" {
+		t.Fatal("unexpected encoding", string(data))
+	}
+
+	if Csv.(Checker).Supports(map[string]string{}) {
+		t.Fatal("only slices are supported")
+	}
+}
@@ -1 +1,131 @@
 package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"reflect"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/vmihailenco/msgpack/v4"
+	"gopkg.in/yaml.v2"
+)
+
+// ErrNotSupported is returned when a codec cannot encode or decode a type
+var ErrNotSupported = errors.New("codec: type not supported")
+
+// StreamEncoder is implemented by codecs that write encoded values directly to a writer
+type StreamEncoder interface {
+	EncodeTo(w io.Writer, o interface{}) error
+}
+
+// Checker is implemented by codecs that only support some types
+type Checker interface {
+	Supports(o interface{}) bool
+}
+
+var Xml Codec
+var Yaml Codec
+var MsgPack Codec
+var Protobuf Codec
+
+func init() {
+	Xml = &xmlc{}
+	Yaml = &yamlc{}
+	MsgPack = &msgpackc{}
+	Protobuf = &protoc{}
+}
+
+func (j *jsonc) EncodeTo(w io.Writer, o interface{}) error {
+	return json.NewEncoder(w).Encode(o)
+}
+
+type xmlc struct{}
+
+func (x *xmlc) Encode(o interface{}) ([]byte, error) {
+	return xml.Marshal(o)
+}
+
+func (x *xmlc) Decode(data []byte, o interface{}) error {
+	return xml.Unmarshal(data, o)
+}
+
+// Supports rejects maps, which encoding/xml cannot encode
+func (x *xmlc) Supports(o interface{}) bool {
+	v := reflect.ValueOf(o)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	return v.IsValid() && v.Kind() != reflect.Map
+}
+
+// EncodeTo encodes o in a buffer first, so that nothing is written to w when encoding fails
+func (x *xmlc) EncodeTo(w io.Writer, o interface{}) error {
+	buff := bytes.NewBufferString(xml.Header)
+	err := xml.NewEncoder(buff).Encode(o)
+	if err != nil {
+		return err
+	}
+	_, err = buff.WriteTo(w)
+	return err
+}
+
+type yamlc struct{}
+
+func (y *yamlc) Encode(o interface{}) ([]byte, error) {
+	return yaml.Marshal(o)
+}
+
+func (y *yamlc) Decode(data []byte, o interface{}) error {
+	return yaml.Unmarshal(data, o)
+}
+
+func (y *yamlc) EncodeTo(w io.Writer, o interface{}) error {
+	encoder := yaml.NewEncoder(w)
+	err := encoder.Encode(o)
+	if err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+type msgpackc struct{}
+
+func (m *msgpackc) Encode(o interface{}) ([]byte, error) {
+	buff := bytes.NewBuffer(nil)
+	err := m.EncodeTo(buff, o)
+	return buff.Bytes(), err
+}
+
+func (m *msgpackc) Decode(data []byte, o interface{}) error {
+	return msgpack.NewDecoder(bytes.NewReader(data)).UseJSONTag(true).Decode(o)
+}
+
+func (m *msgpackc) EncodeTo(w io.Writer, o interface{}) error {
+	return msgpack.NewEncoder(w).UseJSONTag(true).Encode(o)
+}
+
+type protoc struct{}
+
+func (p *protoc) Supports(o interface{}) bool {
+	_, ok := o.(proto.Message)
+	return ok
+}
+
+func (p *protoc) Encode(o interface{}) ([]byte, error) {
+	m, ok := o.(proto.Message)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return proto.Marshal(m)
+}
+
+func (p *protoc) Decode(data []byte, o interface{}) error {
+	m, ok := o.(proto.Message)
+	if !ok {
+		return ErrNotSupported
+	}
+	return proto.Unmarshal(data, m)
+}
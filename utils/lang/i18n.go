@@ -11,6 +11,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sync"
 )
 
 type Entry struct {
@@ -23,12 +24,31 @@ func NewManager(dir string) *I18n {
 }
 
 type I18n struct {
+	sync.RWMutex
 	dir             string
 	defaultLanguage language.Tag
 	matcher         language.Matcher
+	supported       []language.Tag
+	keys            map[language.Tag]map[string]bool
 	updated         bool
 }
 
+// SetDefaultLanguage sets the language used when none of the accepted languages
+// is supported and when a key is not translated in the matched language
+func (i *I18n) SetDefaultLanguage(tag language.Tag) {
+	i.Lock()
+	defer i.Unlock()
+	i.defaultLanguage = tag
+	i.updated = true
+}
+
+// DefaultLanguage returns the default language
+func (i *I18n) DefaultLanguage() language.Tag {
+	i.RLock()
+	defer i.RUnlock()
+	return i.defaultLanguage
+}
+
 func (i *I18n) Load() error {
 	langDir, err := os.Open(i.dir)
 	if err != nil {
@@ -123,23 +143,81 @@ func (i *I18n) Translations(tag language.Tag) (map[string]string, error) {
 }
 
 func (i *I18n) AddEntry(tag language.Tag, entry Entry) error {
+	i.Lock()
+	defer i.Unlock()
+
 	i.updated = true
+	if i.keys == nil {
+		i.keys = map[language.Tag]map[string]bool{}
+	}
+	if i.keys[tag] == nil {
+		i.keys[tag] = map[string]bool{}
+	}
+	i.keys[tag][entry.Key] = true
 	return message.SetString(tag, entry.Key, entry.Value)
 }
 
-func (i *I18n) LanguageFromAcceptLanguageHeader(header string) language.Tag {
+// Has tells whether key is translated in the language tag
+func (i *I18n) Has(tag language.Tag, key string) bool {
+	i.RLock()
+	defer i.RUnlock()
+	return i.keys[tag][key]
+}
+
+func (i *I18n) getMatcher() (language.Matcher, []language.Tag) {
+	i.Lock()
+	defer i.Unlock()
+
 	if i.matcher == nil || i.updated {
-		i.matcher = language.NewMatcher(message.DefaultCatalog.Languages())
+		// the default language is listed first so that it is matched when nothing else is
+		var supported []language.Tag
+		if i.defaultLanguage != language.Und {
+			supported = append(supported, i.defaultLanguage)
+		}
+		for _, tag := range message.DefaultCatalog.Languages() {
+			if tag != i.defaultLanguage {
+				supported = append(supported, tag)
+			}
+		}
+		i.supported = supported
+		i.matcher = language.NewMatcher(supported)
+		i.updated = false
 	}
+	return i.matcher, i.supported
+}
+
+func (i *I18n) LanguageFromAcceptLanguageHeader(header string) language.Tag {
+	matcher, supported := i.getMatcher()
 	tags, _, err := language.ParseAcceptLanguage(header)
 	if err != nil {
 		log.Println("Accept-Languages header parsing failed:", err)
-		tags = []language.Tag{i.defaultLanguage}
+		tags = []language.Tag{i.DefaultLanguage()}
+	}
+
+	t, index, _ := matcher.Match(tags...)
+	if index >= 0 && index < len(supported) {
+		return supported[index]
 	}
-	t, _, _ := i.matcher.Match(tags...)
 	return t
 }
 
+// Translation returns the translation of key in the language tag, or in the default language
+// if key is not translated in tag. It returns false if key is translated in none of them
+func (i *I18n) Translation(tag language.Tag, key string, args ...interface{}) (string, bool) {
+	if !i.Has(tag, key) {
+		tag = i.DefaultLanguage()
+		if !i.Has(tag, key) {
+			return "", false
+		}
+	}
+	return message.NewPrinter(tag).Sprintf(key, args...), true
+}
+
+// TranslationFromAcceptLanguageHeader is Translation for the language that best matches acceptLanguagesHeader
+func (i *I18n) TranslationFromAcceptLanguageHeader(acceptLanguagesHeader string, key string, args ...interface{}) (string, bool) {
+	return i.Translation(i.LanguageFromAcceptLanguageHeader(acceptLanguagesHeader), key, args...)
+}
+
 func (i *I18n) Translator(acceptLanguagesHeader string) *message.Printer {
 	t := i.LanguageFromAcceptLanguageHeader(acceptLanguagesHeader)
 	return message.NewPrinter(t)
@@ -151,6 +229,9 @@ func (i *I18n) Translated(tag language.Tag, key string, args ...interface{}) str
 }
 
 func (i *I18n) TranslatedFromAcceptLanguageHeader(acceptLanguagesHeader string, key string, args ...interface{}) string {
+	if text, ok := i.TranslationFromAcceptLanguageHeader(acceptLanguagesHeader, key, args...); ok {
+		return text
+	}
 	translator := i.Translator(acceptLanguagesHeader)
 	return translator.Sprintf(key, args...)
 }
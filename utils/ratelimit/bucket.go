@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// NewBucket creates a token bucket that refills rate tokens per second up to burst tokens.
+// The bucket starts full
+func NewBucket(rate float64, burst int) *Bucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Bucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Bucket is a token bucket rate limiter safe for concurrent use
+type Bucket struct {
+	sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// Allow takes a token if one is available
+func (b *Bucket) Allow() bool {
+	ok, _ := b.Take(time.Now())
+	return ok
+}
+
+// Take takes a token at now. When none is available it returns the duration after which one will be
+func (b *Bucket) Take(now time.Time) (bool, time.Duration) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	if b.rate <= 0 {
+		return false, time.Duration(1<<63 - 1)
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// Remaining returns the number of available tokens at now
+func (b *Bucket) Remaining(now time.Time) int {
+	b.Lock()
+	defer b.Unlock()
+
+	b.refill(now)
+	return int(b.tokens)
+}
+
+// Burst returns the bucket capacity
+func (b *Bucket) Burst() int {
+	return int(b.burst)
+}
+
+func (b *Bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	b.last = now
+
+	b.tokens += elapsed * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
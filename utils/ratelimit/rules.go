@@ -0,0 +1,50 @@
+package ratelimit
+
+import "strings"
+
+// Rules maps route or method names to limits. Patterns are either exact names or prefixes ending with "*"
+type Rules struct {
+	Default Limit
+	exact   map[string]Limit
+	prefix  map[string]Limit
+}
+
+// NewRules creates rules that apply defaultLimit to the names that match no pattern
+func NewRules(defaultLimit Limit) *Rules {
+	return &Rules{
+		Default: defaultLimit,
+		exact:   map[string]Limit{},
+		prefix:  map[string]Limit{},
+	}
+}
+
+// Set sets the limit of the names that match pattern. A zero limit disables limiting for them
+func (r *Rules) Set(pattern string, limit Limit) {
+	if strings.HasSuffix(pattern, "*") {
+		r.prefix[strings.TrimSuffix(pattern, "*")] = limit
+		return
+	}
+	r.exact[pattern] = limit
+}
+
+// Match returns the pattern and the limit of the first name that matches a rule. Exact patterns are
+// preferred to prefixes and longer prefixes to shorter ones. The default limit is returned with an empty
+// pattern if no name matches
+func (r *Rules) Match(names ...string) (string, Limit) {
+	for _, name := range names {
+		if limit, found := r.exact[name]; found {
+			return name, limit
+		}
+
+		matched, found := "", false
+		for prefix := range r.prefix {
+			if strings.HasPrefix(name, prefix) && (!found || len(prefix) > len(matched)) {
+				matched, found = prefix, true
+			}
+		}
+		if found {
+			return matched + "*", r.prefix[matched]
+		}
+	}
+	return "", r.Default
+}
@@ -0,0 +1,140 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Algorithm is the way a Limit counts requests
+type Algorithm int
+
+const (
+	// TokenBucket allows bursts of Limit.Requests and refills them evenly over Limit.Period
+	TokenBucket = Algorithm(iota)
+
+	// SlidingWindow allows Limit.Requests over any Limit.Period long window
+	SlidingWindow
+)
+
+// Limit is a number of requests allowed per period. The zero Limit means no limit
+type Limit struct {
+	Requests  int
+	Period    time.Duration
+	Algorithm Algorithm
+}
+
+// PerSecond returns a token bucket limit of n requests per second
+func PerSecond(n int) Limit {
+	return Limit{Requests: n, Period: time.Second}
+}
+
+// PerMinute returns a token bucket limit of n requests per minute
+func PerMinute(n int) Limit {
+	return Limit{Requests: n, Period: time.Minute}
+}
+
+// PerHour returns a token bucket limit of n requests per hour
+func PerHour(n int) Limit {
+	return Limit{Requests: n, Period: time.Hour}
+}
+
+// Sliding returns a copy of l that uses the sliding window algorithm
+func (l Limit) Sliding() Limit {
+	l.Algorithm = SlidingWindow
+	return l
+}
+
+// IsZero tells if l does not limit anything
+func (l Limit) IsZero() bool {
+	return l.Requests <= 0 || l.Period <= 0
+}
+
+// Result is the outcome of a Store.Take call
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	ResetIn    time.Duration
+}
+
+// Store counts requests by key. Implementations backed by shared databases let several instances share limits
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (*Result, error)
+}
+
+type memoryEntry struct {
+	bucket   *Bucket
+	window   *Window
+	lastUsed time.Time
+	idle     time.Duration
+}
+
+// MemoryStore is a Store that keeps counters in memory. Idle counters are removed periodically
+type MemoryStore struct {
+	sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries:   map[string]*memoryEntry{},
+		lastSweep: time.Now(),
+	}
+}
+
+// Take counts a request for key
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (*Result, error) {
+	now := time.Now()
+	entry := s.entry(key, limit, now)
+
+	result := &Result{Limit: limit.Requests}
+	if entry.window != nil {
+		result.Allowed, result.RetryAfter = entry.window.Take(now)
+		result.Remaining = entry.window.Remaining(now)
+		result.ResetIn = entry.window.ResetIn(now)
+		return result, nil
+	}
+
+	result.Allowed, result.RetryAfter = entry.bucket.Take(now)
+	result.Remaining = entry.bucket.Remaining(now)
+	missing := limit.Requests - result.Remaining
+	result.ResetIn = time.Duration(math.Ceil(float64(missing) * float64(limit.Period) / float64(limit.Requests)))
+	return result, nil
+}
+
+func (s *MemoryStore) entry(key string, limit Limit, now time.Time) *memoryEntry {
+	s.Lock()
+	defer s.Unlock()
+
+	if now.Sub(s.lastSweep) > time.Minute {
+		s.sweep(now)
+	}
+
+	entry, found := s.entries[key]
+	if !found {
+		entry = &memoryEntry{idle: 2 * limit.Period}
+		if limit.Algorithm == SlidingWindow {
+			entry.window = NewWindow(limit.Requests, limit.Period)
+		} else {
+			entry.bucket = NewBucket(float64(limit.Requests)/limit.Period.Seconds(), limit.Requests)
+		}
+		s.entries[key] = entry
+	}
+	entry.lastUsed = now
+	return entry
+}
+
+// sweep removes the counters that are back to their initial state
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, entry := range s.entries {
+		if now.Sub(entry.lastUsed) > entry.idle {
+			delete(s.entries, key)
+		}
+	}
+	s.lastSweep = now
+}
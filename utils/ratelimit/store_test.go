@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWindow(t *testing.T) {
+	w := NewWindow(10, time.Minute)
+	start := time.Now().Truncate(time.Minute)
+
+	for i := 0; i < 10; i++ {
+		if ok, _ := w.Take(start.Add(time.Second)); !ok {
+			t.Fatal("event must be accepted", i)
+		}
+	}
+	if ok, wait := w.Take(start.Add(time.Second)); ok || wait != 59*time.Second {
+		t.Fatal("event over limit must be rejected until the window ends", wait)
+	}
+
+	// at the middle of the next window, half of the previous count still weighs
+	now := start.Add(90 * time.Second)
+	if remaining := w.Remaining(now); remaining != 5 {
+		t.Fatal("unexpected remaining", remaining)
+	}
+	for i := 0; i < 5; i++ {
+		if ok, _ := w.Take(now); !ok {
+			t.Fatal("event must be accepted", i)
+		}
+	}
+	if ok, wait := w.Take(now); ok || wait <= 0 {
+		t.Fatal("event over limit must be rejected")
+	}
+}
+
+func TestRules(t *testing.T) {
+	rules := NewRules(PerSecond(10))
+	rules.Set("/files/*", PerSecond(5))
+	rules.Set("/files/upload", PerMinute(1))
+	rules.Set("POST /files/*", PerSecond(2))
+	rules.Set("/health", Limit{})
+
+	cases := map[string]Limit{
+		"/files/list":   PerSecond(5),
+		"/files/upload": PerMinute(1),
+		"/users":        PerSecond(10),
+		"/health":       {},
+	}
+	for name, expected := range cases {
+		if _, limit := rules.Match(name); limit != expected {
+			t.Fatal(name, "unexpected limit", limit)
+		}
+	}
+
+	if pattern, limit := rules.Match("POST /files/list", "/files/list"); pattern != "POST /files/*" || limit != PerSecond(2) {
+		t.Fatal("method patterns must be matched first", pattern)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	limits := []Limit{PerMinute(3), PerMinute(3).Sliding()}
+	for _, limit := range limits {
+		key := "key" + string(rune('0'+limit.Algorithm))
+		for i := 0; i < 3; i++ {
+			result, err := store.Take(context.Background(), key, limit)
+			if err != nil || !result.Allowed || result.Remaining != 2-i {
+				t.Fatal("request must be allowed", limit.Algorithm, i, result)
+			}
+		}
+
+		result, _ := store.Take(context.Background(), key, limit)
+		if result.Allowed || result.RetryAfter <= 0 || result.Limit != 3 {
+			t.Fatal("request must be rejected", limit.Algorithm, result)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// NewWindow creates a sliding window limiter that accepts limit events per period.
+// The count of the previous fixed window is weighted by its overlap with the sliding window
+func NewWindow(limit int, period time.Duration) *Window {
+	if limit < 1 {
+		limit = 1
+	}
+	return &Window{
+		limit:  limit,
+		period: period,
+	}
+}
+
+// Window is a sliding window counter safe for concurrent use
+type Window struct {
+	sync.Mutex
+	limit    int
+	period   time.Duration
+	start    time.Time
+	previous int
+	current  int
+}
+
+// Allow counts an event if the limit is not reached
+func (w *Window) Allow() bool {
+	ok, _ := w.Take(time.Now())
+	return ok
+}
+
+// Take counts an event at now. When the limit is reached it returns the duration after which an event will be accepted
+func (w *Window) Take(now time.Time) (bool, time.Duration) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.slide(now)
+	weight := w.previousWeight(now)
+	if float64(w.previous)*weight+float64(w.current) < float64(w.limit) {
+		w.current++
+		return true, 0
+	}
+
+	// time until the weighted previous count lets one more event in
+	elapsed := now.Sub(w.start)
+	if w.previous == 0 || w.current >= w.limit {
+		return false, w.period - elapsed
+	}
+	needed := 1 - float64(w.limit-w.current)/float64(w.previous)
+	wait := time.Duration(needed*float64(w.period)) - elapsed
+	if wait <= 0 {
+		wait = time.Millisecond
+	}
+	return false, wait
+}
+
+// Remaining returns the number of events that can still be counted at now
+func (w *Window) Remaining(now time.Time) int {
+	w.Lock()
+	defer w.Unlock()
+
+	w.slide(now)
+	remaining := w.limit - int(float64(w.previous)*w.previousWeight(now)+0.999999) - w.current
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// Limit returns the number of events accepted per period
+func (w *Window) Limit() int {
+	return w.limit
+}
+
+// ResetIn returns the duration until the current fixed window ends
+func (w *Window) ResetIn(now time.Time) time.Duration {
+	w.Lock()
+	defer w.Unlock()
+
+	w.slide(now)
+	return w.period - now.Sub(w.start)
+}
+
+func (w *Window) slide(now time.Time) {
+	if w.start.IsZero() {
+		w.start = now.Truncate(w.period)
+		return
+	}
+
+	elapsed := now.Sub(w.start)
+	if elapsed < w.period {
+		return
+	}
+
+	if elapsed < 2*w.period {
+		w.previous = w.current
+	} else {
+		w.previous = 0
+	}
+	w.current = 0
+	w.start = now.Truncate(w.period)
+}
+
+func (w *Window) previousWeight(now time.Time) float64 {
+	return 1 - float64(now.Sub(w.start))/float64(w.period)
+}